@@ -1,40 +1,236 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"surf_bot/internal/app"
+	"surf_bot/internal/config"
 	"surf_bot/internal/handler"
+	"surf_bot/internal/messenger"
 	"surf_bot/internal/repository"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+const usage = `Usage:
+  bot [start]                              run the bot
+  bot migrate up|down|status|roundtrip     manage database migrations`
+
 func main() {
+	command, args := "start", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "start":
+		runBot()
+	case "migrate":
+		runMigrate(args)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// runMigrate applies embedded migrations without starting the bot
+func runMigrate(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadDB()
+	if err != nil {
+		log.Fatalf("❌ Некорректная конфигурация:\n%v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := app.InitDB(ctx, cfg.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	migrator, err := app.NewMigrator(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "status":
+		err = migrator.Status(ctx)
+	case "roundtrip":
+		err = migrator.RoundTrip(ctx)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
+
+func runBot() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("❌ Некорректная конфигурация:\n%v", err)
+	}
+	for _, w := range cfg.Warnings() {
+		log.Printf("⚠️  %s", w)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize DB connection using app-level InitDB
-	db := app.InitDB()
+	db, err := app.InitDB(ctx, cfg.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.DB.AutoMigrate {
+		migrator, err := app.NewMigrator(db)
+		if err == nil {
+			err = migrator.Up(ctx)
+		}
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+
 	repo := repository.NewUserRepository(db)
 
 	// Setup bot
-	bot, err := tgbotapi.NewBotAPI(os.Getenv("BOT_TOKEN"))
+	bot, err := tgbotapi.NewBotAPI(cfg.Bot.Token)
 	if err != nil {
 		log.Panic(err)
 	}
 
-	bot.Debug = true
-	secret := os.Getenv("COACH_SECRET")
-	handler := handler.NewTelegramHandler(repo, bot, secret)
+	bot.Debug = cfg.Bot.Debug
+	handler := handler.NewTelegramHandler(repo, messenger.NewTelegram(bot), cfg)
+
+	if err := handler.SetBotCommands(); err != nil {
+		log.Fatalf("не удалось установить команды бота: %v", err)
+	}
+	handler.BootstrapAdmins(ctx)
+
+	var (
+		updates  tgbotapi.UpdatesChannel
+		serveErr <-chan error // ошибка webhook-сервера, nil в режиме polling
+	)
+	if cfg.Bot.Mode == config.ModeWebhook {
+		updates, serveErr = startWebhook(ctx, bot, cfg.Webhook, cfg.Bot.ShutdownTimeout.Std())
+	} else {
+		updates = startPolling(ctx, bot)
+	}
+
+	// Обработчики живут дольше ctx: после SIGTERM они дорабатывают до дедлайна
+	handlerCtx, cancelHandlers := context.WithCancel(context.Background())
+	defer cancelHandlers()
+
+	dispatcher := app.NewDispatcher(
+		handlerCtx,
+		cfg.Dispatch.Workers,
+		cfg.Dispatch.QueueDepth,
+		handler.HandleUpdate,
+	)
+
+	if ttl := cfg.Points.RequestTTL.Std(); ttl > 0 {
+		go app.ExpireRequests(ctx, repo, ttl)
+	}
+
+	// Main loop
+	for update := range updates {
+		dispatcher.Submit(update)
+	}
+
+	log.Println("🛑 Stopping: waiting for in-flight updates...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Bot.ShutdownTimeout.Std())
+	defer cancel()
+	if err := dispatcher.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️  handlers did not finish in time: %v", err)
+		cancelHandlers()
+	}
+	handler.FlushNotifications()
+
+	if err := db.Close(); err != nil {
+		log.Printf("⚠️  failed to close database: %v", err)
+	}
+	if serveErr != nil {
+		if err := <-serveErr; err != nil {
+			log.Fatalf("❌ webhook server stopped: %v", err)
+		}
+	}
+	log.Println("👋 Bot stopped")
+}
+
+// startPolling receives updates with getUpdates until ctx is cancelled.
+// The returned channel is closed right away on cancellation instead of
+// waiting for the current long poll to time out.
+func startPolling(ctx context.Context, bot *tgbotapi.BotAPI) tgbotapi.UpdatesChannel {
+	// getUpdates не работает, пока зарегистрирован webhook
+	if _, err := bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Fatalf("не удалось удалить webhook: %v", err)
+	}
 
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 	updates := bot.GetUpdatesChan(u)
 
-	// if err := handler.SetBotCommands(); err != nil {
-	// 	log.Fatalf("не удалось установить команды бота: %v", err)
-	// }
+	out := make(chan tgbotapi.Update)
+	go func() {
+		defer close(out)
+		defer bot.StopReceivingUpdates()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+				select {
+				case out <- update:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
 
-	// Main loop
-	for update := range updates {
-		handler.HandleUpdate(update)
+	return out
+}
+
+// startWebhook registers the webhook and serves it until ctx is cancelled.
+// The error channel receives the result of Run once the updates channel is closed.
+func startWebhook(ctx context.Context, bot *tgbotapi.BotAPI, cfg config.WebhookConfig, shutdownTimeout time.Duration) (tgbotapi.UpdatesChannel, <-chan error) {
+	server, err := app.NewWebhookServer(cfg.URL, cfg.Listen, cfg.Secret, shutdownTimeout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// порт занимаем до setWebhook, иначе Telegram начнёт слать запросы в пустоту
+	if err := server.Bind(); err != nil {
+		log.Fatal(err)
 	}
+	if err := server.Register(bot); err != nil {
+		log.Fatal(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.Run(ctx) }()
+
+	return server.Updates(), errCh
 }
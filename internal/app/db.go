@@ -1,26 +1,54 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"log"
-	"os"
+	"time"
+
+	"surf_bot/internal/config"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
 
-// InitDB initializes and returns a PostgreSQL connection via sqlx
-func InitDB() *sqlx.DB {
-	dsn := "host=" + os.Getenv("POSTGRES_HOST") +
-		" port=" + os.Getenv("POSTGRES_PORT") +
-		" user=" + os.Getenv("POSTGRES_USER") +
-		" password=" + os.Getenv("POSTGRES_PASSWORD") +
-		" dbname=" + os.Getenv("POSTGRES_DB") +
-		" sslmode=disable"
-
-	db, err := sqlx.Connect("postgres", dsn)
+// InitDB opens a PostgreSQL connection via sqlx and waits until the server
+// accepts connections, retrying with exponential backoff for up to cfg.WaitTimeout.
+func InitDB(ctx context.Context, cfg config.DBConfig) (*sqlx.DB, error) {
+	db, err := sqlx.Open("postgres", cfg.DSN())
 	if err != nil {
-		log.Fatalf("failed to connect to database: %v", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	return db
+	if err := waitForDB(ctx, db, cfg.WaitTimeout.Std()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// waitForDB pings the database until it answers or the timeout expires
+func waitForDB(ctx context.Context, db *sqlx.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	delay := 250 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			log.Println("✅ PostgreSQL is ready")
+			return nil
+		}
+
+		log.Printf("⏳ Waiting for PostgreSQL (attempt %d): %v", attempt, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to connect to database: %w", err)
+		case <-time.After(delay):
+		}
+
+		if delay < 5*time.Second {
+			delay *= 2
+		}
+	}
 }
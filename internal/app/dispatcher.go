@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Dispatcher runs update handlers concurrently across chats while keeping
+// updates of a single chat strictly in the order they arrived.
+type Dispatcher struct {
+	ctx     context.Context // передаётся обработчикам; его отмена прерывает работу
+	handle  func(context.Context, tgbotapi.Update)
+	workers chan struct{} // ограничивает число одновременно работающих обработчиков
+	slots   chan struct{} // ограничивает число принятых, но ещё не обработанных апдейтов
+
+	mu     sync.Mutex
+	queues map[int64][]tgbotapi.Update // очередь на чат; ключ есть, пока чат обрабатывается
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher with at most workers handlers running at once
+// and at most queueDepth updates waiting or in progress. Handlers receive ctx;
+// once it is cancelled, queued updates are dropped instead of handled.
+func NewDispatcher(ctx context.Context, workers, queueDepth int, handle func(context.Context, tgbotapi.Update)) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueDepth < workers {
+		queueDepth = workers
+	}
+	return &Dispatcher{
+		ctx:     ctx,
+		handle:  handle,
+		workers: make(chan struct{}, workers),
+		slots:   make(chan struct{}, queueDepth),
+		queues:  make(map[int64][]tgbotapi.Update),
+	}
+}
+
+// Submit queues an update. It blocks while the queue is full.
+func (d *Dispatcher) Submit(update tgbotapi.Update) {
+	d.slots <- struct{}{}
+
+	key := chatKey(update)
+
+	d.mu.Lock()
+	queue, running := d.queues[key]
+	d.queues[key] = append(queue, update)
+	if !running {
+		d.wg.Add(1)
+		go d.drain(key)
+	}
+	d.mu.Unlock()
+}
+
+// Shutdown waits until every submitted update has been handled or ctx expires.
+// Submit must not be called after Shutdown.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drain handles queued updates of one chat one by one until the queue is empty
+func (d *Dispatcher) drain(key int64) {
+	defer d.wg.Done()
+
+	for {
+		d.mu.Lock()
+		queue := d.queues[key]
+		if len(queue) == 0 {
+			delete(d.queues, key)
+			d.mu.Unlock()
+			return
+		}
+		update := queue[0]
+		d.queues[key] = queue[1:]
+		d.mu.Unlock()
+
+		if d.ctx.Err() != nil {
+			log.Printf("⚠️  dropping update %d: %v", update.UpdateID, d.ctx.Err())
+		} else {
+			d.workers <- struct{}{}
+			d.run(update)
+			<-d.workers
+		}
+		<-d.slots
+	}
+}
+
+// run calls the handler and keeps a panic from taking the whole bot down
+func (d *Dispatcher) run(update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("🔥 panic while handling update %d: %v\n%s", update.UpdateID, r, debug.Stack())
+		}
+	}()
+	d.handle(d.ctx, update)
+}
+
+// chatKey returns the chat an update belongs to; updates without a chat share key 0
+func chatKey(update tgbotapi.Update) int64 {
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+	if from := update.SentFrom(); from != nil {
+		return from.ID
+	}
+	return 0
+}
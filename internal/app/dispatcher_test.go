@@ -0,0 +1,188 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func chatUpdate(id int, chatID int64) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: id, Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: chatID}}}
+}
+
+func shutdown(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDispatcherChatOrder(t *testing.T) {
+	var mu sync.Mutex
+	handled := make(map[int64][]int)
+	d := NewDispatcher(context.Background(), 4, 100, func(_ context.Context, u tgbotapi.Update) {
+		time.Sleep(time.Duration(u.UpdateID%3) * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		chat := u.Message.Chat.ID
+		handled[chat] = append(handled[chat], u.UpdateID)
+	})
+
+	want := make(map[int64][]int)
+	for id := 1; id <= 60; id++ {
+		chat := int64(id % 3)
+		want[chat] = append(want[chat], id)
+		d.Submit(chatUpdate(id, chat))
+	}
+	shutdown(t, d)
+
+	for chat, ids := range want {
+		if !slices.Equal(handled[chat], ids) {
+			t.Fatalf("chat %d handled %v, want %v", chat, handled[chat], ids)
+		}
+	}
+}
+
+func TestDispatcherWorkerLimit(t *testing.T) {
+	var active, peak atomic.Int32
+	release := make(chan struct{})
+	d := NewDispatcher(context.Background(), 2, 10, func(context.Context, tgbotapi.Update) {
+		n := active.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		active.Add(-1)
+	})
+
+	for id := 1; id <= 5; id++ {
+		d.Submit(chatUpdate(id, int64(id)))
+	}
+	waitFor(t, "two running handlers", func() bool { return active.Load() == 2 })
+	time.Sleep(20 * time.Millisecond)
+	if p := peak.Load(); p != 2 {
+		t.Fatalf("peak concurrency = %d, want 2", p)
+	}
+	close(release)
+	shutdown(t, d)
+}
+
+func TestDispatcherPanic(t *testing.T) {
+	var handled []int
+	d := NewDispatcher(context.Background(), 1, 10, func(_ context.Context, u tgbotapi.Update) {
+		if u.UpdateID == 1 {
+			panic("boom")
+		}
+		handled = append(handled, u.UpdateID)
+	})
+
+	d.Submit(chatUpdate(1, 7))
+	d.Submit(chatUpdate(2, 7))
+	d.Submit(chatUpdate(3, 8))
+	shutdown(t, d)
+
+	slices.Sort(handled)
+	if !slices.Equal(handled, []int{2, 3}) {
+		t.Fatalf("handled %v after a panic, want [2 3]", handled)
+	}
+}
+
+func TestDispatcherFullQueue(t *testing.T) {
+	release := make(chan struct{})
+	d := NewDispatcher(context.Background(), 1, 2, func(context.Context, tgbotapi.Update) { <-release })
+
+	d.Submit(chatUpdate(1, 1))
+	d.Submit(chatUpdate(2, 2))
+
+	submitted := make(chan struct{})
+	go func() {
+		d.Submit(chatUpdate(3, 3))
+		close(submitted)
+	}()
+	select {
+	case <-submitted:
+		t.Fatal("Submit did not block on a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-submitted:
+	case <-time.After(time.Second):
+		t.Fatal("Submit stayed blocked after the queue drained")
+	}
+	shutdown(t, d)
+}
+
+func TestDispatcherShutdown(t *testing.T) {
+	t.Run("drains the queue", func(t *testing.T) {
+		var handled atomic.Int32
+		d := NewDispatcher(context.Background(), 2, 10, func(context.Context, tgbotapi.Update) {
+			time.Sleep(5 * time.Millisecond)
+			handled.Add(1)
+		})
+		for id := 1; id <= 6; id++ {
+			d.Submit(chatUpdate(id, int64(id%2)))
+		}
+		shutdown(t, d)
+		if n := handled.Load(); n != 6 {
+			t.Fatalf("handled %d updates before Shutdown returned, want 6", n)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		d := NewDispatcher(context.Background(), 1, 10, func(context.Context, tgbotapi.Update) { <-release })
+		d.Submit(chatUpdate(1, 1))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := d.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Shutdown = %v, want deadline exceeded", err)
+		}
+	})
+
+	t.Run("cancelled context drops queued updates", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+		var handled []int
+		d := NewDispatcher(ctx, 1, 10, func(ctx context.Context, u tgbotapi.Update) {
+			handled = append(handled, u.UpdateID)
+			close(started)
+			<-ctx.Done()
+		})
+		d.Submit(chatUpdate(1, 1))
+		d.Submit(chatUpdate(2, 1))
+		<-started
+		cancel()
+		shutdown(t, d)
+
+		if !slices.Equal(handled, []int{1}) {
+			t.Fatalf("handled %v, want only the update in progress", handled)
+		}
+	})
+}
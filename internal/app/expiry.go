@@ -0,0 +1,40 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RequestExpirer is the part of the repository ExpireRequests needs
+type RequestExpirer interface {
+	ExpirePendingRequests(ctx context.Context, maxAge time.Duration) (int64, error)
+}
+
+// ExpireRequests periodically marks pending requests older than ttl as expired
+// until ctx is cancelled.
+func ExpireRequests(ctx context.Context, repo RequestExpirer, ttl time.Duration) {
+	// проверяем чаще, чем истекает срок, но не чаще раза в минуту
+	interval := ttl / 10
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := repo.ExpirePendingRequests(ctx, ttl)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("⚠️  %v", err)
+		} else if n > 0 {
+			log.Printf("⌛ Expired %d pending requests", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
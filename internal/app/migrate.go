@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"surf_bot/migrations"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pressly/goose/v3"
+)
+
+// Migrator applies the SQL migrations embedded into the binary
+type Migrator struct {
+	provider *goose.Provider
+}
+
+func NewMigrator(db *sqlx.DB) (*Migrator, error) {
+	provider, err := goose.NewProvider(goose.DialectPostgres, db.DB, migrations.FS)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить миграции: %w", err)
+	}
+	return &Migrator{provider: provider}, nil
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up(ctx context.Context) error {
+	results, err := m.provider.Up(ctx)
+	for _, r := range results {
+		log.Printf("🧱 up %s (%s)", r.Source.Path, r.Duration)
+	}
+	if err != nil {
+		return fmt.Errorf("миграция не применена: %w", err)
+	}
+	if len(results) == 0 {
+		log.Println("✅ Migrations are up to date")
+	}
+	return nil
+}
+
+// Down rolls back the most recent migration
+func (m *Migrator) Down(ctx context.Context) error {
+	r, err := m.provider.Down(ctx)
+	if r != nil {
+		log.Printf("↩️  down %s (%s)", r.Source.Path, r.Duration)
+	}
+	if err != nil {
+		return fmt.Errorf("откат не выполнен: %w", err)
+	}
+	return nil
+}
+
+// Status prints every migration with its state
+func (m *Migrator) Status(ctx context.Context) error {
+	statuses, err := m.provider.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("не удалось получить статус миграций: %w", err)
+	}
+	for _, s := range statuses {
+		applied := "pending"
+		if s.State == goose.StateApplied {
+			applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%-20s %s\n", applied, s.Source.Path)
+	}
+	return nil
+}
+
+// Reset rolls back every applied migration
+func (m *Migrator) Reset(ctx context.Context) error {
+	results, err := m.provider.DownTo(ctx, 0)
+	for _, r := range results {
+		log.Printf("↩️  down %s (%s)", r.Source.Path, r.Duration)
+	}
+	if err != nil {
+		return fmt.Errorf("откат не выполнен: %w", err)
+	}
+	return nil
+}
+
+// RoundTrip applies every migration, rolls all of them back and applies them
+// again. It only runs against a database with no applied migrations so no data
+// is lost. TestMigrationsRoundTrip does the same on every go test run with a
+// test database.
+func (m *Migrator) RoundTrip(ctx context.Context) error {
+	version, err := m.provider.GetDBVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("не удалось получить версию базы: %w", err)
+	}
+	if version != 0 {
+		return fmt.Errorf("roundtrip запускается только на пустой базе, текущая версия %d", version)
+	}
+
+	if err := m.Up(ctx); err != nil {
+		return err
+	}
+	if err := m.Reset(ctx); err != nil {
+		return err
+	}
+	return m.Up(ctx)
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"surf_bot/internal/testdb"
+)
+
+// Runs up → down to zero → up over the embedded migrations. Every down must
+// undo its up completely, otherwise leftovers show up after the reset or the
+// second up fails (like the old down of users that dropped "user"). The schema
+// after the second up must match the first one column by column.
+func TestMigrationsRoundTrip(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	tables, columns, enums := testdb.Tables(t, db), testdb.Columns(t, db), testdb.Enums(t, db)
+
+	if err := m.Reset(ctx); err != nil {
+		t.Fatalf("down to 0: %v", err)
+	}
+	if left := slices.DeleteFunc(testdb.Tables(t, db), func(name string) bool { return name == "goose_db_version" }); len(left) > 0 {
+		t.Fatalf("после отката остались таблицы: %v", left)
+	}
+	if left := testdb.Enums(t, db); len(left) > 0 {
+		t.Fatalf("после отката остались типы: %v", left)
+	}
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("second up: %v", err)
+	}
+	compare := func(what string, before, after []string) {
+		t.Helper()
+		if !slices.Equal(before, after) {
+			t.Fatalf("%s после повторного up отличаются:\n%v\n%v", what, before, after)
+		}
+	}
+	compare("таблицы", tables, testdb.Tables(t, db))
+	compare("колонки", columns, testdb.Columns(t, db))
+	compare("перечисления", enums, testdb.Enums(t, db))
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// secretHeader is sent by Telegram with every webhook call when a secret token is registered
+const secretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// WebhookServer receives updates pushed by Telegram and feeds them into the
+// same channel the long-polling loop reads from.
+type WebhookServer struct {
+	URL    string // публичный адрес, который видит Telegram
+	Listen string // адрес, на котором слушает сервер, например ":8080"
+	Secret string
+
+	// ShutdownTimeout ограничивает ожидание текущих запросов при остановке
+	ShutdownTimeout time.Duration
+
+	updates  chan tgbotapi.Update
+	server   *http.Server
+	listener net.Listener
+
+	mu       sync.Mutex
+	done     chan struct{}  // закрывается при остановке, ServeHTTP перестаёт ждать место в очереди
+	inflight sync.WaitGroup // ServeHTTP, которые ещё могут писать в updates
+}
+
+// NewWebhookServer prepares a server for the given public URL; call Run to start it.
+func NewWebhookServer(publicURL, listen, secret string, shutdownTimeout time.Duration) (*WebhookServer, error) {
+	u, err := url.Parse(publicURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("некорректный WEBHOOK_URL %q", publicURL)
+	}
+	if secret == "" {
+		return nil, errors.New("WEBHOOK_SECRET обязателен в режиме webhook")
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	s := &WebhookServer{
+		URL:             publicURL,
+		Listen:          listen,
+		Secret:          secret,
+		ShutdownTimeout: shutdownTimeout,
+		updates:         make(chan tgbotapi.Update, 100),
+		done:            make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, s)
+	s.server = &http.Server{
+		Addr:              listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s, nil
+}
+
+// Updates returns the channel with incoming updates. It is closed when Run stops the server.
+func (s *WebhookServer) Updates() tgbotapi.UpdatesChannel {
+	return s.updates
+}
+
+// Bind opens the listening socket. Call it before Register, so Telegram is
+// never pointed at an address the bot cannot serve.
+func (s *WebhookServer) Bind() error {
+	ln, err := net.Listen("tcp", s.Listen)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть %s: %w", s.Listen, err)
+	}
+	s.listener = ln
+	return nil
+}
+
+// Register tells Telegram where to deliver updates and which secret to send along.
+// setWebhook's secret_token is newer than the bot library, so the call is made by hand.
+func (s *WebhookServer) Register(bot *tgbotapi.BotAPI) error {
+	params := tgbotapi.Params{
+		"url":          s.URL,
+		"secret_token": s.Secret,
+	}
+	if _, err := bot.MakeRequest("setWebhook", params); err != nil {
+		return fmt.Errorf("не удалось зарегистрировать webhook: %w", err)
+	}
+	return nil
+}
+
+// ServeHTTP accepts a single update from Telegram
+func (s *WebhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	got := r.Header.Get(secretHeader)
+	if subtle.ConstantTimeCompare([]byte(got), []byte(s.Secret)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&update); err != nil {
+		http.Error(w, "bad update: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !s.enter() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.inflight.Done()
+
+	// Telegram повторит доставку, если не получит 200
+	select {
+	case s.updates <- update:
+		w.WriteHeader(http.StatusOK)
+	case <-s.done:
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+	case <-r.Context().Done():
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}
+}
+
+// enter registers a handler that is about to write to updates; false once the server is stopping
+func (s *WebhookServer) enter() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	s.inflight.Add(1)
+	return true
+}
+
+// stop releases handlers waiting for the queue and closes updates once none of them can write to it
+func (s *WebhookServer) stop() {
+	s.mu.Lock()
+	close(s.done)
+	s.mu.Unlock()
+
+	s.inflight.Wait()
+	close(s.updates)
+}
+
+// Run serves webhook calls until ctx is cancelled, then shuts the server down
+// and closes the updates channel. It binds the socket itself if Bind was not called.
+func (s *WebhookServer) Run(ctx context.Context) error {
+	if s.listener == nil {
+		if err := s.Bind(); err != nil {
+			s.stop()
+			return err
+		}
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("🌐 Webhook server listening on %s", s.listener.Addr())
+		errCh <- s.server.Serve(s.listener)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+		s.stop()
+	case <-ctx.Done():
+		// Shutdown не отменяет r.Context(), поэтому ждущие очередь обработчики отпускаем сами
+		s.stop()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+		defer cancel()
+		err = s.server.Shutdown(shutdownCtx)
+		<-errCh
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func newTestWebhook(t *testing.T) *WebhookServer {
+	t.Helper()
+	s, err := NewWebhookServer("https://bot.example.com/hook", "127.0.0.1:0", "secret", time.Second)
+	if err != nil {
+		t.Fatalf("NewWebhookServer: %v", err)
+	}
+	return s
+}
+
+func postUpdate(t *testing.T, s *WebhookServer, secret string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := os.ReadFile("../../testdata/updates/start.json")
+	if err != nil {
+		t.Fatalf("read update: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(string(body)))
+	if secret != "" {
+		req.Header.Set(secretHeader, secret)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func TestWebhookSecret(t *testing.T) {
+	s := newTestWebhook(t)
+
+	tests := []struct {
+		name   string
+		secret string
+		want   int
+	}{
+		{"no secret", "", http.StatusForbidden},
+		{"wrong secret", "nope", http.StatusForbidden},
+		{"valid", "secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := postUpdate(t, s, tt.secret); w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	select {
+	case u := <-s.Updates():
+		if u.Message == nil || u.Message.Text != "/start" {
+			t.Fatalf("unexpected update %+v", u)
+		}
+	default:
+		t.Fatal("valid update was not queued")
+	}
+}
+
+// Run must report a port it cannot bind instead of leaving the bot without updates
+func TestWebhookBindError(t *testing.T) {
+	busy := newTestWebhook(t)
+	if err := busy.Bind(); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	defer busy.listener.Close()
+
+	s := newTestWebhook(t)
+	s.Listen = busy.listener.Addr().String()
+	if err := s.Run(context.Background()); err == nil {
+		t.Fatal("Run on a busy port returned nil")
+	}
+	if _, ok := <-s.Updates(); ok {
+		t.Fatal("updates channel is still open")
+	}
+}
+
+// A handler blocked on a full queue must not panic when Run closes the channel
+func TestWebhookShutdownWithFullQueue(t *testing.T) {
+	s := newTestWebhook(t)
+	for i := 0; i < cap(s.updates); i++ {
+		s.updates <- tgbotapi.Update{UpdateID: i}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.Run(ctx) }()
+
+	blocked := make(chan int, 1)
+	go func() { blocked <- postUpdate(t, s, "secret").Code }()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case code := <-blocked:
+		if code != http.StatusServiceUnavailable {
+			t.Fatalf("blocked handler status = %d, want 503", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocked handler was not released")
+	}
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("Run did not return")
+	}
+
+	if w := postUpdate(t, s, "secret"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status after stop = %d, want 503", w.Code)
+	}
+}
@@ -0,0 +1,425 @@
+// internal/config/config.go
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the whole bot configuration. It is loaded once at startup by Load
+// and passed down to the subsystems that need it.
+type Config struct {
+	Bot      BotConfig      `json:"bot"`
+	Webhook  WebhookConfig  `json:"webhook"`
+	DB       DBConfig       `json:"db"`
+	Coach    CoachConfig    `json:"coach"`
+	Admin    AdminConfig    `json:"admin"`
+	Dispatch DispatchConfig `json:"dispatch"`
+	Points   PointsConfig   `json:"points"`
+	Notify   NotifyConfig   `json:"notify"`
+}
+
+type BotConfig struct {
+	Token           string   `json:"token"`
+	Debug           bool     `json:"debug"`
+	Mode            string   `json:"mode"` // polling | webhook
+	UpdateTimeout   Duration `json:"update_timeout"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+}
+
+type WebhookConfig struct {
+	URL    string `json:"url"`
+	Listen string `json:"listen"`
+	Secret string `json:"secret"`
+}
+
+type DBConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	SSLMode  string `json:"sslmode"`
+
+	WaitTimeout Duration `json:"wait_timeout"` // сколько ждать готовности базы при старте
+	AutoMigrate bool     `json:"auto_migrate"` // применять миграции при запуске бота
+}
+
+type CoachConfig struct {
+	// Secret allows legacy registration via /coach <secret>; empty disables it.
+	// Prefer one-time invitations made with /coach_invite.
+	Secret string `json:"secret"`
+	// InviteTTL is how long a /coach_invite link stays valid
+	InviteTTL Duration `json:"invite_ttl"`
+}
+
+type AdminConfig struct {
+	// IDs are Telegram user IDs that are made admins at startup
+	IDs []int64 `json:"ids"`
+}
+
+type DispatchConfig struct {
+	Workers    int `json:"workers"`
+	QueueDepth int `json:"queue_depth"`
+}
+
+type PointsConfig struct {
+	// RequestTTL closes pending requests older than this as expired; 0 disables it
+	RequestTTL Duration `json:"request_ttl"`
+	// BulkConfirm is how many requests a bulk /approve or /reject may touch without confirmation
+	BulkConfirm int `json:"bulk_confirm"`
+}
+
+type NotifyConfig struct {
+	// DigestWindow is how long requests are collected before a digest goes out
+	DigestWindow Duration `json:"digest_window"`
+	// BurstThreshold switches an instant-mode coach to a digest for the rest of
+	// the window once this many requests reached them within it; 0 disables it
+	BurstThreshold int `json:"burst_threshold"`
+}
+
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+
+	// minCoachSecretLen is the shortest coach secret accepted at startup
+	minCoachSecretLen = 16
+)
+
+var (
+	sslModes          = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	webhookSecretExpr = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+)
+
+// Default returns the configuration used when nothing overrides a value
+func Default() Config {
+	return Config{
+		Bot: BotConfig{
+			Mode:            ModePolling,
+			UpdateTimeout:   Duration(30 * time.Second),
+			ShutdownTimeout: Duration(20 * time.Second),
+		},
+		Webhook: WebhookConfig{
+			Listen: ":8080",
+		},
+		Coach: CoachConfig{
+			InviteTTL: Duration(72 * time.Hour),
+		},
+		DB: DBConfig{
+			Port:        5432,
+			SSLMode:     "disable",
+			WaitTimeout: Duration(60 * time.Second),
+		},
+		Dispatch: DispatchConfig{
+			Workers:    8,
+			QueueDepth: 1000,
+		},
+		Points: PointsConfig{
+			BulkConfirm: 10,
+		},
+		Notify: NotifyConfig{
+			DigestWindow:   Duration(15 * time.Minute),
+			BurstThreshold: 5,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the optional JSON file named by
+// CONFIG_FILE and environment variables (in that order), then validates it.
+func Load() (Config, error) {
+	cfg, err := load()
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// LoadDB is like Load but only validates the database section. It is used by
+// commands that do not talk to Telegram, such as migrate.
+func LoadDB() (Config, error) {
+	cfg, err := load()
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, errors.Join(cfg.validateDB()...)
+}
+
+func load() (Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return cfg, err
+		}
+	}
+
+	return cfg, cfg.loadEnv()
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать файл конфигурации: %w", err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("ошибка в файле конфигурации %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	var errs []error
+
+	str := func(name string, dst *string) {
+		if v, ok := os.LookupEnv(name); ok {
+			*dst = v
+		}
+	}
+	num := func(name string, dst *int) {
+		if v, ok := os.LookupEnv(name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: ожидается число, получено %q", name, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	flag := func(name string, dst *bool) {
+		if v, ok := os.LookupEnv(name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: ожидается true/false, получено %q", name, v))
+				return
+			}
+			*dst = b
+		}
+	}
+	dur := func(name string, dst *Duration) {
+		if v, ok := os.LookupEnv(name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: ожидается длительность вроде 30s, получено %q", name, v))
+				return
+			}
+			*dst = Duration(d)
+		}
+	}
+
+	str("BOT_TOKEN", &c.Bot.Token)
+	flag("BOT_DEBUG", &c.Bot.Debug)
+	str("BOT_MODE", &c.Bot.Mode)
+	dur("UPDATE_TIMEOUT", &c.Bot.UpdateTimeout)
+	dur("SHUTDOWN_TIMEOUT", &c.Bot.ShutdownTimeout)
+
+	str("WEBHOOK_URL", &c.Webhook.URL)
+	str("WEBHOOK_LISTEN", &c.Webhook.Listen)
+	str("WEBHOOK_SECRET", &c.Webhook.Secret)
+
+	str("POSTGRES_HOST", &c.DB.Host)
+	num("POSTGRES_PORT", &c.DB.Port)
+	str("POSTGRES_USER", &c.DB.User)
+	str("POSTGRES_PASSWORD", &c.DB.Password)
+	str("POSTGRES_DB", &c.DB.Name)
+	str("POSTGRES_SSLMODE", &c.DB.SSLMode)
+	dur("DB_WAIT_TIMEOUT", &c.DB.WaitTimeout)
+	flag("DB_AUTO_MIGRATE", &c.DB.AutoMigrate)
+
+	str("COACH_SECRET", &c.Coach.Secret)
+	dur("COACH_INVITE_TTL", &c.Coach.InviteTTL)
+
+	if v, ok := os.LookupEnv("ADMIN_IDS"); ok {
+		c.Admin.IDs = nil
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("ADMIN_IDS: ожидается список чисел через запятую, получено %q", v))
+				break
+			}
+			c.Admin.IDs = append(c.Admin.IDs, id)
+		}
+	}
+
+	num("DISPATCH_WORKERS", &c.Dispatch.Workers)
+	num("DISPATCH_QUEUE_DEPTH", &c.Dispatch.QueueDepth)
+
+	dur("POINT_REQUEST_TTL", &c.Points.RequestTTL)
+	num("POINT_BULK_CONFIRM", &c.Points.BulkConfirm)
+	dur("NOTIFY_DIGEST_WINDOW", &c.Notify.DigestWindow)
+	num("NOTIFY_BURST_THRESHOLD", &c.Notify.BurstThreshold)
+
+	return errors.Join(errs...)
+}
+
+// Validate reports every missing or unsafe value at once
+func (c Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Bot.Token == "" {
+		fail("BOT_TOKEN не задан")
+	}
+	if c.Bot.UpdateTimeout <= 0 {
+		fail("UPDATE_TIMEOUT должен быть больше нуля")
+	}
+	if c.Bot.ShutdownTimeout <= 0 {
+		fail("SHUTDOWN_TIMEOUT должен быть больше нуля")
+	}
+
+	switch c.Bot.Mode {
+	case ModePolling:
+	case ModeWebhook:
+		u, err := url.Parse(c.Webhook.URL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			fail("WEBHOOK_URL должен быть https-адресом, получено %q", c.Webhook.URL)
+		}
+		if !webhookSecretExpr.MatchString(c.Webhook.Secret) {
+			fail("WEBHOOK_SECRET обязателен в режиме webhook: 1–256 символов A-Z, a-z, 0-9, _ и -")
+		}
+		if c.Webhook.Listen == "" {
+			fail("WEBHOOK_LISTEN не задан")
+		}
+	default:
+		fail("BOT_MODE должен быть %s или %s, получено %q", ModePolling, ModeWebhook, c.Bot.Mode)
+	}
+
+	errs = append(errs, c.validateDB()...)
+
+	// пустой ключ отключает регистрацию по ключу, короткий легко подобрать
+	if s := c.Coach.Secret; s != "" && len(strings.TrimSpace(s)) < minCoachSecretLen {
+		fail("COACH_SECRET слишком короткий: нужно не меньше %d символов", minCoachSecretLen)
+	}
+	if c.Coach.InviteTTL <= 0 {
+		fail("COACH_INVITE_TTL должен быть больше нуля")
+	}
+
+	for _, id := range c.Admin.IDs {
+		if id <= 0 {
+			fail("ADMIN_IDS: некорректный ID %d", id)
+		}
+	}
+
+	if c.Dispatch.Workers <= 0 {
+		fail("DISPATCH_WORKERS должен быть больше нуля")
+	}
+	if c.Dispatch.QueueDepth < c.Dispatch.Workers {
+		fail("DISPATCH_QUEUE_DEPTH не может быть меньше DISPATCH_WORKERS")
+	}
+
+	if c.Points.RequestTTL < 0 {
+		fail("POINT_REQUEST_TTL не может быть отрицательным")
+	}
+	if c.Points.BulkConfirm < 0 {
+		fail("POINT_BULK_CONFIRM не может быть отрицательным")
+	}
+	if c.Notify.DigestWindow <= 0 {
+		fail("NOTIFY_DIGEST_WINDOW должен быть больше нуля")
+	}
+	if c.Notify.BurstThreshold < 0 {
+		fail("NOTIFY_BURST_THRESHOLD не может быть отрицательным")
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c Config) validateDB() []error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.DB.Host == "" {
+		fail("POSTGRES_HOST не задан")
+	}
+	if c.DB.Port <= 0 || c.DB.Port > 65535 {
+		fail("POSTGRES_PORT вне диапазона: %d", c.DB.Port)
+	}
+	if c.DB.User == "" {
+		fail("POSTGRES_USER не задан")
+	}
+	if c.DB.Name == "" {
+		fail("POSTGRES_DB не задан")
+	}
+	if !slices.Contains(sslModes, c.DB.SSLMode) {
+		fail("POSTGRES_SSLMODE должен быть одним из %s", strings.Join(sslModes, ", "))
+	}
+	if c.DB.WaitTimeout <= 0 {
+		fail("DB_WAIT_TIMEOUT должен быть больше нуля")
+	}
+	return errs
+}
+
+// Warnings lists allowed but risky settings worth logging at startup
+func (c Config) Warnings() []string {
+	var warnings []string
+	if c.Coach.Secret != "" {
+		warnings = append(warnings, "COACH_SECRET задан: регистрация тренеров по общему ключу устарела, используйте /coach_invite")
+	}
+	if len(c.Admin.IDs) == 0 {
+		warnings = append(warnings, "ADMIN_IDS не задан: назначить администратора можно только вручную в базе")
+	}
+	if c.Bot.Debug {
+		warnings = append(warnings, "BOT_DEBUG включён: содержимое запросов к Telegram попадёт в логи")
+	}
+	if c.DB.SSLMode == "disable" && c.DB.Host != "localhost" && c.DB.Host != "127.0.0.1" {
+		warnings = append(warnings, "соединение с PostgreSQL не шифруется (POSTGRES_SSLMODE=disable)")
+	}
+	return warnings
+}
+
+// DSN returns the PostgreSQL connection string
+func (d DBConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		quote(d.Host), d.Port, quote(d.User), quote(d.Password), quote(d.Name), d.SSLMode)
+}
+
+// quote escapes a value for a key=value connection string
+func quote(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+// Duration is a time.Duration written as "30s" in the config file
+type Duration time.Duration
+
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("длительность должна быть строкой вроде \"30s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// clearEnv hides settings of the surrounding environment from the test
+func clearEnv(t *testing.T) {
+	t.Helper()
+	prefixes := []string{"BOT_", "UPDATE_", "SHUTDOWN_", "WEBHOOK_", "POSTGRES_", "DB_", "COACH_",
+		"ADMIN_", "DISPATCH_", "POINT_", "NOTIFY_", "CONFIG_FILE"}
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		for _, p := range prefixes {
+			if strings.HasPrefix(name, p) {
+				t.Setenv(name, "") // восстановит исходное значение после теста
+				os.Unsetenv(name)
+			}
+		}
+	}
+}
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// valid returns a configuration that passes Validate
+func valid() Config {
+	c := Default()
+	c.Bot.Token = "token"
+	c.DB.Host = "localhost"
+	c.DB.User = "app"
+	c.DB.Name = "app"
+	return c
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeConfig(t, `{
+		"bot": {"token": "from-file", "mode": "polling"},
+		"db": {"host": "db", "user": "app", "name": "app"},
+		"dispatch": {"workers": 3},
+		"notify": {"digest_window": "5m"}
+	}`))
+	t.Setenv("BOT_TOKEN", "from-env")
+	t.Setenv("DISPATCH_WORKERS", "6")
+	t.Setenv("ADMIN_IDS", "1, 2")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Bot.Token != "from-env" || cfg.Dispatch.Workers != 6 {
+		t.Fatalf("env did not override the file: token %q, workers %d", cfg.Bot.Token, cfg.Dispatch.Workers)
+	}
+	if cfg.DB.Host != "db" || cfg.Notify.DigestWindow.Std() != 5*time.Minute {
+		t.Fatalf("file values lost: host %q, digest window %s", cfg.DB.Host, cfg.Notify.DigestWindow.Std())
+	}
+	if cfg.Dispatch.QueueDepth != 1000 || cfg.DB.Port != 5432 {
+		t.Fatalf("defaults lost: queue depth %d, port %d", cfg.Dispatch.QueueDepth, cfg.DB.Port)
+	}
+	if len(cfg.Admin.IDs) != 2 || cfg.Admin.IDs[1] != 2 {
+		t.Fatalf("admin IDs = %v", cfg.Admin.IDs)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want string
+	}{
+		{name: "unknown field", file: `{"bot": {"tokn": "x"}}`, want: "tokn"},
+		{name: "bad duration", file: `{"bot": {"update_timeout": 30}}`, want: "длительность"},
+		{name: "missing file", file: "-", want: "не удалось прочитать"},
+		{name: "bad number", env: map[string]string{"DISPATCH_WORKERS": "many"}, want: "DISPATCH_WORKERS"},
+		{name: "bad flag", env: map[string]string{"DB_AUTO_MIGRATE": "yes please"}, want: "DB_AUTO_MIGRATE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			switch tt.file {
+			case "":
+			case "-":
+				t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.json"))
+			default:
+				t.Setenv("CONFIG_FILE", writeConfig(t, tt.file))
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Load() error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("valid config: %v", err)
+	}
+
+	webhook := func(c *Config) {
+		c.Bot.Mode = ModeWebhook
+		c.Webhook.URL = "https://bot.example.com/hook"
+		c.Webhook.Secret = "secret"
+	}
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   string
+	}{
+		{"webhook without URL", func(c *Config) { webhook(c); c.Webhook.URL = "" }, "WEBHOOK_URL"},
+		{"webhook over http", func(c *Config) { webhook(c); c.Webhook.URL = "http://bot.example.com" }, "WEBHOOK_URL"},
+		{"webhook without secret", func(c *Config) { webhook(c); c.Webhook.Secret = "" }, "WEBHOOK_SECRET"},
+		{"webhook secret with spaces", func(c *Config) { webhook(c); c.Webhook.Secret = "a b" }, "WEBHOOK_SECRET"},
+		{"unknown mode", func(c *Config) { c.Bot.Mode = "push" }, "BOT_MODE"},
+		{"no workers", func(c *Config) { c.Dispatch.Workers = 0 }, "DISPATCH_WORKERS"},
+		{"negative workers", func(c *Config) { c.Dispatch.Workers = -1 }, "DISPATCH_WORKERS"},
+		{"queue shorter than workers", func(c *Config) { c.Dispatch.QueueDepth = 2 }, "DISPATCH_QUEUE_DEPTH"},
+		{"short coach secret", func(c *Config) { c.Coach.Secret = "short" }, "COACH_SECRET"},
+		{"no token", func(c *Config) { c.Bot.Token = "" }, "BOT_TOKEN"},
+		{"bad sslmode", func(c *Config) { c.DB.SSLMode = "on" }, "POSTGRES_SSLMODE"},
+		{"negative burst", func(c *Config) { c.Notify.BurstThreshold = -1 }, "NOTIFY_BURST_THRESHOLD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.change(&c)
+			err := c.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate() = %v, want an error about %s", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("valid webhook", func(t *testing.T) {
+		c := valid()
+		webhook(&c)
+		if err := c.Validate(); err != nil {
+			t.Fatalf("Validate() = %v", err)
+		}
+	})
+}
+
+func TestDSN(t *testing.T) {
+	tests := []struct {
+		password string
+		want     string
+	}{
+		{"plain", "password=plain "},
+		{"", "password='' "},
+		{"with space", "password='with space' "},
+		{"it's", `password='it\'s' `},
+		{`back\slash`, `password='back\\slash' `},
+		{`' or '1'='1`, `password='\' or \'1\'=\'1' `},
+	}
+	for _, tt := range tests {
+		d := valid().DB
+		d.Password = tt.password
+		dsn := d.DSN()
+		if !strings.Contains(dsn, tt.want) {
+			t.Fatalf("DSN with password %q = %s, want %s", tt.password, dsn, tt.want)
+		}
+		// lib/pq разбирает строку при создании коннектора, не подключаясь к базе
+		if _, err := pq.NewConnector(dsn); err != nil {
+			t.Fatalf("lib/pq rejects %s: %v", dsn, err)
+		}
+	}
+}
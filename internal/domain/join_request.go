@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// JoinStatus is the state of a request to join a team
+type JoinStatus string
+
+const (
+	JoinPending  JoinStatus = "pending"
+	JoinApproved JoinStatus = "approved"
+	JoinDenied   JoinStatus = "denied"
+)
+
+// JoinRequest is a self-registration into a team. Until a coach approves it
+// the applicant has no account: no user row, no score and no team.
+type JoinRequest struct {
+	ID        int        `db:"id"`
+	UserID    int64      `db:"user_id"`
+	Name      string     `db:"name"`
+	Username  string     `db:"username"`
+	TeamID    int        `db:"team_id"`
+	TeamName  string     `db:"team_name"`
+	InviteID  *int       `db:"invite_id"`
+	Status    JoinStatus `db:"status"`
+	CreatedAt time.Time  `db:"created_at"`
+	DecidedBy *int64     `db:"decided_by"`
+	DecidedAt *time.Time `db:"decided_at"`
+}
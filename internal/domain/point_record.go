@@ -1,6 +1,37 @@
 package domain
 
+import "time"
+
+// PointStatus is the lifecycle state of a point entry
+type PointStatus string
+
+const (
+	PointPending   PointStatus = "pending"
+	PointApproved  PointStatus = "approved"
+	PointRejected  PointStatus = "rejected"
+	PointCancelled PointStatus = "cancelled"
+	PointExpired   PointStatus = "expired"
+)
+
 type PointRecord struct {
-	Amount int    `db:"amount"`
-	Reason string `db:"reason"`
+	ID        int         `db:"id"`
+	Amount    int         `db:"amount"`
+	Reason    string      `db:"reason"`
+	Status    PointStatus `db:"status"`
+	CreatedAt time.Time   `db:"created_at"`
+	DecidedAt *time.Time  `db:"decided_at"`
+	DecidedBy *string     `db:"decided_by"` // имя тренера
+	Comment   *string     `db:"decision_comment"`
+	Team      *string     `db:"team_name"` // команда, за которую начислены баллы
+
+	// Requested is what the athlete asked for when the coach granted a different amount
+	Requested *int `db:"requested_amount"`
+
+	// CorrectsID links a compensating entry written by /revoke or /adjust to the entry it corrects
+	CorrectsID *int `db:"corrects_id"`
+}
+
+// IsFine reports whether the entry is a deduction written by /fine
+func (r PointRecord) IsFine() bool {
+	return r.Amount < 0 && r.CorrectsID == nil
 }
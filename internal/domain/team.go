@@ -1,6 +1,74 @@
 package domain
 
+import "time"
+
 type Team struct {
 	ID   int    `db:"id"`
 	Name string `db:"name"`
+
+	// TokenInvites is set once the team has a token invite; numeric team_<id> links stop working
+	TokenInvites bool `db:"token_invites"`
+
+	// AllowNegative lets fines for this team take an athlete's score below zero
+	AllowNegative bool `db:"allow_negative"`
+
+	// FreeRequests lets athletes ask for any amount with their own reason; otherwise only the activity catalog is used
+	FreeRequests bool `db:"free_requests"`
+
+	// ArchivedAt hides the team from lists and stops its invites; history and ranking are kept
+	ArchivedAt *time.Time `db:"archived_at"`
+}
+
+// Activity is an entry of the team's catalog: something athletes do for a fixed number of points
+type Activity struct {
+	ID        int       `db:"id"`
+	TeamID    int       `db:"team_id"`
+	Name      string    `db:"name"`
+	Amount    int       `db:"amount"`
+	CreatedBy *int64    `db:"created_by"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// TeamImpact counts what deleting or archiving a team would touch
+type TeamImpact struct {
+	Members         int `db:"members"`
+	FormerMembers   int `db:"former_members"`
+	PendingRequests int `db:"pending_requests"`
+	PendingJoins    int `db:"pending_joins"`
+	ActiveInvites   int `db:"active_invites"`
+	PointEntries    int `db:"point_entries"`
+}
+
+// HasHistory reports whether the team has anything that deleting it would lose
+func (i TeamImpact) HasHistory() bool {
+	return i.Members+i.FormerMembers+i.PointEntries > 0
+}
+
+// Membership is a period the athlete spent in a team
+type Membership struct {
+	TeamID   int        `db:"team_id"`
+	TeamName string     `db:"team_name"`
+	JoinedAt time.Time  `db:"joined_at"`
+	LeftAt   *time.Time `db:"left_at"` // nil — состоит сейчас
+}
+
+// TeamInvite is an invite link into a team. Only the hash of its token is stored.
+type TeamInvite struct {
+	ID            int        `db:"id"`
+	TeamID        int        `db:"team_id"`
+	TokenHash     string     `db:"token_hash"`
+	CreatedBy     *int64     `db:"created_by"`
+	CreatedAt     time.Time  `db:"created_at"`
+	ExpiresAt     *time.Time `db:"expires_at"` // nil — бессрочно
+	MaxUses       *int       `db:"max_uses"`   // nil — без ограничений
+	Uses          int        `db:"uses"`
+	NeedsApproval bool       `db:"needs_approval"`
+	RevokedAt     *time.Time `db:"revoked_at"`
+}
+
+// Active reports whether the invite can still be used
+func (i TeamInvite) Active(now time.Time) bool {
+	return i.RevokedAt == nil &&
+		(i.ExpiresAt == nil || now.Before(*i.ExpiresAt)) &&
+		(i.MaxUses == nil || i.Uses < *i.MaxUses)
 }
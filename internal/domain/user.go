@@ -1,10 +1,27 @@
 package domain
 
+import "time"
+
 type Role string
 
 const (
 	RoleAthlete Role = "athlete"
 	RoleCoach   Role = "coach"
+	RoleAdmin   Role = "admin" // тренер без ограничений по командам, управляет ролями
+)
+
+// IsStaff reports whether the role may run coach commands
+func (r Role) IsStaff() bool {
+	return r == RoleCoach || r == RoleAdmin
+}
+
+// NotifyMode is how a coach wants to hear about new point requests
+type NotifyMode string
+
+const (
+	NotifyInstant NotifyMode = "instant" // сообщение на каждый запрос
+	NotifyDigest  NotifyMode = "digest"  // сводка раз в окно
+	NotifyOff     NotifyMode = "off"
 )
 
 type User struct {
@@ -18,4 +35,15 @@ type AthleteShort struct {
 	ID       int64  `db:"id"`
 	Name     string `db:"name"`
 	Username string `db:"username"`
-}
\ No newline at end of file
+}
+
+// RoleChange is an audit record of a user's role being changed
+type RoleChange struct {
+	ID        int       `db:"id"`
+	UserID    int64     `db:"user_id"`
+	Username  string    `db:"username"`
+	OldRole   Role      `db:"old_role"` // пусто — пользователь зарегистрирован сразу с новой ролью
+	NewRole   Role      `db:"new_role"`
+	ChangedBy *string   `db:"changed_by"` // имя администратора; nil — из конфигурации
+	CreatedAt time.Time `db:"created_at"`
+}
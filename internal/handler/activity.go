@@ -0,0 +1,294 @@
+// internal/handler/activity.go
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"surf_bot/internal/domain"
+	repo "surf_bot/internal/repository"
+	"surf_bot/internal/util"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Every team has a catalog of activities with fixed points. Athletes pick an
+// activity with a button ("act:request:<activity_id>"), and the request gets its
+// name and points. Requests with their own amount can be switched off per team.
+
+// activityRequest is the action of catalog buttons
+const activityRequest = "request"
+
+// maxActivityName keeps button labels readable
+const maxActivityName = 48
+
+func activityKeyboard(activities []domain.Activity) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, len(activities))
+	for i, a := range activities {
+		rows[i] = tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%s — %d", a.Name, a.Amount),
+			fmt.Sprintf("%s:%s:%d", callbackActivity, activityRequest, a.ID)))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// offerActivities shows the athlete the catalog of their team as buttons
+func (h *TelegramHandler) offerActivities(ctx context.Context, chatID int64, user *domain.User, teamArg int) {
+	teamID, ok := h.pointTeam(ctx, chatID, user, user, teamArg)
+	if !ok {
+		return
+	}
+	if teamID == nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("request")))
+		return
+	}
+
+	team, err := h.Repo.GetTeamByID(ctx, *teamID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+	activities, err := h.Repo.ListActivities(ctx, team.ID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+
+	if len(activities) == 0 {
+		if team.FreeRequests {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("request")))
+		} else {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+				"📭 В каталоге команды '%s' пока нет занятий. Попроси тренера их добавить.", team.Name)))
+		}
+		return
+	}
+
+	text := fmt.Sprintf("🏄 Выбери занятие, за которое просишь баллы (команда '%s'):", team.Name)
+	if team.FreeRequests {
+		text += "\n\nЕсли занятия нет в списке: /request <баллы> <причина>"
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = activityKeyboard(activities)
+	util.SafeSend(h.Bot, msg)
+}
+
+// handleActivityCallback creates a request for the activity the athlete picked
+func (h *TelegramHandler) handleActivityCallback(ctx context.Context, cb *tgbotapi.CallbackQuery, user *domain.User, action string, id int) {
+	chatID := cb.Message.Chat.ID
+
+	if !hasRole(user, domain.RoleAthlete) {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "🚫 Запросы на баллы отправляют спортсмены."))
+		return
+	}
+	if action != activityRequest {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "❓ Неизвестное действие."))
+		return
+	}
+
+	activity, err := h.Repo.GetActivity(ctx, id)
+	if err != nil {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "⚠️ Занятие убрали из каталога."))
+		h.closeRequestMessage(chatID, cb.Message.MessageID, cb.Message.Text, "⚠️ Каталог изменился, открой его заново: /request")
+		return
+	}
+
+	teams, err := h.Repo.ListUserTeams(ctx, user.ID)
+	if err != nil {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "❌ Не удалось отправить запрос."))
+		return
+	}
+	member := false
+	for _, t := range teams {
+		member = member || t.ID == activity.TeamID
+	}
+	if !member {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "🚫 Ты больше не состоишь в этой команде."))
+		h.closeRequestMessage(chatID, cb.Message.MessageID, cb.Message.Text, "🚫 Ты больше не состоишь в этой команде.")
+		return
+	}
+
+	requestID, err := h.Repo.CreatePendingRequest(ctx, user.ID, activity.Amount, activity.Name, &activity.TeamID)
+	if err != nil {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "❌ Не удалось отправить запрос."))
+		return
+	}
+
+	// кнопки убираем, чтобы повторное нажатие не создало второй такой же запрос
+	util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "📨 Запрос отправлен."))
+	h.closeRequestMessage(chatID, cb.Message.MessageID, cb.Message.Text, fmt.Sprintf(
+		"📨 Запрос #%d: %s — %d баллов, отправлен на подтверждение тренеру.", requestID, activity.Name, activity.Amount))
+	h.notifyCoaches(ctx, user.ID, &activity.TeamID, requestID)
+}
+
+// handleActivities shows the catalog: athletes get buttons to request points, coaches a list with IDs
+func (h *TelegramHandler) handleActivities(ctx context.Context, chatID int64, text string, user *domain.User) {
+	rest, teamArg, ok := cutTeamArg(strings.TrimPrefix(text, "/activities"))
+	if !ok || rest != "" {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("activities")))
+		return
+	}
+
+	if user.Role == domain.RoleAthlete {
+		h.offerActivities(ctx, chatID, user, teamArg)
+		return
+	}
+
+	var teams []domain.Team
+	var err error
+	switch {
+	case teamArg > 0:
+		if !h.requireTeam(ctx, chatID, user, teamArg) {
+			return
+		}
+		var team *domain.Team
+		if team, err = h.Repo.GetTeamByID(ctx, teamArg); err == nil {
+			teams = []domain.Team{*team}
+		}
+	case user.Role == domain.RoleAdmin:
+		teams, err = h.Repo.ListTeams(ctx)
+	default:
+		teams, err = h.Repo.ListCoachTeams(ctx, user.ID)
+	}
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+	if len(teams) == 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "📭 У тебя пока нет команд."))
+		return
+	}
+
+	var msg string
+	for _, t := range teams {
+		activities, err := h.Repo.ListActivities(ctx, t.ID)
+		if err != nil {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+			return
+		}
+
+		msg += fmt.Sprintf("📋 %s (ID: %d)\n", t.Name, t.ID)
+		for _, a := range activities {
+			msg += fmt.Sprintf("• #%d %s — %d баллов\n", a.ID, a.Name, a.Amount)
+		}
+		if len(activities) == 0 {
+			msg += "Каталог пуст.\n"
+		}
+		if t.FreeRequests {
+			msg += "✍️ Запросы со своими баллами разрешены.\n\n"
+		} else {
+			msg += "🔒 Только занятия из каталога.\n\n"
+		}
+	}
+	msg += "➕ /add_activity <team_id> <баллы> <название>\n➖ /remove_activity <id>"
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+}
+
+func (h *TelegramHandler) handleAddActivity(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.SplitN(text, " ", 4)
+	if len(args) < 4 || strings.TrimSpace(args[3]) == "" {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("add_activity")))
+		return
+	}
+
+	teamID, err := strconv.Atoi(args[1])
+	if err != nil || teamID <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный team_id."))
+		return
+	}
+	amount, err := strconv.Atoi(args[2])
+	if err != nil || amount <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректное число баллов больше нуля."))
+		return
+	}
+	name := strings.Join(strings.Fields(args[3]), " ")
+	if len([]rune(name)) > maxActivityName {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("❗ Название занятия должно быть не длиннее %d символов.", maxActivityName)))
+		return
+	}
+	if !h.requireTeam(ctx, chatID, user, teamID) || !h.requireActiveTeam(ctx, chatID, teamID) {
+		return
+	}
+
+	id, err := h.Repo.AddActivity(ctx, &domain.Activity{TeamID: teamID, Name: name, Amount: amount, CreatedBy: &user.ID})
+	if errors.Is(err, repo.ErrActivityExists) {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "⚠️ "+err.Error()+". Удали старое через /remove_activity и добавь заново."))
+		return
+	}
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+
+	log.Printf("📋 Activity #%d added to team #%d by %d", id, teamID, user.ID)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"✅ Занятие #%d «%s» — %d баллов добавлено в каталог команды #%d.", id, name, amount, teamID)))
+}
+
+func (h *TelegramHandler) handleRemoveActivity(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.Fields(text)
+	if len(args) != 2 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("remove_activity")))
+		return
+	}
+
+	id, err := strconv.Atoi(args[1])
+	if err != nil || id <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный ID занятия."))
+		return
+	}
+	activity, err := h.Repo.GetActivity(ctx, id)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Занятие #%d не найдено.", id)))
+		return
+	}
+	if !h.requireTeam(ctx, chatID, user, activity.TeamID) {
+		return
+	}
+
+	if err := h.Repo.RemoveActivity(ctx, id); err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+
+	log.Printf("📋 Activity #%d removed from team #%d by %d", id, activity.TeamID, user.ID)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"🗑 Занятие «%s» убрано из каталога. Уже отправленные запросы не изменятся.", activity.Name)))
+}
+
+// handleFreeRequests sets whether athletes of the team may request points outside the catalog
+func (h *TelegramHandler) handleFreeRequests(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.Fields(text)
+	if len(args) != 3 || (args[2] != "on" && args[2] != "off") {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("free_requests")))
+		return
+	}
+
+	teamID, err := strconv.Atoi(args[1])
+	if err != nil || teamID <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный team_id."))
+		return
+	}
+	if !h.requireTeam(ctx, chatID, user, teamID) {
+		return
+	}
+
+	allow := args[2] == "on"
+	if err := h.Repo.SetTeamFreeRequests(ctx, teamID, allow); err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+
+	msg := fmt.Sprintf("✅ В команде #%d спортсмены могут запрашивать свои баллы с любой причиной.", teamID)
+	if !allow {
+		msg = fmt.Sprintf("✅ В команде #%d баллы теперь запрашиваются только за занятия из каталога.", teamID)
+		if activities, err := h.Repo.ListActivities(ctx, teamID); err == nil && len(activities) == 0 {
+			msg += fmt.Sprintf("\n⚠️ Каталог пока пуст — добавь занятия: /add_activity %d <баллы> <название>", teamID)
+		}
+	}
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+}
@@ -0,0 +1,196 @@
+// internal/handler/admin.go
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"surf_bot/internal/domain"
+	repo "surf_bot/internal/repository"
+	"surf_bot/internal/util"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var roleNames = map[domain.Role]string{
+	domain.RoleAthlete: "спортсмен",
+	domain.RoleCoach:   "тренер",
+	domain.RoleAdmin:   "администратор",
+}
+
+// roleLadder orders roles for /promote and /demote
+var roleLadder = []domain.Role{domain.RoleAthlete, domain.RoleCoach, domain.RoleAdmin}
+
+// BootstrapAdmins makes the users listed in the config admins. Users that have
+// not started the bot yet become admins on their first /start.
+func (h *TelegramHandler) BootstrapAdmins(ctx context.Context) {
+	for _, id := range h.Config.Admin.IDs {
+		user, err := h.Repo.GetUserByID(ctx, id)
+		if err != nil {
+			log.Printf("⚠️  failed to load admin %d: %v", id, err)
+			continue
+		}
+		if user == nil || user.Role == domain.RoleAdmin {
+			continue
+		}
+		if _, err := h.Repo.SetUserRole(ctx, id, domain.RoleAdmin, nil); err != nil {
+			log.Printf("⚠️  failed to make %d an admin: %v", id, err)
+			continue
+		}
+		h.setChatMenu(id, domain.RoleAdmin)
+		log.Printf("👑 User %d (@%s) is now an admin", id, user.Username)
+	}
+}
+
+// isConfiguredAdmin reports whether the chat is listed in ADMIN_IDS
+func (h *TelegramHandler) isConfiguredAdmin(chatID int64) bool {
+	return slices.Contains(h.Config.Admin.IDs, chatID)
+}
+
+func (h *TelegramHandler) handlePromote(ctx context.Context, chatID int64, text string, admin *domain.User) {
+	h.moveRole(ctx, chatID, text, admin, "promote", +1)
+}
+
+func (h *TelegramHandler) handleDemote(ctx context.Context, chatID int64, text string, admin *domain.User) {
+	h.moveRole(ctx, chatID, text, admin, "demote", -1)
+}
+
+// moveRole moves a user one step up or down roleLadder
+func (h *TelegramHandler) moveRole(ctx context.Context, chatID int64, text string, admin *domain.User, command string, step int) {
+	args := strings.Fields(text)
+	if len(args) != 2 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage(command)))
+		return
+	}
+
+	target := h.findRoleTarget(ctx, chatID, args[1], admin)
+	if target == nil {
+		return
+	}
+
+	i := slices.Index(roleLadder, target.Role) + step
+	if i < 0 || i >= len(roleLadder) {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID,
+			fmt.Sprintf("ℹ️ @%s уже %s.", target.Username, roleNames[target.Role])))
+		return
+	}
+	h.changeRole(ctx, chatID, admin, target, roleLadder[i])
+}
+
+func (h *TelegramHandler) handleSetRole(ctx context.Context, chatID int64, text string, admin *domain.User) {
+	args := strings.Fields(text)
+	if len(args) != 3 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("set_role")))
+		return
+	}
+
+	role := domain.Role(strings.ToLower(args[2]))
+	if _, ok := roleNames[role]; !ok {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Роль должна быть athlete, coach или admin."))
+		return
+	}
+
+	target := h.findRoleTarget(ctx, chatID, args[1], admin)
+	if target == nil {
+		return
+	}
+	if target.Role == role {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID,
+			fmt.Sprintf("ℹ️ @%s уже %s.", target.Username, roleNames[role])))
+		return
+	}
+	h.changeRole(ctx, chatID, admin, target, role)
+}
+
+// findRoleTarget looks up the user whose role is being changed; admins cannot change their own role
+func (h *TelegramHandler) findRoleTarget(ctx context.Context, chatID int64, username string, admin *domain.User) *domain.User {
+	target, err := h.Repo.GetUserByUsername(ctx, strings.TrimPrefix(username, "@"))
+	if err != nil || target == nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Пользователь не найден."))
+		return nil
+	}
+	if target.ID == admin.ID {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Нельзя менять роль самому себе."))
+		return nil
+	}
+	return target
+}
+
+func (h *TelegramHandler) changeRole(ctx context.Context, chatID int64, admin, target *domain.User, role domain.Role) {
+	old, err := h.Repo.SetUserRole(ctx, target.ID, role, &admin.ID)
+	if errors.Is(err, repo.ErrLastCoach) {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"⚠️ @%s: %s.\nСначала добавь этим командам другого тренера через /add_coach.", target.Username, err.Error())))
+		return
+	}
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+
+	h.setChatMenu(target.ID, role)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(target.ID,
+		fmt.Sprintf("🔄 Твоя роль изменена: %s → %s.\n\n%s", roleNames[old], roleNames[role], helpText(role))))
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID,
+		fmt.Sprintf("✅ @%s: %s → %s.", target.Username, roleNames[old], roleNames[role])))
+}
+
+func (h *TelegramHandler) handleRoleLog(ctx context.Context, chatID int64, args string) {
+	limit := 20
+	if args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n <= 0 || n > 100 {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи число записей от 1 до 100."))
+			return
+		}
+		limit = n
+	}
+
+	changes, err := h.Repo.ListRoleChanges(ctx, limit)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+	if len(changes) == 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "📭 Роли ещё не менялись."))
+		return
+	}
+
+	msg := "📜 Журнал изменения ролей:\n\n"
+	for _, c := range changes {
+		by := "из конфигурации"
+		if c.ChangedBy != nil {
+			by = *c.ChangedBy
+		}
+		old := roleNames[c.OldRole]
+		if c.OldRole == "" {
+			old = "новый пользователь"
+		}
+		msg += fmt.Sprintf("• %s @%s: %s → %s (%s)\n",
+			c.CreatedAt.Format("02.01.2006 15:04"), c.Username, old, roleNames[c.NewRole], by)
+	}
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+}
+
+func (h *TelegramHandler) handleCoaches(ctx context.Context, chatID int64) {
+	staff, err := h.Repo.ListStaff(ctx)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+
+	msg := "👥 Тренеры и администраторы:\n\n"
+	for _, u := range staff {
+		icon := "🧑‍🏫"
+		if u.Role == domain.RoleAdmin {
+			icon = "👑"
+		}
+		msg += fmt.Sprintf("%s %s (@%s)\n", icon, u.Name, u.Username)
+	}
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+}
@@ -0,0 +1,254 @@
+// internal/handler/bulk.go
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"surf_bot/internal/domain"
+	repo "surf_bot/internal/repository"
+	"surf_bot/internal/util"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// /approve and /reject take a list of IDs ("3,5,9-14") or "all [team:<id>]".
+// Every request is decided in its own transaction, so one failure does not
+// stop the rest. Actions touching more than Points.BulkConfirm requests wait
+// for a confirmation button.
+
+const (
+	// maxBulkRequests bounds ranges like 1-100000 typed by mistake
+	maxBulkRequests = 500
+	// bulkConfirmTTL is how long a confirmation button stays valid
+	bulkConfirmTTL = 10 * time.Minute
+)
+
+// Actions of the confirmation buttons: "bulk:<action>:<seq>"
+const (
+	bulkRun    = "run"
+	bulkCancel = "cancel"
+)
+
+// bulkAction is a bulk decision waiting for confirmation
+type bulkAction struct {
+	Seq       int
+	Action    string // actionApprove или actionReject
+	IDs       []int
+	Reason    string
+	CreatedAt time.Time
+}
+
+// bulkFailure is a request that could not be decided and why
+type bulkFailure struct {
+	ID     int
+	Reason string
+}
+
+// parseRequestIDs parses "3,5,9-14" into IDs in the given order without repeats
+func parseRequestIDs(s string) ([]int, error) {
+	var ids []int
+	seen := map[int]bool{}
+	add := func(id int) error {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+		if len(ids) > maxBulkRequests {
+			return fmt.Errorf("за раз можно обработать не больше %d запросов", maxBulkRequests)
+		}
+		return nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		a, err := strconv.Atoi(from)
+		b := a
+		if err == nil && isRange {
+			b, err = strconv.Atoi(to)
+		}
+		if err != nil || a <= 0 || b < a {
+			return nil, fmt.Errorf("%q — укажи ID через запятую или диапазон вроде 9-14", part)
+		}
+		if b-a >= maxBulkRequests {
+			return nil, fmt.Errorf("за раз можно обработать не больше %d запросов", maxBulkRequests)
+		}
+		for id := a; id <= b; id++ {
+			if err := add(id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ids, nil
+}
+
+// bulkTargets resolves the request selector at the start of args and returns the remaining words.
+// "all" means every pending request the coach may decide, optionally only of one team.
+func (h *TelegramHandler) bulkTargets(ctx context.Context, chatID int64, user *domain.User, args []string) ([]int, []string, bool) {
+	if args[0] != "all" {
+		ids, err := parseRequestIDs(args[0])
+		if err != nil {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Не удалось разобрать ID: "+err.Error()))
+			return nil, nil, false
+		}
+		return ids, args[1:], true
+	}
+
+	rest := args[1:]
+	var teamID int
+	if len(rest) > 0 && strings.HasPrefix(rest[0], "team:") {
+		id, err := strconv.Atoi(strings.TrimPrefix(rest[0], "team:"))
+		if err != nil || id <= 0 {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный team_id."))
+			return nil, nil, false
+		}
+		if !h.requireTeam(ctx, chatID, user, id) {
+			return nil, nil, false
+		}
+		teamID, rest = id, rest[1:]
+	}
+
+	var reqs []repo.PendingRequest
+	var err error
+	switch {
+	case teamID > 0:
+		reqs, err = h.Repo.GetPendingRequestsByTeam(ctx, &teamID)
+	case user.Role == domain.RoleAdmin:
+		reqs, err = h.Repo.GetPendingRequests(ctx)
+	default:
+		reqs, err = h.Repo.GetPendingRequestsByCoach(ctx, user.ID)
+	}
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось получить запросы: "+err.Error()))
+		return nil, nil, false
+	}
+	if len(reqs) == 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "📭 Нет запросов на подтверждение."))
+		return nil, nil, false
+	}
+
+	ids := make([]int, len(reqs))
+	for i, r := range reqs {
+		ids[i] = r.ID
+	}
+	return ids, rest, true
+}
+
+// startBulk runs the action right away or, for many requests, asks to confirm it first
+func (h *TelegramHandler) startBulk(ctx context.Context, chatID int64, user *domain.User, action string, ids []int, reason string) {
+	if len(ids) <= h.Config.Points.BulkConfirm {
+		h.runBulk(ctx, chatID, user, action, ids, reason)
+		return
+	}
+
+	h.mu.Lock()
+	h.bulkSeq++
+	pending := bulkAction{Seq: h.bulkSeq, Action: action, IDs: ids, Reason: reason, CreatedAt: time.Now()}
+	h.bulks[chatID] = pending
+	h.mu.Unlock()
+
+	verb := "подтвердить"
+	if action == actionReject {
+		verb = "отклонить"
+	}
+	text := fmt.Sprintf("⚠️ Ты собираешься %s %d запросов: %s.", verb, len(ids), formatIDs(ids))
+	if reason != "" {
+		text += "\n💬 " + reason
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✅ Да, %s %d", verb, len(ids)),
+			fmt.Sprintf("%s:%s:%d", callbackBulk, bulkRun, pending.Seq)),
+		tgbotapi.NewInlineKeyboardButtonData("✖️ Отмена", fmt.Sprintf("%s:%s:%d", callbackBulk, bulkCancel, pending.Seq)),
+	))
+	util.SafeSend(h.Bot, msg)
+}
+
+// handleBulkCallback runs or drops a bulk action after its confirmation buttons
+func (h *TelegramHandler) handleBulkCallback(ctx context.Context, cb *tgbotapi.CallbackQuery, user *domain.User, action string, seq int) {
+	chatID := cb.Message.Chat.ID
+
+	h.mu.Lock()
+	pending, ok := h.bulks[chatID]
+	if ok && pending.Seq == seq {
+		delete(h.bulks, chatID)
+	}
+	h.mu.Unlock()
+
+	if !ok || pending.Seq != seq || time.Since(pending.CreatedAt) > bulkConfirmTTL {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "⚠️ Подтверждение устарело."))
+		h.closeRequestMessage(chatID, cb.Message.MessageID, cb.Message.Text, "⚠️ Подтверждение устарело, повтори команду.")
+		return
+	}
+
+	switch action {
+	case bulkRun:
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, ""))
+		h.closeRequestMessage(chatID, cb.Message.MessageID, cb.Message.Text, "⏳ Выполняю…")
+		h.runBulk(ctx, chatID, user, pending.Action, pending.IDs, pending.Reason)
+	case bulkCancel:
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, ""))
+		h.closeRequestMessage(chatID, cb.Message.MessageID, cb.Message.Text, "✖️ Отменено, запросы не изменились.")
+	default:
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "❓ Неизвестное действие."))
+	}
+}
+
+// runBulk decides every request on its own and reports what succeeded and what failed
+func (h *TelegramHandler) runBulk(ctx context.Context, chatID int64, user *domain.User, action string, ids []int, reason string) {
+	var done []int
+	var failed []bulkFailure
+
+	for _, id := range ids {
+		req, err := h.Repo.GetPendingRequest(ctx, id)
+		if err != nil {
+			failed = append(failed, bulkFailure{id, "не найден или уже обработан"})
+			continue
+		}
+		if !h.coachesRequest(ctx, user, req) {
+			failed = append(failed, bulkFailure{id, "спортсмен не из твоей команды"})
+			continue
+		}
+
+		if action == actionApprove {
+			grant, err := h.Repo.ApproveRequest(ctx, id, user.ID, nil, "")
+			if err != nil {
+				failed = append(failed, bulkFailure{id, err.Error()})
+				continue
+			}
+			h.notifyGrant(ctx, grant, fmt.Sprintf("✅ Твой запрос #%d подтверждён!", id), user.Name)
+		} else {
+			fromID, err := h.Repo.RejectRequest(ctx, id, user.ID, reason)
+			if err != nil {
+				failed = append(failed, bulkFailure{id, err.Error()})
+				continue
+			}
+			h.notifyRejection(fromID, id, user.Name, reason)
+		}
+		done = append(done, id)
+	}
+
+	verb := "Подтверждено"
+	if action == actionReject {
+		verb = "Отклонено"
+	}
+	msg := fmt.Sprintf("📋 %s %d из %d.", verb, len(done), len(ids))
+	if len(done) > 0 {
+		msg += "\n✅ " + formatIDs(done)
+	}
+	for _, f := range failed {
+		msg += fmt.Sprintf("\n❌ #%d — %s", f.ID, f.Reason)
+	}
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+}
+
+func formatIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("#%d", id)
+	}
+	return strings.Join(parts, ", ")
+}
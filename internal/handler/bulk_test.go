@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseRequestIDs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []int
+		err  string
+	}{
+		{in: "1-3,5", want: []int{1, 2, 3, 5}},
+		{in: "7", want: []int{7}},
+		{in: " 2 , 4-5 ", want: []int{2, 4, 5}},
+		{in: "3,1-3,3", want: []int{3, 1, 2}}, // повторы отбрасываются, порядок сохраняется
+		{in: "4-4", want: []int{4}},
+		{in: "3-1", err: `"3-1"`},
+		{in: "1,,2", err: `""`},
+		{in: "0", err: `"0"`},
+		{in: "-2", err: `"-2"`},
+		{in: "1-2-3", err: `"1-2-3"`},
+		{in: "x", err: `"x"`},
+		{in: "", err: `""`},
+		{in: "1-501", err: "не больше 500"},
+		{in: "1-1000000000", err: "не больше 500"},
+		{in: "1-300,301-600", err: "не больше 500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseRequestIDs(tt.in)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("parseRequestIDs(%q) = %v, %v; want error with %s", tt.in, got, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRequestIDs(%q): %v", tt.in, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("parseRequestIDs(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	// ровно предел ещё допустим
+	if ids, err := parseRequestIDs("1-500"); err != nil || len(ids) != maxBulkRequests {
+		t.Fatalf("parseRequestIDs(1-500) = %d IDs, %v; want %d", len(ids), err, maxBulkRequests)
+	}
+}
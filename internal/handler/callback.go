@@ -0,0 +1,294 @@
+// internal/handler/callback.go
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"surf_bot/internal/domain"
+	repo "surf_bot/internal/repository"
+	"surf_bot/internal/util"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Callback data for inline buttons: "<kind>:<action>:<id>".
+// Pending point requests use kind "req", join requests — "join",
+// confirmation of /delete_team — "team", of bulk /approve and /reject — "bulk",
+// the activity catalog athletes pick from — "act".
+const (
+	callbackRequest  = "req"
+	callbackJoin     = "join"
+	callbackTeam     = "team"
+	callbackBulk     = "bulk"
+	callbackActivity = "act"
+)
+
+const (
+	actionApprove = "approve"
+	actionReject  = "reject"
+	actionEdit    = "edit"
+)
+
+// promptTTL is how long a coach may take to answer "Отклонить" or "Изменить баллы"
+const promptTTL = 10 * time.Minute
+
+// replyPrompt remembers which pending request message a coach is answering:
+// a new amount after "Изменить баллы" or a reason after "Отклонить"
+type replyPrompt struct {
+	Action    string
+	RequestID int
+	MessageID int
+	Text      string    // текст сообщения с запросом до изменений
+	CreatedAt time.Time // после promptTTL ответ уже не принимается
+}
+
+func requestCallbackData(action string, id int) string {
+	return fmt.Sprintf("%s:%s:%d", callbackRequest, action, id)
+}
+
+func parseCallback(data string) (kind, action string, id int, ok bool) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 3 {
+		return "", "", 0, false
+	}
+	id, err := strconv.Atoi(parts[2])
+	if err != nil || id <= 0 {
+		return "", "", 0, false
+	}
+	return parts[0], parts[1], id, true
+}
+
+// pendingKeyboard builds inline buttons for a single pending request
+func pendingKeyboard(id int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Подтвердить", requestCallbackData(actionApprove, id)),
+			tgbotapi.NewInlineKeyboardButtonData("🚫 Отклонить", requestCallbackData(actionReject, id)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Изменить баллы", requestCallbackData(actionEdit, id)),
+		),
+	)
+}
+
+func formatPendingRequest(req repo.PendingRequest) string {
+	text := fmt.Sprintf("ID: %d | 👤 %s (@%s) | ➕ %d баллов\n📎 %s",
+		req.ID, req.Name, req.Username, req.Amount, req.Reason)
+	if req.TeamName != "" {
+		text += "\n👥 " + req.TeamName
+	}
+	return text
+}
+
+// handleCallback processes inline button presses
+func (h *TelegramHandler) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
+	if cb.Message == nil {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, ""))
+		return
+	}
+
+	kind, action, id, ok := parseCallback(cb.Data)
+	if !ok || (kind != callbackRequest && kind != callbackJoin && kind != callbackTeam && kind != callbackBulk &&
+		kind != callbackActivity) {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "❓ Неизвестное действие."))
+		return
+	}
+
+	user, _ := h.Repo.GetUserByID(ctx, cb.From.ID)
+	if kind == callbackActivity {
+		h.handleActivityCallback(ctx, cb, user, action, id)
+		return
+	}
+	if !hasRole(user, staffRoles...) {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "🚫 Действие доступно только тренерам."))
+		return
+	}
+
+	if kind == callbackJoin {
+		h.handleJoinCallback(ctx, cb, user, action, id)
+		return
+	}
+	if kind == callbackTeam {
+		h.handleTeamCallback(ctx, cb, user, action, id)
+		return
+	}
+	if kind == callbackBulk {
+		h.handleBulkCallback(ctx, cb, user, action, id)
+		return
+	}
+
+	chatID := cb.Message.Chat.ID
+	messageID := cb.Message.MessageID
+
+	req, err := h.Repo.GetPendingRequest(ctx, id)
+	if err != nil {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "⚠️ Запрос уже обработан."))
+		h.closeRequestMessage(chatID, messageID, cb.Message.Text, "⚠️ Запрос уже обработан.")
+		return
+	}
+	if !h.coachesRequest(ctx, user, req) {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, athleteDeniedMessage))
+		return
+	}
+
+	switch action {
+	case actionApprove:
+		grant, err := h.Repo.ApproveRequest(ctx, id, user.ID, nil, "")
+		if err != nil {
+			util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "❌ Не удалось подтвердить запрос."))
+			h.closeRequestMessage(chatID, messageID, cb.Message.Text, "⚠️ Запрос уже обработан.")
+			return
+		}
+		h.notifyGrant(ctx, grant, fmt.Sprintf("✅ Твой запрос #%d подтверждён!", id), user.Name)
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, fmt.Sprintf("✅ Запрос #%d подтвержден.", id)))
+		h.closeRequestMessage(chatID, messageID, cb.Message.Text,
+			fmt.Sprintf("✅ Подтверждено тренером %s.", user.Name))
+
+	case actionReject:
+		h.askReply(chatID, replyPrompt{Action: action, RequestID: id, MessageID: messageID, Text: cb.Message.Text},
+			fmt.Sprintf("💬 Напиши причину отказа по запросу #%d, спортсмен её увидит:", id))
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, ""))
+
+	case actionEdit:
+		h.askReply(chatID, replyPrompt{Action: action, RequestID: id, MessageID: messageID},
+			fmt.Sprintf("✏️ Введи новое количество баллов для запроса #%d:", id))
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, ""))
+
+	default:
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "❓ Неизвестное действие."))
+	}
+}
+
+// closeRequestMessage replaces buttons of a pending request with its final state
+func (h *TelegramHandler) closeRequestMessage(chatID int64, messageID int, text, status string) {
+	util.SafeEdit(h.Bot, tgbotapi.NewEditMessageText(chatID, messageID, text+"\n\n"+status))
+}
+
+// askReply stores the prompt and asks the coach to answer it with a plain message
+func (h *TelegramHandler) askReply(chatID int64, prompt replyPrompt, question string) {
+	prompt.CreatedAt = time.Now()
+	h.mu.Lock()
+	h.prompts[chatID] = prompt
+	h.mu.Unlock()
+
+	msg := tgbotapi.NewMessage(chatID, question)
+	msg.ReplyMarkup = tgbotapi.ForceReply{ForceReply: true, Selective: true}
+	util.SafeSend(h.Bot, msg)
+}
+
+// takePrompt returns and clears the prompt a coach is currently answering
+func (h *TelegramHandler) takePrompt(chatID int64) (replyPrompt, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	prompt, ok := h.prompts[chatID]
+	if ok {
+		delete(h.prompts, chatID)
+	}
+	return prompt, ok
+}
+
+// restorePrompt puts a prompt back after an invalid answer
+func (h *TelegramHandler) restorePrompt(chatID int64, prompt replyPrompt) {
+	h.mu.Lock()
+	h.prompts[chatID] = prompt
+	h.mu.Unlock()
+}
+
+// handlePromptReply routes a plain message to the prompt it answers
+func (h *TelegramHandler) handlePromptReply(ctx context.Context, chatID int64, text string, user *domain.User, prompt replyPrompt) {
+	if !hasRole(user, staffRoles...) {
+		return
+	}
+	if time.Since(prompt.CreatedAt) > promptTTL {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"⌛ Ответ по запросу #%d ждал слишком долго. Нажми кнопку под запросом ещё раз.", prompt.RequestID)))
+		return
+	}
+
+	switch prompt.Action {
+	case actionEdit:
+		h.handleAmountReply(ctx, chatID, text, user, prompt)
+	case actionReject:
+		h.handleRejectReply(ctx, chatID, text, user, prompt)
+	}
+}
+
+// promptRequest re-reads the request a prompt was opened for: while the coach
+// was typing it may have been decided or left the teams they run
+func (h *TelegramHandler) promptRequest(ctx context.Context, chatID int64, user *domain.User, id int) (*repo.PendingRequest, bool) {
+	req, err := h.Repo.GetPendingRequest(ctx, id)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ Запрос #%d уже обработан.", id)))
+		return nil, false
+	}
+	if !h.coachesRequest(ctx, user, req) {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, athleteDeniedMessage))
+		return nil, false
+	}
+	return req, true
+}
+
+// handleRejectReply rejects a request with the reason typed after pressing "Отклонить"
+func (h *TelegramHandler) handleRejectReply(ctx context.Context, chatID int64, text string, user *domain.User, prompt replyPrompt) {
+	reason := strings.TrimSpace(text)
+	if reason == "" {
+		h.restorePrompt(chatID, prompt)
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи причину отказа текстом."))
+		return
+	}
+	if _, ok := h.promptRequest(ctx, chatID, user, prompt.RequestID); !ok {
+		return
+	}
+
+	fromID, err := h.Repo.RejectRequest(ctx, prompt.RequestID, user.ID, reason)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось отклонить запрос: "+err.Error()))
+		return
+	}
+
+	h.notifyRejection(fromID, prompt.RequestID, user.Name, reason)
+	h.closeRequestMessage(chatID, prompt.MessageID, prompt.Text,
+		fmt.Sprintf("🚫 Отклонено тренером %s.\n💬 %s", user.Name, reason))
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("🚫 Запрос #%d отклонён.", prompt.RequestID)))
+}
+
+// handleAmountReply applies the amount typed after pressing "Изменить баллы"
+// and tells the athlete, like approving with another amount does
+func (h *TelegramHandler) handleAmountReply(ctx context.Context, chatID int64, text string, user *domain.User, edit replyPrompt) {
+	amount, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || amount <= 0 {
+		h.restorePrompt(chatID, edit)
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректное число баллов больше нуля."))
+		return
+	}
+
+	before, ok := h.promptRequest(ctx, chatID, user, edit.RequestID)
+	if !ok {
+		return
+	}
+	if err := h.Repo.UpdatePendingAmount(ctx, edit.RequestID, amount); err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось изменить запрос: "+err.Error()))
+		return
+	}
+
+	req, err := h.Repo.GetPendingRequest(ctx, edit.RequestID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+
+	if before.Amount != amount {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(req.UserID, fmt.Sprintf(
+			"✏️ Тренер %s изменил твой запрос #%d: %d → %d баллов. Запрос ещё ждёт подтверждения.",
+			user.Name, req.ID, before.Amount, amount)))
+	}
+
+	text = formatPendingRequest(*req) + fmt.Sprintf("\n\n✏️ Изменено тренером %s: было %d баллов.", user.Name, before.Amount)
+	util.SafeEdit(h.Bot, tgbotapi.NewEditMessageTextAndMarkup(chatID, edit.MessageID, text, pendingKeyboard(req.ID)))
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID,
+		fmt.Sprintf("✏️ Запрос #%d изменён: %d баллов. Подтверди или отклони его кнопками выше.", req.ID, amount)))
+}
@@ -0,0 +1,363 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"surf_bot/internal/domain"
+	"surf_bot/internal/messenger"
+	repo "surf_bot/internal/repository"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// answer returns the text of the only callback answer
+func answer(t *testing.T, records []messenger.Record) string {
+	t.Helper()
+	answers := texts(records, messenger.KindCallback, 0)
+	if len(answers) != 1 {
+		t.Fatalf("callback answers = %q, want exactly one", answers)
+	}
+	return answers[0]
+}
+
+// edit returns the only edit of message #99 in the chat
+func edit(t *testing.T, records []messenger.Record, chatID int64) messenger.Record {
+	t.Helper()
+	var edits []messenger.Record
+	for _, r := range records {
+		if r.Kind == messenger.KindEdit && r.ChatID == chatID {
+			edits = append(edits, r)
+		}
+	}
+	if len(edits) != 1 || edits[0].MessageID != 99 {
+		t.Fatalf("edits = %+v, want exactly one of message #99", edits)
+	}
+	return edits[0]
+}
+
+func assertNoEdits(t *testing.T, records []messenger.Record) {
+	t.Helper()
+	for _, r := range records {
+		if r.Kind == messenger.KindEdit {
+			t.Fatalf("unexpected edit %+v", r)
+		}
+	}
+}
+
+func TestRequestCallbacks(t *testing.T) {
+	t.Run("approve", func(t *testing.T) {
+		f := newFixture(t)
+		f.request(5, "Тренировка")
+
+		records := f.press(coachID, "req:approve:1")
+		if got, want := answer(t, records), "✅ Запрос #1 подтвержден."; got != want {
+			t.Fatalf("answer = %q, want %q", got, want)
+		}
+		if got, want := edit(t, records, coachID).Text, "запрос\n\n✅ Подтверждено тренером coach."; got != want {
+			t.Fatalf("edit = %q, want %q", got, want)
+		}
+		assertContains(t, reply(t, records, athleteID), "✅ Твой запрос #1 подтверждён!")
+
+		// повторное нажатие не начисляет баллы второй раз
+		records = f.press(coachID, "req:approve:1")
+		if got, want := answer(t, records), "⚠️ Запрос уже обработан."; got != want {
+			t.Fatalf("answer = %q, want %q", got, want)
+		}
+		assertContains(t, edit(t, records, coachID).Text, "⚠️ Запрос уже обработан.")
+		if score, _ := f.r.GetUserScore(f.ctx, athleteID); score != 5 {
+			t.Fatalf("score = %d, want 5", score)
+		}
+	})
+
+	t.Run("reject with reason", func(t *testing.T) {
+		f := newFixture(t)
+		f.request(5, "Тренировка")
+
+		records := f.press(coachID, "req:reject:1")
+		if got := answer(t, records); got != "" {
+			t.Fatalf("answer = %q, want empty", got)
+		}
+		assertContains(t, reply(t, records, coachID), "💬 Напиши причину отказа по запросу #1")
+		if _, ok := records[0].Markup.(tgbotapi.ForceReply); !ok {
+			t.Fatalf("prompt markup = %+v, want ForceReply", records[0].Markup)
+		}
+
+		records = f.send(coachID, "Нет фото")
+		if got, want := edit(t, records, coachID).Text, "запрос\n\n🚫 Отклонено тренером coach.\n💬 Нет фото"; got != want {
+			t.Fatalf("edit = %q, want %q", got, want)
+		}
+		if got := reply(t, records, coachID); got != "🚫 Запрос #1 отклонён." {
+			t.Fatalf("reply = %q", got)
+		}
+		assertContains(t, reply(t, records, athleteID), "💬 Причина: Нет фото")
+	})
+
+	t.Run("edit amount", func(t *testing.T) {
+		f := newFixture(t)
+		f.request(5, "Тренировка")
+
+		records := f.press(coachID, "req:edit:1")
+		assertContains(t, reply(t, records, coachID), "✏️ Введи новое количество баллов для запроса #1")
+		if _, ok := records[0].Markup.(tgbotapi.ForceReply); !ok {
+			t.Fatalf("prompt markup = %+v, want ForceReply", records[0].Markup)
+		}
+
+		records = f.send(coachID, "ноль")
+		if got := reply(t, records, coachID); got != "❗ Укажи корректное число баллов больше нуля." {
+			t.Fatalf("reply = %q", got)
+		}
+
+		records = f.send(coachID, "8")
+		e := edit(t, records, coachID)
+		assertContains(t, e.Text, "✏️ Изменено тренером coach: было 5 баллов.")
+		if _, ok := e.Markup.(tgbotapi.InlineKeyboardMarkup); !ok {
+			t.Fatalf("edited request lost its buttons: %+v", e)
+		}
+		assertContains(t, reply(t, records, coachID), "✏️ Запрос #1 изменён: 8 баллов.")
+		assertContains(t, reply(t, records, athleteID), "5 → 8 баллов")
+		if req, _ := f.r.GetPendingRequest(f.ctx, 1); req.Amount != 8 {
+			t.Fatalf("amount = %d, want 8", req.Amount)
+		}
+	})
+
+	t.Run("command cancels the prompt", func(t *testing.T) {
+		f := newFixture(t)
+		f.request(5, "Тренировка")
+
+		f.press(coachID, "req:reject:1")
+		f.send(coachID, "/pending")
+		records := f.send(coachID, "просто сообщение")
+		assertNoEdits(t, records)
+		if req, err := f.r.GetPendingRequest(f.ctx, 1); err != nil || req == nil {
+			t.Fatalf("request is no longer pending: %v", err)
+		}
+	})
+
+	t.Run("scope rechecked on reply", func(t *testing.T) {
+		for _, action := range []string{"reject", "edit"} {
+			f := newFixture(t)
+			f.request(5, "Тренировка")
+			f.must(f.r.AddTeamCoach(f.ctx, 1, otherID))
+
+			f.press(otherID, "req:"+action+":1")
+			f.must(f.r.RemoveTeamCoach(f.ctx, 1, otherID))
+			records := f.send(otherID, "7")
+			if got := reply(t, records, otherID); got != athleteDeniedMessage {
+				t.Fatalf("%s: reply = %q, want %q", action, got, athleteDeniedMessage)
+			}
+			if req, _ := f.r.GetPendingRequest(f.ctx, 1); req == nil || req.Amount != 5 {
+				t.Fatalf("%s: request changed: %+v", action, req)
+			}
+		}
+	})
+
+	t.Run("decided while typing", func(t *testing.T) {
+		f := newFixture(t)
+		f.request(5, "Тренировка")
+
+		f.press(coachID, "req:reject:1")
+		f.press(coachID, "req:approve:1")
+		records := f.send(coachID, "Нет фото")
+		if got := reply(t, records, coachID); got != "⚠️ Запрос #1 уже обработан." {
+			t.Fatalf("reply = %q", got)
+		}
+		assertNoEdits(t, records)
+	})
+
+	t.Run("expired prompt", func(t *testing.T) {
+		f := newFixture(t)
+		f.request(5, "Тренировка")
+
+		f.press(coachID, "req:reject:1")
+		prompt := f.h.prompts[coachID]
+		prompt.CreatedAt = prompt.CreatedAt.Add(-promptTTL - time.Minute)
+		f.h.prompts[coachID] = prompt
+
+		records := f.send(coachID, "Нет фото")
+		assertContains(t, reply(t, records, coachID), "⌛ Ответ по запросу #1 ждал слишком долго")
+		assertNoEdits(t, records)
+		if req, err := f.r.GetPendingRequest(f.ctx, 1); err != nil || req == nil {
+			t.Fatalf("request is no longer pending: %v", err)
+		}
+	})
+
+	t.Run("coach of another team", func(t *testing.T) {
+		f := newFixture(t)
+		f.request(5, "Тренировка")
+
+		records := f.press(otherID, "req:approve:1")
+		if got := answer(t, records); got != athleteDeniedMessage {
+			t.Fatalf("answer = %q, want %q", got, athleteDeniedMessage)
+		}
+		assertNoEdits(t, records)
+	})
+
+	t.Run("athlete", func(t *testing.T) {
+		f := newFixture(t)
+		f.request(5, "Тренировка")
+
+		records := f.press(athleteID, "req:approve:1")
+		if got := answer(t, records); got != "🚫 Действие доступно только тренерам." {
+			t.Fatalf("answer = %q", got)
+		}
+		assertNoEdits(t, records)
+	})
+}
+
+func TestJoinCallbacks(t *testing.T) {
+	tests := []struct {
+		action  string
+		answer  string
+		status  string
+		notify  string
+		outcome domain.Role
+	}{
+		{"approve", "✅ Заявка #1 принята.", "✅ Принято тренером coach.", "Волна", domain.RoleAthlete},
+		{"deny", "🚫 Заявка #1 отклонена.", "🚫 Отклонено тренером coach.", "Волна", roleGuest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			f := newFixture(t)
+			f.send(guestID, "/athlete 1")
+
+			records := f.press(coachID, "join:"+tt.action+":1")
+			if got := answer(t, records); got != tt.answer {
+				t.Fatalf("answer = %q, want %q", got, tt.answer)
+			}
+			if got := edit(t, records, coachID).Text; got != "запрос\n\n"+tt.status {
+				t.Fatalf("edit = %q, want status %q", got, tt.status)
+			}
+			assertContains(t, reply(t, records, guestID), tt.notify)
+			if role := f.role(guestID); role != tt.outcome {
+				t.Fatalf("role = %q, want %q", role, tt.outcome)
+			}
+		})
+	}
+
+	t.Run("coach of another team", func(t *testing.T) {
+		f := newFixture(t)
+		f.send(guestID, "/athlete 1")
+
+		records := f.press(otherID, "join:approve:1")
+		if got := answer(t, records); got != teamDeniedMessage(1) {
+			t.Fatalf("answer = %q", got)
+		}
+		assertNoEdits(t, records)
+	})
+}
+
+func TestTeamCallbacks(t *testing.T) {
+	t.Run("archive", func(t *testing.T) {
+		f := newFixture(t)
+		records := f.press(adminID, "team:archive:2")
+		if got := answer(t, records); got != "🗄 Команда в архиве." {
+			t.Fatalf("answer = %q", got)
+		}
+		assertContains(t, edit(t, records, adminID).Text, "🗄 Команда отправлена в архив (boss). Вернуть: /restore_team 2")
+		if team, _ := f.r.GetTeamByID(f.ctx, 2); team.ArchivedAt == nil {
+			t.Fatal("team is not archived")
+		}
+	})
+
+	t.Run("archive closes requests", func(t *testing.T) {
+		f := newFixture(t)
+		f.request(5, "Тренировка")
+		f.send(guestID, "/athlete 1")
+
+		records := f.press(adminID, "team:archive:1")
+		assertContains(t, reply(t, records, athleteID), repo.ArchivedRequestComment)
+		assertContains(t, reply(t, records, guestID), "🚫 Команда 'Волна' больше не набирает спортсменов")
+		if reqs, _ := f.r.GetPendingRequests(f.ctx); len(reqs) != 0 {
+			t.Fatalf("pending = %+v, want none", reqs)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		f := newFixture(t)
+		records := f.press(adminID, "team:cancel:2")
+		assertContains(t, edit(t, records, adminID).Text, "✖️ Отменено, команда не изменилась.")
+	})
+
+	t.Run("coach", func(t *testing.T) {
+		f := newFixture(t)
+		records := f.press(otherID, "team:archive:2")
+		if got := answer(t, records); got != "🚫 Действие доступно только администраторам." {
+			t.Fatalf("answer = %q", got)
+		}
+		assertNoEdits(t, records)
+	})
+}
+
+func TestBulkCallbacks(t *testing.T) {
+	setup := func(t *testing.T) *fixture {
+		f := newFixture(t)
+		f.h.Config.Points.BulkConfirm = 1
+		f.request(5, "Тренировка")
+		f.request(3, "Зарядка")
+
+		msg := reply(t, f.send(coachID, "/approve all"), coachID)
+		if msg != "⚠️ Ты собираешься подтвердить 2 запросов: #1, #2." {
+			t.Fatalf("confirmation = %q", msg)
+		}
+		return f
+	}
+
+	t.Run("run", func(t *testing.T) {
+		f := setup(t)
+		records := f.press(coachID, "bulk:run:1")
+		assertContains(t, edit(t, records, coachID).Text, "⏳ Выполняю…")
+		if got := texts(records, messenger.KindMessage, coachID); len(got) != 1 || got[0] != "📋 Подтверждено 2 из 2.\n✅ #1, #2" {
+			t.Fatalf("report = %q", got)
+		}
+
+		records = f.press(coachID, "bulk:run:1")
+		if got := answer(t, records); got != "⚠️ Подтверждение устарело." {
+			t.Fatalf("answer = %q", got)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		f := setup(t)
+		records := f.press(coachID, "bulk:cancel:1")
+		assertContains(t, edit(t, records, coachID).Text, "✖️ Отменено, запросы не изменились.")
+		if reqs, _ := f.r.GetPendingRequests(f.ctx); len(reqs) != 2 {
+			t.Fatalf("pending = %d, want 2", len(reqs))
+		}
+	})
+}
+
+func TestActivityCallbacks(t *testing.T) {
+	f := newFixture(t)
+	f.send(coachID, "/add_activity 1 5 Йога")
+
+	records := f.press(athleteID, "act:request:1")
+	if got := answer(t, records); got != "📨 Запрос отправлен." {
+		t.Fatalf("answer = %q", got)
+	}
+	assertContains(t, edit(t, records, athleteID).Text, "📨 Запрос #1: Йога — 5 баллов, отправлен на подтверждение тренеру.")
+	assertContains(t, reply(t, records, coachID), "Йога")
+
+	records = f.press(strangeID, "act:request:1")
+	if got := answer(t, records); got != "🚫 Ты больше не состоишь в этой команде." {
+		t.Fatalf("answer = %q", got)
+	}
+
+	records = f.press(coachID, "act:request:1")
+	if got := answer(t, records); got != "🚫 Запросы на баллы отправляют спортсмены." {
+		t.Fatalf("answer = %q", got)
+	}
+}
+
+func TestUnknownCallback(t *testing.T) {
+	f := newFixture(t)
+	for _, data := range []string{"", "req:approve", "req:approve:x", "foo:bar:1"} {
+		records := f.press(coachID, data)
+		if got := answer(t, records); got != "❓ Неизвестное действие." {
+			t.Fatalf("%q: answer = %q", data, got)
+		}
+		if len(records) != 1 {
+			t.Fatalf("%q: records = %+v, want only the answer", data, records)
+		}
+	}
+}
@@ -0,0 +1,548 @@
+// internal/handler/commands.go
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"surf_bot/internal/domain"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// roleGuest marks commands available to chats that are not registered yet
+const roleGuest domain.Role = "guest"
+
+var (
+	staffRoles = []domain.Role{domain.RoleCoach, domain.RoleAdmin}
+	adminRoles = []domain.Role{domain.RoleAdmin}
+)
+
+// commandContext carries everything a command handler needs about the incoming message
+type commandContext struct {
+	ChatID  int64
+	Text    string // текст сообщения, команда без @имя_бота
+	Args    string // всё, что после команды
+	User    *domain.User
+	Message *tgbotapi.Message
+}
+
+// Command describes a bot command: dispatch, role checks, help and the Telegram menu
+// are all generated from this description.
+type Command struct {
+	Name        string
+	Roles       []domain.Role // пустой список — команда доступна всем
+	Usage       string        // аргументы, например "<id>"
+	Description string
+	Handler     func(h *TelegramHandler, ctx context.Context, c *commandContext)
+
+	HideFromMenu bool // не показывать в меню команд Telegram
+}
+
+// commands returns the registry of all bot commands in the order they are shown to users.
+func commands() []Command {
+	return []Command{
+		{
+			Name:        "start",
+			Description: "Начать работу с ботом",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleStart(ctx, c.ChatID, c.User, c.Args, c.Message.From)
+			},
+		},
+		{
+			Name:         "help",
+			Roles:        []domain.Role{roleGuest, domain.RoleAthlete, domain.RoleCoach, domain.RoleAdmin},
+			Description:  "Список доступных команд",
+			HideFromMenu: true,
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleHelp(c.ChatID, c.User)
+			},
+		},
+		{
+			Name:         "athlete",
+			Roles:        []domain.Role{roleGuest},
+			Usage:        "<id_команды>",
+			Description:  "Подать заявку в команду как спортсмен",
+			HideFromMenu: true,
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleAthlete(ctx, c.ChatID, c.Text, c.Message.From)
+			},
+		},
+		{
+			Name:         "coach",
+			Roles:        []domain.Role{roleGuest},
+			Usage:        "<секретный_ключ>",
+			Description:  "Зарегистрироваться как тренер",
+			HideFromMenu: true,
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleCoach(ctx, c.ChatID, c.Args, c.Message.From)
+			},
+		},
+		{
+			Name:        "request",
+			Roles:       []domain.Role{domain.RoleAthlete},
+			Usage:       "[<баллы> <причина>] [team:<id>]",
+			Description: "Отправить запрос на баллы: без аргументов — выбрать занятие из каталога",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleRequest(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "cancel",
+			Roles:       []domain.Role{domain.RoleAthlete},
+			Usage:       "<id>",
+			Description: "Отменить свой запрос, пока его не рассмотрели",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleCancel(ctx, c.ChatID, c.Text)
+			},
+		},
+		{
+			Name:        "my_score",
+			Roles:       []domain.Role{domain.RoleAthlete},
+			Description: "Посмотреть свой счёт",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleMyScore(ctx, c.ChatID, c.User)
+			},
+		},
+		{
+			Name:        "ranking",
+			Roles:       []domain.Role{domain.RoleAthlete, domain.RoleCoach, domain.RoleAdmin},
+			Usage:       "[team:<id>]",
+			Description: "Рейтинг спортсменов",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleRanking(ctx, c.ChatID, c.User, c.Text)
+			},
+		},
+		{
+			Name:        "history",
+			Roles:       []domain.Role{domain.RoleAthlete, domain.RoleCoach, domain.RoleAdmin},
+			Usage:       "[@username]",
+			Description: "История начислений (тренер указывает спортсмена)",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleHistory(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "pending",
+			Roles:       staffRoles,
+			Usage:       "[team_id]",
+			Description: "Список запросов на подтверждение",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handlePending(ctx, c.ChatID, c.User, c.Text)
+			},
+		},
+		{
+			Name:        "approve",
+			Roles:       staffRoles,
+			Usage:       "<id> [баллы] [комментарий] | <3,5,9-14|all [team:<id>]>",
+			Description: "Подтвердить запросы (можно с другим количеством баллов)",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleApprove(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "reject",
+			Roles:       staffRoles,
+			Usage:       "<id|3,5,9-14|all [team:<id>]> <причина>",
+			Description: "Отклонить запросы",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleReject(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "give",
+			Roles:       staffRoles,
+			Usage:       "@username <баллы> <причина> [team:<id>]",
+			Description: "Начислить баллы вручную",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleGive(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "fine",
+			Roles:       staffRoles,
+			Usage:       "@username <баллы> <причина> [team:<id>]",
+			Description: "Оштрафовать: списать баллы",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleFine(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "revoke",
+			Roles:       staffRoles,
+			Usage:       "<point_id> [причина]",
+			Description: "Отменить подтверждённое начисление",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleRevoke(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "adjust",
+			Roles:       staffRoles,
+			Usage:       "<point_id> <баллы>",
+			Description: "Исправить сумму подтверждённого начисления",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleAdjust(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "allow_negative",
+			Roles:       staffRoles,
+			Usage:       "<team_id> <on|off>",
+			Description: "Разрешить штрафам уводить счёт в минус",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleAllowNegative(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "activities",
+			Roles:       []domain.Role{domain.RoleAthlete, domain.RoleCoach, domain.RoleAdmin},
+			Usage:       "[team:<id>]",
+			Description: "Каталог занятий команды",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleActivities(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "add_activity",
+			Roles:       staffRoles,
+			Usage:       "<team_id> <баллы> <название>",
+			Description: "Добавить занятие в каталог команды",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleAddActivity(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "remove_activity",
+			Roles:       staffRoles,
+			Usage:       "<id>",
+			Description: "Убрать занятие из каталога",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleRemoveActivity(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "free_requests",
+			Roles:       staffRoles,
+			Usage:       "<team_id> <on|off>",
+			Description: "Разрешить запросы со своими баллами помимо каталога",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleFreeRequests(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "athletes",
+			Roles:       staffRoles,
+			Usage:       "[team_id]",
+			Description: "Список спортсменов",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleAthletes(ctx, c.ChatID, c.User, c.Text)
+			},
+		},
+		{
+			Name:        "teams",
+			Roles:       staffRoles,
+			Usage:       "[archived]",
+			Description: "Список команд",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleTeams(ctx, c.ChatID, c.User, c.Text)
+			},
+		},
+		{
+			Name:        "create_team",
+			Roles:       staffRoles,
+			Usage:       "<название>",
+			Description: "Создать команду",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleCreateTeam(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "delete_team",
+			Roles:       adminRoles,
+			Usage:       "<team_id>",
+			Description: "Архивировать или удалить команду",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleDeleteTeam(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "restore_team",
+			Roles:       adminRoles,
+			Usage:       "<team_id>",
+			Description: "Вернуть команду из архива",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleRestoreTeam(ctx, c.ChatID, c.Text)
+			},
+		},
+		{
+			Name:        "move_athletes",
+			Roles:       staffRoles,
+			Usage:       "<from_team_id> <to_team_id>",
+			Description: "Перевести всех спортсменов в другую команду",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleMoveAthletes(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "assign_team",
+			Roles:       staffRoles,
+			Usage:       "@username <team_id>",
+			Description: "Добавить спортсмена в команду (прежние команды сохраняются)",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleAssignTeam(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "unassign_team",
+			Roles:       staffRoles,
+			Usage:       "@username <team_id>",
+			Description: "Вывести спортсмена из команды",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleUnassignTeam(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "add_coach",
+			Roles:       staffRoles,
+			Usage:       "<team_id> @username",
+			Description: "Добавить тренера в свою команду",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleAddCoach(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "remove_coach",
+			Roles:       staffRoles,
+			Usage:       "<team_id> @username",
+			Description: "Убрать тренера из своей команды",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleRemoveCoach(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "invite_link",
+			Roles:       staffRoles,
+			Usage:       "<team_id> [ttl:7d] [uses:20] [approval]",
+			Description: "Создать ссылку-приглашение в команду",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleInviteLink(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "joins",
+			Roles:       staffRoles,
+			Description: "Заявки на вступление в команды",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleJoins(ctx, c.ChatID, c.User)
+			},
+		},
+		{
+			Name:        "invites",
+			Roles:       staffRoles,
+			Usage:       "<team_id>",
+			Description: "Активные приглашения команды",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleInvites(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "revoke_invite",
+			Roles:       staffRoles,
+			Usage:       "<invite_id>",
+			Description: "Отозвать приглашение в команду",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleRevokeInvite(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "notify",
+			Roles:       staffRoles,
+			Usage:       "[instant|digest|off]",
+			Description: "Уведомления о новых запросах",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleNotify(ctx, c.ChatID, c.Args)
+			},
+		},
+		{
+			Name:        "coaches",
+			Roles:       adminRoles,
+			Description: "Список тренеров и администраторов",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleCoaches(ctx, c.ChatID)
+			},
+		},
+		{
+			Name:        "coach_invite",
+			Roles:       adminRoles,
+			Description: "Создать одноразовое приглашение тренера",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleCoachInvite(ctx, c.ChatID, c.User)
+			},
+		},
+		{
+			Name:        "promote",
+			Roles:       adminRoles,
+			Usage:       "@username",
+			Description: "Повысить роль: спортсмен → тренер → администратор",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handlePromote(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "demote",
+			Roles:       adminRoles,
+			Usage:       "@username",
+			Description: "Понизить роль: администратор → тренер → спортсмен",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleDemote(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "set_role",
+			Roles:       adminRoles,
+			Usage:       "@username <athlete|coach|admin>",
+			Description: "Назначить роль пользователю",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleSetRole(ctx, c.ChatID, c.Text, c.User)
+			},
+		},
+		{
+			Name:        "role_log",
+			Roles:       adminRoles,
+			Usage:       "[количество]",
+			Description: "Журнал изменения ролей",
+			Handler: func(h *TelegramHandler, ctx context.Context, c *commandContext) {
+				h.handleRoleLog(ctx, c.ChatID, c.Args)
+			},
+		},
+	}
+}
+
+// findCommand looks a command up by name (without the leading slash)
+func findCommand(name string) (Command, bool) {
+	for _, cmd := range commands() {
+		if cmd.Name == name {
+			return cmd, true
+		}
+	}
+	return Command{}, false
+}
+
+// parseCommand splits "/cmd@bot args" into the command name and its arguments.
+func parseCommand(text string) (name, args string, ok bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+
+	head, rest, _ := strings.Cut(text, " ")
+	name, _, _ = strings.Cut(strings.TrimPrefix(head, "/"), "@")
+	return strings.ToLower(name), strings.TrimSpace(rest), name != ""
+}
+
+// roleOf returns the user's role, or roleGuest for unregistered chats
+func roleOf(user *domain.User) domain.Role {
+	if user == nil {
+		return roleGuest
+	}
+	return user.Role
+}
+
+// hasRole reports whether the user has one of the given roles
+func hasRole(user *domain.User, roles ...domain.Role) bool {
+	return slices.Contains(roles, roleOf(user))
+}
+
+// Allows reports whether the command may be run by the given user
+func (c Command) Allows(user *domain.User) bool {
+	return len(c.Roles) == 0 || hasRole(user, c.Roles...)
+}
+
+// visibleFor reports whether the command is listed in help for the given role
+func (c Command) visibleFor(role domain.Role) bool {
+	return c.Name != "start" && slices.Contains(c.Roles, role)
+}
+
+// inMenu reports whether the command is pushed to the Telegram menu of the given role.
+// Commands open to everyone only appear in the default menu of unregistered chats.
+func (c Command) inMenu(role domain.Role) bool {
+	if c.HideFromMenu {
+		return false
+	}
+	if len(c.Roles) == 0 {
+		return role == roleGuest
+	}
+	return slices.Contains(c.Roles, role)
+}
+
+// Help returns the "/name usage — description" line for the command
+func (c Command) Help() string {
+	line := "/" + c.Name
+	if c.Usage != "" {
+		line += " " + c.Usage
+	}
+	return line + " — " + c.Description
+}
+
+// usageMessage returns the format hint for a command from the registry
+func usageMessage(name string) string {
+	cmd, _ := findCommand(name)
+	return "❗ Формат: /" + cmd.Name + " " + cmd.Usage
+}
+
+// deniedMessage explains why the user may not run the command
+func deniedMessage(cmd Command, user *domain.User) string {
+	if user == nil {
+		return "Сначала зарегистрируйся через /start."
+	}
+	if len(cmd.Roles) == 1 && cmd.Roles[0] == roleGuest {
+		return fmt.Sprintf("ℹ️ Ты уже зарегистрирован как %s.", roleNames[user.Role])
+	}
+
+	var who []string
+	for _, r := range cmd.Roles {
+		switch r {
+		case domain.RoleAthlete:
+			who = append(who, "спортсменам")
+		case domain.RoleCoach:
+			who = append(who, "тренерам")
+		case domain.RoleAdmin:
+			who = append(who, "администраторам")
+		}
+	}
+	if len(who) > 1 {
+		who = append(who[:len(who)-2], who[len(who)-2]+" и "+who[len(who)-1])
+	}
+	return "🚫 Команда доступна только " + strings.Join(who, ", ") + "."
+}
+
+// helpText lists commands available to the given role
+func helpText(role domain.Role) string {
+	var b strings.Builder
+	b.WriteString("📋 Доступные команды:\n")
+	for _, cmd := range commands() {
+		if cmd.visibleFor(role) {
+			b.WriteString("• " + cmd.Help() + "\n")
+		}
+	}
+	return b.String()
+}
+
+// botCommands converts registry entries into the Telegram command menu of the given role
+func botCommands(role domain.Role) []tgbotapi.BotCommand {
+	var list []tgbotapi.BotCommand
+	for _, cmd := range commands() {
+		if !cmd.inMenu(role) {
+			continue
+		}
+		desc := cmd.Description
+		if cmd.Usage != "" {
+			desc += ": /" + cmd.Name + " " + cmd.Usage
+		}
+		list = append(list, tgbotapi.BotCommand{Command: cmd.Name, Description: desc})
+	}
+	return list
+}
@@ -0,0 +1,113 @@
+// internal/handler/correction.go
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"surf_bot/internal/domain"
+	repo "surf_bot/internal/repository"
+	"surf_bot/internal/util"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Approved entries are never edited. /revoke and /adjust write a compensating
+// entry linked to the original, so /history shows both what was credited and
+// how it was corrected.
+
+// handleRevoke cancels an approved entry: /revoke <point_id> [причина]
+func (h *TelegramHandler) handleRevoke(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.SplitN(text, " ", 3)
+	if len(args) < 2 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("revoke")))
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimSpace(args[1]))
+	if err != nil || id <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный ID начисления."))
+		return
+	}
+	comment := ""
+	if len(args) == 3 {
+		comment = strings.TrimSpace(args[2])
+	}
+
+	if _, ok := h.requireGrant(ctx, chatID, user, id); !ok {
+		return
+	}
+	c, err := h.Repo.CorrectGrant(ctx, user.ID, id, 0, comment)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось отменить начисление: "+err.Error()))
+		return
+	}
+
+	h.notifyCorrection(ctx, c, fmt.Sprintf("↩️ Тренер отменил начисление #%d.", id), user.Name, comment)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"↩️ Начисление #%d отменено: %+d баллов (запись #%d).", id, c.Amount, c.PointID)))
+}
+
+// handleAdjust changes the amount of an approved entry: /adjust <point_id> <баллы>.
+// For a fine the amount is the size of the fine.
+func (h *TelegramHandler) handleAdjust(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.Fields(text)
+	if len(args) != 3 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("adjust")))
+		return
+	}
+
+	id, err := strconv.Atoi(args[1])
+	if err != nil || id <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный ID начисления."))
+		return
+	}
+	amount, err := strconv.Atoi(args[2])
+	if err != nil || amount <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи новое количество баллов больше нуля. Чтобы обнулить начисление, используй /revoke."))
+		return
+	}
+
+	g, ok := h.requireGrant(ctx, chatID, user, id)
+	if !ok {
+		return
+	}
+	if g.Amount < 0 {
+		amount = -amount
+	}
+
+	c, err := h.Repo.CorrectGrant(ctx, user.ID, id, amount, "")
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось исправить начисление: "+err.Error()))
+		return
+	}
+
+	title := fmt.Sprintf("✏️ Тренер исправил начисление #%d: %d → %d баллов.", id, c.OldAmount, c.NewAmount)
+	h.notifyCorrection(ctx, c, title, user.Name, "")
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"✏️ Начисление #%d исправлено: %d → %d баллов (запись #%d).", id, c.OldAmount, c.NewAmount, c.PointID)))
+}
+
+// requireGrant checks that an approved entry exists and is in the coach's scope
+func (h *TelegramHandler) requireGrant(ctx context.Context, chatID int64, coach *domain.User, id int) (*repo.Grant, bool) {
+	g, err := h.Repo.GetGrant(ctx, id)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"❌ Начисление #%d не найдено. Исправить можно только подтверждённую запись, а не другое исправление.", id)))
+		return nil, false
+	}
+	if !h.coachesEntry(ctx, coach, g.UserID, g.TeamID) {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, athleteDeniedMessage))
+		return nil, false
+	}
+	return g, true
+}
+
+func (h *TelegramHandler) notifyCorrection(ctx context.Context, c *repo.Correction, title, coachName, comment string) {
+	if comment != "" {
+		title += "\n💬 " + comment
+	}
+	h.notifyGrant(ctx, &c.Grant, title, coachName)
+}
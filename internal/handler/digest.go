@@ -0,0 +1,108 @@
+// internal/handler/digest.go
+package handler
+
+import (
+	"sync"
+	"time"
+)
+
+// digest collects new request IDs per coach and hands them over in one batch
+// once the window since the first collected request has passed.
+// It also watches instant notifications: after burst of them within the
+// window, the rest of the window is collected into a digest as well.
+type digest struct {
+	window time.Duration
+	burst  int // 0 — без ограничения
+	flush  func(coachID int64, requestIDs []int)
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending map[int64][]int
+	timers  map[int64]*time.Timer
+	sent    map[int64][]time.Time // мгновенные уведомления тренеру за последнее окно
+}
+
+func newDigest(window time.Duration, burst int, flush func(coachID int64, requestIDs []int)) *digest {
+	return &digest{
+		window:  window,
+		burst:   burst,
+		flush:   flush,
+		now:     time.Now,
+		pending: make(map[int64][]int),
+		timers:  make(map[int64]*time.Timer),
+		sent:    make(map[int64][]time.Time),
+	}
+}
+
+// Add queues a request for the coach; the first request in a window starts the timer
+func (d *digest) Add(coachID int64, requestID int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.add(coachID, requestID)
+}
+
+// add queues a request; the caller holds d.mu
+func (d *digest) add(coachID int64, requestID int) {
+	d.pending[coachID] = append(d.pending[coachID], requestID)
+	if _, ok := d.timers[coachID]; !ok {
+		d.timers[coachID] = time.AfterFunc(d.window, func() { d.fire(coachID) })
+	}
+}
+
+// Instant reports whether a request may be sent to an instant-mode coach right
+// away. Otherwise it is queued: the coach got burst requests in this window
+// or a digest for them is already being collected.
+func (d *digest) Instant(coachID int64, requestID int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, collecting := d.timers[coachID]; collecting {
+		d.add(coachID, requestID)
+		return false
+	}
+
+	now := d.now()
+	sent := d.sent[coachID]
+	i := 0
+	for i < len(sent) && now.Sub(sent[i]) >= d.window {
+		i++
+	}
+	sent = sent[i:]
+
+	if d.burst > 0 && len(sent) >= d.burst {
+		// после сводки счёт начинается заново
+		delete(d.sent, coachID)
+		d.add(coachID, requestID)
+		return false
+	}
+	d.sent[coachID] = append(sent, now)
+	return true
+}
+
+func (d *digest) fire(coachID int64) {
+	d.mu.Lock()
+	ids := d.pending[coachID]
+	delete(d.pending, coachID)
+	delete(d.timers, coachID)
+	d.mu.Unlock()
+
+	if len(ids) > 0 {
+		d.flush(coachID, ids)
+	}
+}
+
+// FlushAll sends everything collected so far without waiting for the windows to end
+func (d *digest) FlushAll() {
+	d.mu.Lock()
+	pending := d.pending
+	for _, t := range d.timers {
+		t.Stop()
+	}
+	d.pending = make(map[int64][]int)
+	d.timers = make(map[int64]*time.Timer)
+	d.mu.Unlock()
+
+	for coachID, ids := range pending {
+		d.flush(coachID, ids)
+	}
+}
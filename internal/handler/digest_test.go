@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"surf_bot/internal/messenger"
+)
+
+// flushes collects what a digest hands over
+type flushes map[int64][]int
+
+func (f flushes) add(coachID int64, ids []int) { f[coachID] = append(f[coachID], ids...) }
+
+func TestDigestBurst(t *testing.T) {
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	got := flushes{}
+	d := newDigest(time.Hour, 2, got.add)
+	d.now = func() time.Time { return now }
+	defer d.FlushAll()
+
+	steps := []struct {
+		after   time.Duration
+		coach   int64
+		request int
+		instant bool
+	}{
+		{0, 1, 1, true},
+		{40 * time.Minute, 1, 2, true},
+		{0, 2, 3, true},            // у другого тренера свой счёт
+		{time.Minute, 1, 4, false}, // третий за окно уходит в сводку
+		{time.Minute, 1, 5, false}, // пока сводка копится, в неё идут и остальные
+	}
+	for _, s := range steps {
+		now = now.Add(s.after)
+		if ok := d.Instant(s.coach, s.request); ok != s.instant {
+			t.Fatalf("Instant(%d, %d) = %t, want %t", s.coach, s.request, ok, s.instant)
+		}
+	}
+
+	d.FlushAll()
+	if want := (flushes{1: {4, 5}}); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("flushed %v, want %v", got, want)
+	}
+
+	// после сводки счёт начинается заново
+	if !d.Instant(1, 6) {
+		t.Fatal("request after the digest was not sent instantly")
+	}
+}
+
+func TestDigestWindowExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	d := newDigest(time.Hour, 2, func(int64, []int) {})
+	d.now = func() time.Time { return now }
+	defer d.FlushAll()
+
+	d.Instant(1, 1)
+	now = now.Add(40 * time.Minute)
+	d.Instant(1, 2)
+	now = now.Add(21 * time.Minute) // первый запрос вышел из окна
+	if !d.Instant(1, 3) {
+		t.Fatal("request after the window was collected into a digest")
+	}
+	now = now.Add(time.Minute)
+	if d.Instant(1, 4) {
+		t.Fatal("third request within the window was sent instantly")
+	}
+}
+
+func TestDigestNoBurst(t *testing.T) {
+	d := newDigest(time.Hour, 0, func(int64, []int) {})
+	defer d.FlushAll()
+	for id := 1; id <= 100; id++ {
+		if !d.Instant(1, id) {
+			t.Fatalf("request #%d was collected with the burst limit off", id)
+		}
+	}
+}
+
+func TestDigestTimer(t *testing.T) {
+	flushed := make(chan []int, 2)
+	d := newDigest(10*time.Millisecond, 0, func(_ int64, ids []int) { flushed <- ids })
+
+	d.Add(1, 1)
+	d.Add(1, 2)
+	select {
+	case ids := <-flushed:
+		if !slices.Equal(ids, []int{1, 2}) {
+			t.Fatalf("flushed %v, want [1 2]", ids)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("digest was not flushed after the window")
+	}
+
+	// следующий запрос открывает новое окно
+	d.Add(1, 3)
+	select {
+	case ids := <-flushed:
+		if !slices.Equal(ids, []int{3}) {
+			t.Fatalf("flushed %v, want [3]", ids)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second digest was not flushed")
+	}
+}
+
+func TestNotifyCoaches(t *testing.T) {
+	t.Run("burst", func(t *testing.T) {
+		f := newFixture(t)
+		f.h.digest = newDigest(time.Hour, 2, f.h.sendDigest)
+
+		var instant []string
+		for i := 0; i < 4; i++ {
+			instant = append(instant, texts(f.send(athleteID, "/request 5 Тренировка"), messenger.KindMessage, coachID)...)
+		}
+		if len(instant) != 2 {
+			t.Fatalf("instant notifications = %q, want 2", instant)
+		}
+
+		f.rec.Reset()
+		f.h.FlushNotifications()
+		got := texts(f.rec.Records(), messenger.KindMessage, coachID)
+		if len(got) != 3 || got[0] != "🔔 Новых запросов на баллы: 2. Подтверди или отклони их кнопками ниже." ||
+			!strings.HasPrefix(got[1], "ID: 3 ") || !strings.HasPrefix(got[2], "ID: 4 ") {
+			t.Fatalf("digest = %q", got)
+		}
+	})
+
+	t.Run("digest skips decided requests", func(t *testing.T) {
+		f := newFixture(t)
+		f.send(coachID, "/notify digest")
+		f.send(athleteID, "/request 5 Тренировка")
+		records := f.send(athleteID, "/request 3 Зарядка")
+		if got := texts(records, messenger.KindMessage, coachID); len(got) != 0 {
+			t.Fatalf("digest coach notified instantly: %q", got)
+		}
+		f.send(coachID, "/approve 1")
+
+		f.rec.Reset()
+		f.h.FlushNotifications()
+		got := texts(f.rec.Records(), messenger.KindMessage, coachID)
+		if len(got) != 1 || !strings.Contains(got[0], "🔔 Новый запрос на баллы\n\nID: 2 ") {
+			t.Fatalf("digest = %q", got)
+		}
+	})
+}
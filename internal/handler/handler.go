@@ -2,18 +2,43 @@
 package handler
 
 import (
-	repo "surf_bot/internal/repository" // 👈 добавь псевдоним repo
+	"sync"
 
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"surf_bot/internal/config"
+	"surf_bot/internal/messenger"
+	repo "surf_bot/internal/repository" // 👈 добавь псевдоним repo
 )
 
 type TelegramHandler struct {
-	Repo        *repo.UserRepository
-	SecretCoach string
-	Bot         *tgbotapi.BotAPI
+	Repo   repo.Repository
+	Bot    messenger.Messenger
+	Config config.Config
+
+	mu      sync.Mutex
+	prompts map[int64]replyPrompt // chatID -> запрос, для которого тренер вводит ответ (баллы или причину отказа)
+	bulks   map[int64]bulkAction  // chatID -> массовое действие, ждущее подтверждения
+	bulkSeq int
+
+	digest   *digest   // сводки новых запросов для тренеров в режиме digest
+	keyFails *throttle // неудачные попытки ввести ключ или приглашение
 }
 
 // NewTelegramHandler constructs a new handler instance.
-func NewTelegramHandler(r *repo.UserRepository, bot *tgbotapi.BotAPI, secret string) *TelegramHandler {
-	return &TelegramHandler{Repo: r, SecretCoach: secret, Bot: bot}
+func NewTelegramHandler(r repo.Repository, bot messenger.Messenger, cfg config.Config) *TelegramHandler {
+	h := &TelegramHandler{
+		Repo:    r,
+		Bot:     bot,
+		Config:  cfg,
+		prompts: make(map[int64]replyPrompt),
+		bulks:   make(map[int64]bulkAction),
+
+		keyFails: newThrottle(maxKeyFails, maxKeyFailsTotal, keyFailWindow),
+	}
+	h.digest = newDigest(cfg.Notify.DigestWindow.Std(), cfg.Notify.BurstThreshold, h.sendDigest)
+	return h
+}
+
+// FlushNotifications sends collected digests right away; call it before exiting
+func (h *TelegramHandler) FlushNotifications() {
+	h.digest.FlushAll()
 }
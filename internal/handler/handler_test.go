@@ -0,0 +1,805 @@
+package handler
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"surf_bot/internal/config"
+	"surf_bot/internal/domain"
+	"surf_bot/internal/messenger"
+	repo "surf_bot/internal/repository"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Chats of the seeded users. Team #1 "Волна" is run by coach, team #2 "Прибой" by other.
+const (
+	athleteID int64 = 10 // kelly, команда #1
+	strangeID int64 = 11 // slater, команда #2
+	coachID   int64 = 20
+	otherID   int64 = 21
+	guestID   int64 = 99
+	adminID   int64 = 1
+)
+
+const testSecret = "s3cret"
+
+type fixture struct {
+	t   *testing.T
+	ctx context.Context
+	h   *TelegramHandler
+	r   *repo.MemoryRepository
+	rec *messenger.Recorder
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Coach.Secret = testSecret
+
+	f := &fixture{
+		t:   t,
+		ctx: context.Background(),
+		r:   repo.NewMemoryRepository(),
+		rec: messenger.NewRecorder("surf_bot"),
+	}
+	f.h = NewTelegramHandler(f.r, f.rec, cfg)
+
+	f.user(adminID, "boss", domain.RoleAdmin)
+	f.user(coachID, "coach", domain.RoleCoach)
+	f.user(otherID, "other", domain.RoleCoach)
+	f.user(athleteID, "kelly", domain.RoleAthlete)
+	f.user(strangeID, "slater", domain.RoleAthlete)
+	f.must(f.r.CreateTeam(f.ctx, "Волна", coachID))
+	f.must(f.r.CreateTeam(f.ctx, "Прибой", otherID))
+	f.must(f.r.AssignUserToTeam(f.ctx, athleteID, 1))
+	f.must(f.r.AssignUserToTeam(f.ctx, strangeID, 2))
+	return f
+}
+
+func (f *fixture) must(err error) {
+	f.t.Helper()
+	if err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f *fixture) user(id int64, username string, role domain.Role) {
+	f.t.Helper()
+	f.must(f.r.RegisterUser(f.ctx, &domain.User{ID: id, Name: username, Username: username, Role: role}))
+}
+
+// request files a pending request of kelly for team #1
+func (f *fixture) request(amount int, reason string) int {
+	f.t.Helper()
+	team := 1
+	id, err := f.r.CreatePendingRequest(f.ctx, athleteID, amount, reason, &team)
+	f.must(err)
+	return id
+}
+
+// give credits kelly points for team #1
+func (f *fixture) give(amount int) {
+	f.t.Helper()
+	team := 1
+	_, err := f.r.GivePoints(f.ctx, coachID, "kelly", amount, "Тренировка", &team)
+	f.must(err)
+}
+
+// send handles a message from the chat and returns everything recorded for it
+func (f *fixture) send(from int64, text string) []messenger.Record {
+	f.t.Helper()
+	f.rec.Reset()
+	f.h.HandleUpdate(f.ctx, tgbotapi.Update{Message: &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{ID: from, FirstName: "Гость", UserName: "guest"},
+		Chat:      &tgbotapi.Chat{ID: from},
+		Text:      text,
+	}})
+	return f.rec.Records()
+}
+
+// press handles an inline button pressed under message #99 of the chat
+func (f *fixture) press(from int64, data string) []messenger.Record {
+	f.t.Helper()
+	f.rec.Reset()
+	f.h.HandleUpdate(f.ctx, tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "cb",
+		From:    &tgbotapi.User{ID: from},
+		Data:    data,
+		Message: &tgbotapi.Message{MessageID: 99, Chat: &tgbotapi.Chat{ID: from}, Text: "запрос"},
+	}})
+	return f.rec.Records()
+}
+
+func (f *fixture) role(id int64) domain.Role {
+	f.t.Helper()
+	u, err := f.r.GetUserByID(f.ctx, id)
+	f.must(err)
+	if u == nil {
+		return roleGuest
+	}
+	return u.Role
+}
+
+// texts returns texts of the records of one kind sent to the chat
+func texts(records []messenger.Record, kind messenger.Kind, chatID int64) []string {
+	var out []string
+	for _, r := range records {
+		if r.Kind == kind && r.ChatID == chatID {
+			out = append(out, r.Text)
+		}
+	}
+	return out
+}
+
+// reply returns the only message sent to the chat
+func reply(t *testing.T, records []messenger.Record, chatID int64) string {
+	t.Helper()
+	msgs := texts(records, messenger.KindMessage, chatID)
+	if len(msgs) != 1 {
+		t.Fatalf("messages to %d = %q, want exactly one", chatID, msgs)
+	}
+	return msgs[0]
+}
+
+func assertContains(t *testing.T, got, want string) {
+	t.Helper()
+	if !strings.Contains(got, want) {
+		t.Fatalf("got %q, want it to contain %q", got, want)
+	}
+}
+
+// chatOf returns a seeded chat with the role, or guestID for roleGuest
+func chatOf(role domain.Role) int64 {
+	switch role {
+	case domain.RoleCoach:
+		return coachID
+	case domain.RoleAthlete:
+		return athleteID
+	case domain.RoleAdmin:
+		return adminID
+	}
+	return guestID
+}
+
+func TestCommandsDenied(t *testing.T) {
+	for _, cmd := range commands() {
+		for _, role := range []domain.Role{roleGuest, domain.RoleAthlete, domain.RoleCoach, domain.RoleAdmin} {
+			if len(cmd.Roles) == 0 || slices.Contains(cmd.Roles, role) {
+				continue
+			}
+			t.Run(cmd.Name+"/"+string(role), func(t *testing.T) {
+				f := newFixture(t)
+				chatID := chatOf(role)
+				user, err := f.r.GetUserByID(f.ctx, chatID)
+				f.must(err)
+
+				got := reply(t, f.send(chatID, "/"+cmd.Name+" 1"), chatID)
+				if want := deniedMessage(cmd, user); got != want {
+					t.Fatalf("reply = %q, want %q", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestDeniedMessages(t *testing.T) {
+	tests := []struct {
+		from int64
+		text string
+		want string
+	}{
+		{guestID, "/my_score", "Сначала зарегистрируйся через /start."},
+		{athleteID, "/give @slater 5 Просто так", "🚫 Команда доступна только тренерам и администраторам."},
+		{coachID, "/coaches", "🚫 Команда доступна только администраторам."},
+		{coachID, "/request 5 Тренировка", "🚫 Команда доступна только спортсменам."},
+		{guestID, "/ranking", "Сначала зарегистрируйся через /start."},
+		{athleteID, "/coach " + testSecret, "ℹ️ Ты уже зарегистрирован как спортсмен."},
+		{adminID, "/athlete 1", "ℹ️ Ты уже зарегистрирован как администратор."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			f := newFixture(t)
+			if got := reply(t, f.send(tt.from, tt.text), tt.from); got != tt.want {
+				t.Fatalf("reply = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// A guest whose join request is pending is told to wait instead of to register
+func TestDeniedWhileJoinPending(t *testing.T) {
+	f := newFixture(t)
+	req, err := f.r.CreateJoinRequest(f.ctx, &domain.User{ID: guestID, Name: "Гость", Username: "guest", Role: domain.RoleAthlete}, 1)
+	f.must(err)
+
+	if got, want := reply(t, f.send(guestID, "/my_score"), guestID), joinPendingMessage(req); got != want {
+		t.Fatalf("reply = %q, want %q", got, want)
+	}
+}
+
+func TestCommandsUsage(t *testing.T) {
+	// эти команды без аргументов отвечают своей подсказкой, а не форматом из реестра
+	bare := map[string]string{
+		"coach":   "🚫 Неверный секретный ключ.",
+		"request": "❗ Укажи количество баллов после команды /request.",
+	}
+	for _, cmd := range commands() {
+		if !strings.HasPrefix(cmd.Usage, "<") && !strings.HasPrefix(cmd.Usage, "@") {
+			continue
+		}
+		t.Run(cmd.Name, func(t *testing.T) {
+			f := newFixture(t)
+			want, ok := bare[cmd.Name]
+			if !ok {
+				want = usageMessage(cmd.Name)
+			}
+			chatID := chatOf(cmd.Roles[len(cmd.Roles)-1])
+			if got := reply(t, f.send(chatID, "/"+cmd.Name), chatID); got != want {
+				t.Fatalf("reply = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCommandsBadArguments(t *testing.T) {
+	tests := []struct {
+		from int64
+		text string
+		want string
+	}{
+		{athleteID, "/request 5", "❗ Укажи причину запроса после количества баллов."},
+		{athleteID, "/request много Тренировка", "❗ Укажи корректное число баллов больше нуля."},
+		{athleteID, "/history a b", usageMessage("history")},
+		{athleteID, "/cancel x", "❗ Укажи корректный ID запроса."},
+		{coachID, "/approve x", "❗ Не удалось разобрать ID: \"x\" — укажи ID через запятую или диапазон вроде 9-14"},
+		{coachID, "/approve 1 -5", "❗ Укажи количество баллов числом больше нуля.\n\n" + usageMessage("approve")},
+		{coachID, "/reject 1", "❗ Укажи причину отказа: спортсмен её увидит."},
+		{coachID, "/give @kelly много Тренировка", "❗ Укажи корректное количество баллов."},
+		{coachID, "/history", "❗ Тренеры должны указать @username для просмотра истории спортсмена."},
+		{adminID, "/delete_team x", "❗ Укажи корректный team_id."},
+		{coachID, "/invite_link x", "❗ Укажи корректный числовой team_id."},
+		{guestID, "/athlete x", "🚫 Неверный ID команды."},
+		{guestID, "/coach nope", "🚫 Неверный секретный ключ."},
+		{coachID, "/notify loud", usageMessage("notify")},
+		{coachID, "/revoke x", "❗ Укажи корректный ID начисления."},
+		{coachID, "/adjust 1 -1", "❗ Укажи новое количество баллов больше нуля. Чтобы обнулить начисление, используй /revoke."},
+		{coachID, "/allow_negative 1 maybe", usageMessage("allow_negative")},
+		{coachID, "/free_requests 1 maybe", usageMessage("free_requests")},
+		{coachID, "/add_activity 1 0 Йога", "❗ Укажи корректное число баллов больше нуля."},
+		{athleteID, "/activities lots", usageMessage("activities")},
+		{coachID, "/fine @kelly 0 Опоздание", "❗ Укажи, сколько баллов списать, числом больше нуля."},
+		{coachID, "/fine @kelly 50 Опоздание", "❗ Нельзя списать 50 баллов у @kelly: счёт спортсмена не может уйти в минус: доступно 0 баллов.\nРазрешить уход в минус: /allow_negative 1 on"},
+		{adminID, "/move_athletes 1 1", "❗ Команды должны различаться."},
+		{adminID, "/set_role @kelly king", "❗ Роль должна быть athlete, coach или admin."},
+		{adminID, "/role_log 1000", "❗ Укажи число записей от 1 до 100."},
+		{adminID, "/promote @boss", "🚫 Нельзя менять роль самому себе."},
+		{adminID, "/demote @other", "⚠️ @other: " + repo.ErrLastCoach.Error() + ": это единственный тренер команд #2.\nСначала добавь этим командам другого тренера через /add_coach."},
+		{coachID, "/oops", "❓ Неизвестная команда. Напиши /help."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			f := newFixture(t)
+			if got := reply(t, f.send(tt.from, tt.text), tt.from); got != tt.want {
+				t.Fatalf("reply = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Coaches act only within their teams
+func TestCommandsOutOfScope(t *testing.T) {
+	tests := []struct {
+		text  string
+		setup func(f *fixture)
+		want  string
+	}{
+		{"/give @slater 5 Тренировка", nil, athleteDeniedMessage},
+		{"/fine @slater 5 Опоздание", nil, athleteDeniedMessage},
+		{"/approve 1", func(f *fixture) {
+			team := 2
+			_, err := f.r.CreatePendingRequest(f.ctx, strangeID, 5, "Тренировка", &team)
+			f.must(err)
+		}, athleteDeniedMessage},
+		{"/history @slater", nil, athleteDeniedMessage},
+		{"/ranking team:2", nil, teamDeniedMessage(2)},
+		{"/pending 2", nil, teamDeniedMessage(2)},
+		{"/athletes 2", nil, teamDeniedMessage(2)},
+		{"/assign_team @kelly 2", nil, teamDeniedMessage(2)},
+		{"/allow_negative 2 on", nil, teamDeniedMessage(2)},
+		{"/add_activity 2 5 Йога", nil, teamDeniedMessage(2)},
+		{"/revoke 1", func(f *fixture) {
+			team := 2
+			_, err := f.r.GivePoints(f.ctx, otherID, "slater", 5, "Тренировка", &team)
+			f.must(err)
+		}, athleteDeniedMessage},
+		{"/add_coach 2 @coach", nil, teamDeniedMessage(2)},
+		{"/invite_link 2", nil, teamDeniedMessage(2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			f := newFixture(t)
+			if tt.setup != nil {
+				tt.setup(f)
+			}
+			if got := reply(t, f.send(coachID, tt.text), coachID); got != tt.want {
+				t.Fatalf("reply = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("athlete /ranking of another team", func(t *testing.T) {
+		f := newFixture(t)
+		if got := reply(t, f.send(athleteID, "/ranking team:2"), athleteID); got != "❗ Ты не состоишь в команде #2." {
+			t.Fatalf("reply = %q", got)
+		}
+	})
+}
+
+func TestCoachRegistrationDisabled(t *testing.T) {
+	f := newFixture(t)
+	f.h.Config.Coach.Secret = ""
+
+	got := reply(t, f.send(guestID, "/coach "+testSecret), guestID)
+	assertContains(t, got, "🚫 Регистрация тренеров по ключу отключена.")
+	if role := f.role(guestID); role != roleGuest {
+		t.Fatalf("role = %q, want guest", role)
+	}
+}
+
+// A one-time team invite registers a guest and turns off the numeric team_<id> links
+func TestConfiguredAdminRegistration(t *testing.T) {
+	f := newFixture(t)
+	f.h.Config.Admin.IDs = []int64{guestID}
+
+	f.send(guestID, "/start")
+	if role := f.role(guestID); role != domain.RoleAdmin {
+		t.Fatalf("role = %q, want admin", role)
+	}
+	assertContains(t, reply(t, f.send(adminID, "/role_log"), adminID),
+		"@guest: новый пользователь → администратор (из конфигурации)")
+}
+
+func TestTeamInviteLink(t *testing.T) {
+	f := newFixture(t)
+	got := reply(t, f.send(coachID, "/invite_link 1 uses:1"), coachID)
+	_, token, ok := strings.Cut(got, "?start=")
+	if !ok {
+		t.Fatalf("no link in %q", got)
+	}
+	token, _, _ = strings.Cut(token, "\n")
+
+	if got, want := reply(t, f.send(guestID, "/start "+token), guestID), "✅ Ты зарегистрирован как спортсмен в команде 'Волна'."; got != want {
+		t.Fatalf("reply = %q, want %q", got, want)
+	}
+	if role := f.role(guestID); role != domain.RoleAthlete {
+		t.Fatalf("role = %q, want athlete", role)
+	}
+
+	const late int64 = 98
+	assertContains(t, reply(t, f.send(late, "/start "+token), late), "🚫 Приглашение недействительно")
+	if got := reply(t, f.send(late, "/start team_1"), late); got != tokenInvitesOnlyMessage {
+		t.Fatalf("reply = %q, want %q", got, tokenInvitesOnlyMessage)
+	}
+	if role := f.role(late); role != roleGuest {
+		t.Fatalf("role = %q, want guest", role)
+	}
+}
+
+// A registered athlete joins one more team through an invite and keeps the first one
+func TestTeamInviteForAthlete(t *testing.T) {
+	f := newFixture(t)
+	got := reply(t, f.send(coachID, "/invite_link 1"), coachID)
+	_, token, _ := strings.Cut(got, "?start=")
+	token, _, _ = strings.Cut(token, "\n")
+
+	if got := reply(t, f.send(athleteID, "/start "+token), athleteID); got != "ℹ️ Ты уже состоишь в этой команде, приглашение не использовано." {
+		t.Fatalf("reply = %q", got)
+	}
+	if got := reply(t, f.send(strangeID, "/start "+token), strangeID); got != "✅ Теперь ты в команде 'Волна'." {
+		t.Fatalf("reply = %q", got)
+	}
+	teams, err := f.r.ListUserTeams(f.ctx, strangeID)
+	f.must(err)
+	if len(teams) != 2 {
+		t.Fatalf("teams = %+v, want both", teams)
+	}
+}
+
+// An athlete in several teams names the team of a new request
+func TestRequestTeamChoice(t *testing.T) {
+	f := newFixture(t)
+	f.must(f.r.AssignUserToTeam(f.ctx, athleteID, 2))
+
+	assertContains(t, reply(t, f.send(athleteID, "/request 5 Тренировка"), athleteID),
+		"❗ Ты состоишь в нескольких командах: Волна (team:1), Прибой (team:2).")
+	if got := reply(t, f.send(athleteID, "/request 5 Тренировка team:3"), athleteID); got != "❗ Ты не состоишь в команде #3." {
+		t.Fatalf("reply = %q", got)
+	}
+
+	records := f.send(athleteID, "/request 5 Тренировка team:2")
+	assertContains(t, reply(t, records, athleteID), "📨 Запрос на 5 баллов отправлен")
+	if msgs := texts(records, messenger.KindMessage, coachID); len(msgs) != 0 {
+		t.Fatalf("coach of another team got %q", msgs)
+	}
+	assertContains(t, reply(t, records, otherID), "Тренировка")
+	two := 2
+	if reqs, _ := f.r.GetPendingRequestsByTeam(f.ctx, &two); len(reqs) != 1 {
+		t.Fatalf("requests of team #2 = %+v, want one", reqs)
+	}
+}
+
+func TestCommandsSuccess(t *testing.T) {
+	tests := []struct {
+		name  string // команда из commands()
+		from  int64
+		text  string
+		setup func(f *fixture)
+		want  string
+		check func(t *testing.T, f *fixture, records []messenger.Record)
+	}{
+		{
+			name: "start", from: guestID, text: "/start",
+			want: "👋 Привет! Добро пожаловать в SurfCoinBot.",
+		},
+		{
+			name: "start", from: athleteID, text: "/start",
+			want: "👋 Привет, kelly! Ты зарегистрирован как спортсмен.",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				menu := records[0]
+				if menu.Kind != messenger.KindCommands || menu.ChatID != athleteID {
+					t.Fatalf("first record = %+v, want the athlete menu", menu)
+				}
+			},
+		},
+		{
+			name: "start", from: guestID, text: "/start team_1",
+			want: "📨 Заявка в команду 'Волна' отправлена тренерам.",
+			check: func(t *testing.T, f *fixture, _ []messenger.Record) {
+				if role := f.role(guestID); role != roleGuest {
+					t.Fatalf("role = %q, want guest until approval", role)
+				}
+			},
+		},
+		{
+			name: "help", from: coachID, text: "/help",
+			want: helpText(domain.RoleCoach),
+		},
+		{
+			name: "athlete", from: guestID, text: "/athlete 1",
+			want: "📨 Заявка в команду 'Волна' отправлена тренерам.",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				assertContains(t, reply(t, records, coachID), "guest")
+				if msgs := texts(records, messenger.KindMessage, otherID); len(msgs) != 0 {
+					t.Fatalf("coach of another team got %q", msgs)
+				}
+			},
+		},
+		{
+			name: "coach", from: guestID, text: "/coach " + testSecret,
+			want: "✅ Ты зарегистрирован как тренер.",
+			check: func(t *testing.T, f *fixture, _ []messenger.Record) {
+				if role := f.role(guestID); role != domain.RoleCoach {
+					t.Fatalf("role = %q, want coach", role)
+				}
+			},
+		},
+		{
+			name: "request", from: athleteID, text: "/request",
+			setup: func(f *fixture) { f.send(coachID, "/add_activity 1 5 Йога") },
+			want:  "🏄 Выбери занятие, за которое просишь баллы (команда 'Волна'):",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				kb, ok := records[0].Markup.(tgbotapi.InlineKeyboardMarkup)
+				if !ok || *kb.InlineKeyboard[0][0].CallbackData != "act:request:1" {
+					t.Fatalf("markup = %+v, want the catalog buttons", records[0].Markup)
+				}
+			},
+		},
+		{
+			name: "request", from: athleteID, text: "/request 5 Тренировка",
+			want: "📨 Запрос на 5 баллов отправлен на подтверждение тренеру.",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				assertContains(t, reply(t, records, coachID), "🔔 Новый запрос на баллы")
+			},
+		},
+		{
+			name: "cancel", from: athleteID, text: "/cancel 1",
+			setup: func(f *fixture) { f.request(5, "Тренировка") },
+			want:  "↩️ Запрос #1 отменён.",
+		},
+		{
+			name: "my_score", from: athleteID, text: "/my_score",
+			setup: func(f *fixture) { f.give(7) },
+			want:  "🏅 Твой текущий счёт: 7 баллов\n📊 Волна: 1 место из 1, 7 баллов за команду",
+		},
+		{
+			name: "ranking", from: coachID, text: "/ranking team:1",
+			setup: func(f *fixture) { f.give(7) },
+			want:  "1. kelly (@kelly) — 7 баллов",
+		},
+		{
+			name: "ranking", from: athleteID, text: "/ranking team:1",
+			setup: func(f *fixture) { f.give(7) },
+			want:  "📍 Ты на 1 месте с 7 баллами.",
+		},
+		{
+			name: "history", from: athleteID, text: "/history",
+			setup: func(f *fixture) { f.give(7) },
+			want:  "📜 История начислений:",
+		},
+		{
+			name: "history", from: coachID, text: "/history @kelly",
+			setup: func(f *fixture) { f.give(7) },
+			want:  "📜 История начислений:",
+		},
+		{
+			name: "pending", from: coachID, text: "/pending",
+			setup: func(f *fixture) { f.request(5, "Тренировка") },
+			want:  "📋 Ожидающие запросы: 1",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				if msgs := texts(records, messenger.KindMessage, coachID); len(msgs) != 2 {
+					t.Fatalf("messages = %q, want a header and one request", msgs)
+				}
+				if _, ok := records[1].Markup.(tgbotapi.InlineKeyboardMarkup); !ok {
+					t.Fatalf("request has no buttons: %+v", records[1])
+				}
+			},
+		},
+		{
+			name: "approve", from: coachID, text: "/approve 1",
+			setup: func(f *fixture) { f.request(5, "Тренировка") },
+			want:  "✅ Запрос #1 подтвержден. Баллы начислены.",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				assertContains(t, reply(t, records, athleteID), "✅ Твой запрос #1 подтверждён!")
+			},
+		},
+		{
+			name: "approve", from: coachID, text: "/approve 1 7 За старание",
+			setup: func(f *fixture) { f.request(5, "Тренировка") },
+			want:  "✅ Запрос #1 подтвержден",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				assertContains(t, reply(t, records, athleteID), "За старание")
+				if score, _ := f.r.GetUserScore(f.ctx, athleteID); score != 7 {
+					t.Fatalf("score = %d, want 7", score)
+				}
+			},
+		},
+		{
+			name: "approve", from: coachID, text: "/approve 1,2",
+			setup: func(f *fixture) { f.request(5, "Тренировка"); f.request(3, "Зарядка") },
+			want:  "📋 Подтверждено 2 из 2.\n✅ #1, #2",
+		},
+		{
+			name: "reject", from: coachID, text: "/reject 1 Нет фото",
+			setup: func(f *fixture) { f.request(5, "Тренировка") },
+			want:  "🚫 Запрос #1 отклонён.",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				assertContains(t, reply(t, records, athleteID), "💬 Причина: Нет фото")
+			},
+		},
+		{
+			name: "give", from: coachID, text: "/give @kelly 10 Соревнования",
+			want: "✅ 10 баллов начислены пользователю @kelly.\n📎 Соревнования",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				assertContains(t, reply(t, records, athleteID), "🏅 Твой счёт: 10 баллов")
+			},
+		},
+		{
+			name: "athletes", from: coachID, text: "/athletes",
+			want: "📋 Список спортсменов:",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				got := reply(t, records, coachID)
+				assertContains(t, got, "@kelly")
+				if strings.Contains(got, "@slater") {
+					t.Fatalf("athlete of another team listed: %q", got)
+				}
+			},
+		},
+		{
+			name: "teams", from: coachID, text: "/teams",
+			want: "📦 Список команд (⭐ — твои):",
+		},
+		{
+			name: "create_team", from: coachID, text: "/create_team Штиль",
+			want: "✅ Команда \"Штиль\" создана. Ты её тренер.",
+		},
+		{
+			name: "delete_team", from: adminID, text: "/delete_team 2",
+			want: "⚠️ Команда 'Прибой' (ID: 2)",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				if _, ok := records[0].Markup.(tgbotapi.InlineKeyboardMarkup); !ok {
+					t.Fatalf("no confirmation buttons: %+v", records[0])
+				}
+			},
+		},
+		{
+			name: "restore_team", from: adminID, text: "/restore_team 2",
+			setup: func(f *fixture) {
+				_, err := f.r.ArchiveTeam(f.ctx, 2, adminID)
+				f.must(err)
+			},
+			want: "♻️ Команда #2 восстановлена.",
+		},
+		{
+			name: "move_athletes", from: adminID, text: "/move_athletes 2 1",
+			want: "✅ Спортсменов переведено из команды #2 в #1: 1.",
+		},
+		{
+			name: "assign_team", from: coachID, text: "/assign_team @nomad 1",
+			setup: func(f *fixture) { f.user(12, "nomad", domain.RoleAthlete) },
+			want:  "✅ Пользователь @nomad добавлен в команду #1.",
+		},
+		{
+			name: "fine", from: coachID, text: "/fine @kelly 3 Опоздание",
+			setup: func(f *fixture) { f.give(10) },
+			want:  "💸 У @kelly списано 3 баллов.\n📎 Опоздание",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				assertContains(t, reply(t, records, athleteID), "Опоздание")
+				if score, _ := f.r.GetUserScore(f.ctx, athleteID); score != 7 {
+					t.Fatalf("score = %d, want 7", score)
+				}
+			},
+		},
+		{
+			name: "revoke", from: coachID, text: "/revoke 1 Ошибка",
+			setup: func(f *fixture) { f.give(10) },
+			want:  "↩️ Начисление #1 отменено",
+			check: func(t *testing.T, f *fixture, _ []messenger.Record) {
+				if score, _ := f.r.GetUserScore(f.ctx, athleteID); score != 0 {
+					t.Fatalf("score = %d, want 0", score)
+				}
+			},
+		},
+		{
+			name: "adjust", from: coachID, text: "/adjust 1 4",
+			setup: func(f *fixture) { f.give(10) },
+			want:  "✏️ Начисление #1 исправлено: 10 → 4 баллов (запись #2).",
+		},
+		{
+			name: "allow_negative", from: coachID, text: "/allow_negative 1 on",
+			want: "✅ В команде #1 штрафы могут уводить счёт в минус.",
+		},
+		{
+			name: "activities", from: coachID, text: "/activities",
+			setup: func(f *fixture) { f.send(coachID, "/add_activity 1 5 Йога") },
+			want:  "📋 Волна (ID: 1)\n• #1 Йога — 5 баллов",
+		},
+		{
+			name: "add_activity", from: coachID, text: "/add_activity 1 5 Йога",
+			want: "✅ Занятие #1 «Йога» — 5 баллов добавлено в каталог команды #1.",
+		},
+		{
+			name: "remove_activity", from: coachID, text: "/remove_activity 1",
+			setup: func(f *fixture) { f.send(coachID, "/add_activity 1 5 Йога") },
+			want:  "🗑 Занятие «Йога» убрано из каталога.",
+		},
+		{
+			name: "free_requests", from: coachID, text: "/free_requests 1 off",
+			want: "✅ В команде #1 баллы теперь запрашиваются только за занятия из каталога.",
+		},
+		{
+			name: "unassign_team", from: coachID, text: "/unassign_team @kelly 1",
+			want: "✅ Пользователь @kelly выведен из команды #1.",
+		},
+		{
+			name: "add_coach", from: coachID, text: "/add_coach 1 @other",
+			want: "✅ @other теперь тренер команды #1.",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				assertContains(t, reply(t, records, otherID), "📦 Теперь ты тренер команды #1.")
+			},
+		},
+		{
+			name: "remove_coach", from: coachID, text: "/remove_coach 1 @other",
+			setup: func(f *fixture) { f.must(f.r.AddTeamCoach(f.ctx, 1, otherID)) },
+			want:  "🗑 @other больше не тренер команды #1.",
+		},
+		{
+			name: "invite_link", from: coachID, text: "/invite_link 1 uses:5",
+			want: "🔗 Приглашение #1 в команду 'Волна'",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				assertContains(t, reply(t, records, coachID), "https://t.me/surf_bot?start="+teamInvitePrefix)
+			},
+		},
+		{
+			name: "joins", from: coachID, text: "/joins",
+			setup: func(f *fixture) { f.send(guestID, "/athlete 1") },
+			want:  "🙋 Заявок на вступление: 1",
+		},
+		{
+			name: "invites", from: coachID, text: "/invites 1",
+			setup: func(f *fixture) { f.send(coachID, "/invite_link 1") },
+			want:  "🔗 Активные приглашения команды #1:",
+		},
+		{
+			name: "revoke_invite", from: coachID, text: "/revoke_invite 1",
+			setup: func(f *fixture) { f.send(coachID, "/invite_link 1") },
+			want:  "🗑 Приглашение #1 отозвано.",
+		},
+		{
+			name: "notify", from: coachID, text: "/notify digest",
+			want: "✅ Уведомления о новых запросах: сводкой.",
+		},
+		{
+			name: "notify", from: coachID, text: "/notify",
+			want: "🔔 Уведомления о новых запросах: сразу.",
+		},
+		{
+			name: "coaches", from: adminID, text: "/coaches",
+			want: "👑 boss (@boss)",
+		},
+		{
+			name: "coach_invite", from: adminID, text: "/coach_invite",
+			want: "🔑 Одноразовое приглашение тренера",
+		},
+		{
+			name: "promote", from: adminID, text: "/promote @kelly",
+			want: "✅ @kelly: спортсмен → тренер.",
+			check: func(t *testing.T, f *fixture, records []messenger.Record) {
+				assertContains(t, reply(t, records, athleteID), "🔄 Твоя роль изменена: спортсмен → тренер.")
+			},
+		},
+		{
+			name: "demote", from: adminID, text: "/demote @other",
+			setup: func(f *fixture) { f.must(f.r.AddTeamCoach(f.ctx, 2, coachID)) },
+			want:  "✅ @other: тренер → спортсмен.",
+		},
+		{
+			name: "set_role", from: adminID, text: "/set_role @kelly admin",
+			want: "✅ @kelly: спортсмен → администратор.",
+		},
+		{
+			name: "role_log", from: adminID, text: "/role_log",
+			setup: func(f *fixture) { f.send(adminID, "/promote @kelly") },
+			want:  "📜 Журнал изменения ролей:",
+		},
+	}
+
+	covered := map[string]bool{}
+	for _, tt := range tests {
+		covered[tt.name] = true
+		t.Run(tt.text, func(t *testing.T) {
+			if cmd, ok := findCommand(tt.name); !ok || !strings.HasPrefix(tt.text, "/"+cmd.Name) {
+				t.Fatalf("case %q does not run /%s", tt.text, tt.name)
+			}
+			f := newFixture(t)
+			if tt.setup != nil {
+				tt.setup(f)
+			}
+			records := f.send(tt.from, tt.text)
+			msgs := texts(records, messenger.KindMessage, tt.from)
+			if len(msgs) == 0 {
+				t.Fatal("no reply")
+			}
+			assertContains(t, msgs[0], tt.want)
+			if tt.check != nil {
+				tt.check(t, f, records)
+			}
+		})
+	}
+
+	for _, cmd := range commands() {
+		if !covered[cmd.Name] {
+			t.Errorf("no success case for /%s", cmd.Name)
+		}
+	}
+}
+
+func TestBotMenus(t *testing.T) {
+	f := newFixture(t)
+	f.must(f.h.SetBotCommands())
+	records := f.rec.Records()
+	if len(records) != 1 || records[0].Kind != messenger.KindCommands || records[0].ChatID != 0 {
+		t.Fatalf("records = %+v, want the default menu", records)
+	}
+	if got, want := records[0].Commands, botCommands(roleGuest); !slices.Equal(got, want) {
+		t.Fatalf("default menu = %+v, want %+v", got, want)
+	}
+
+	records = f.send(guestID, "/coach "+testSecret)
+	if got, want := records[0].Commands, botCommands(domain.RoleCoach); records[0].ChatID != guestID || !slices.Equal(got, want) {
+		t.Fatalf("menu = %+v, want the coach menu of chat %d", records[0], guestID)
+	}
+}
@@ -0,0 +1,89 @@
+// internal/handler/invite.go
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"surf_bot/internal/domain"
+	repo "surf_bot/internal/repository"
+	"surf_bot/internal/util"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Failed coach key or invite attempts allowed per chat, and for all chats
+// together, before further attempts have to wait
+const (
+	maxKeyFails      = 5
+	maxKeyFailsTotal = 50
+	keyFailWindow    = 15 * time.Minute
+)
+
+// coachInvitePrefix starts the /start payload of a coach invitation link
+const coachInvitePrefix = "coach_"
+
+// handleCoachInvite creates a single-use coach invitation link
+func (h *TelegramHandler) handleCoachInvite(ctx context.Context, chatID int64, admin *domain.User) {
+	token, err := util.NewToken()
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось создать приглашение: "+err.Error()))
+		return
+	}
+
+	expiresAt := time.Now().Add(h.Config.Coach.InviteTTL.Std())
+	if err := h.Repo.CreateCoachInvite(ctx, util.HashToken(token), admin.ID, expiresAt); err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+
+	link := fmt.Sprintf("https://t.me/%s?start=%s%s", h.Bot.Username(), coachInvitePrefix, token)
+	msg := fmt.Sprintf("🔑 Одноразовое приглашение тренера, действует до %s:\n%s\n\n"+
+		"Перешли ссылку будущему тренеру. Она сработает только один раз.",
+		expiresAt.Format("02.01.2006 15:04"), link)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+}
+
+// redeemCoachInvite registers an unknown chat as a coach by invitation token
+func (h *TelegramHandler) redeemCoachInvite(ctx context.Context, chatID int64, token string, from *tgbotapi.User) {
+	if ok, wait := h.keyFails.Allow(chatID); !ok {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, tooManyAttemptsMessage(wait)))
+		return
+	}
+
+	err := h.Repo.RedeemCoachInvite(ctx, util.HashToken(token), &domain.User{
+		ID:       chatID,
+		Name:     from.FirstName,
+		Username: from.UserName,
+	})
+	if errors.Is(err, repo.ErrInviteInvalid) {
+		h.keyFails.Fail(chatID)
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Приглашение недействительно: оно истекло или уже использовано."))
+		return
+	}
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка регистрации: "+err.Error()))
+		return
+	}
+
+	log.Printf("🧑‍🏫 User %d (@%s) registered as coach by invitation", chatID, from.UserName)
+	h.setChatMenu(chatID, domain.RoleCoach)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "✅ Ты зарегистрирован как тренер.\n\n"+helpText(domain.RoleCoach)))
+}
+
+// secretMatches compares secrets in constant time, regardless of their length
+func secretMatches(provided, secret string) bool {
+	a := sha256.Sum256([]byte(provided))
+	b := sha256.Sum256([]byte(secret))
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1
+}
+
+func tooManyAttemptsMessage(wait time.Duration) string {
+	return fmt.Sprintf("⏳ Слишком много неверных попыток. Попробуй через %d мин.", int(math.Ceil(wait.Minutes())))
+}
@@ -0,0 +1,180 @@
+// internal/handler/join.go
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"surf_bot/internal/domain"
+	repo "surf_bot/internal/repository"
+	"surf_bot/internal/util"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Actions of join request buttons: "join:<action>:<id>"
+const (
+	joinApprove = "approve"
+	joinDeny    = "deny"
+)
+
+func joinKeyboard(id int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Принять", fmt.Sprintf("%s:%s:%d", callbackJoin, joinApprove, id)),
+			tgbotapi.NewInlineKeyboardButtonData("🚫 Отказать", fmt.Sprintf("%s:%s:%d", callbackJoin, joinDeny, id)),
+		),
+	)
+}
+
+func formatJoinRequest(req domain.JoinRequest) string {
+	return fmt.Sprintf("ID: %d | 👤 %s (@%s) → команда '%s'", req.ID, req.Name, req.Username, req.TeamName)
+}
+
+func joinPendingMessage(req *domain.JoinRequest) string {
+	return fmt.Sprintf("⏳ Твоя заявка в команду '%s' ждёт одобрения тренера. "+
+		"Пока её не одобрят, команды спортсмена недоступны — я напишу, когда будет решение.", req.TeamName)
+}
+
+// applyToTeam files a join request of an unregistered chat and tells the team's coaches
+func (h *TelegramHandler) applyToTeam(ctx context.Context, chatID int64, team *domain.Team, from *tgbotapi.User) {
+	req, err := h.Repo.CreateJoinRequest(ctx, &domain.User{
+		ID:       chatID,
+		Name:     from.FirstName,
+		Username: from.UserName,
+		Role:     domain.RoleAthlete,
+	}, team.ID)
+	if errors.Is(err, repo.ErrJoinPending) {
+		h.replyJoinPending(ctx, chatID)
+		return
+	}
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка регистрации: "+err.Error()))
+		return
+	}
+	h.joinRequested(ctx, chatID, req)
+}
+
+// joinRequested confirms a new join request to the applicant and sends it to the coaches
+func (h *TelegramHandler) joinRequested(ctx context.Context, chatID int64, req *domain.JoinRequest) {
+	log.Printf("📨 User %d (@%s) asked to join team #%d, request #%d", chatID, req.Username, req.TeamID, req.ID)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"📨 Заявка в команду '%s' отправлена тренерам. Я напишу, когда её рассмотрят.", req.TeamName)))
+	h.notifyJoinRequest(ctx, *req)
+}
+
+func (h *TelegramHandler) replyJoinPending(ctx context.Context, chatID int64) {
+	req, err := h.Repo.GetPendingJoinRequest(ctx, chatID)
+	if err != nil || req == nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "⏳ "+repo.ErrJoinPending.Error()))
+		return
+	}
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, joinPendingMessage(req)))
+}
+
+// notifyJoinRequest sends the request with buttons to the team's coaches,
+// or to the administrators if the team has none
+func (h *TelegramHandler) notifyJoinRequest(ctx context.Context, req domain.JoinRequest) {
+	recipients, err := h.Repo.ListTeamCoaches(ctx, req.TeamID)
+	if err != nil {
+		log.Printf("⚠️  failed to list coaches of team #%d: %v", req.TeamID, err)
+	}
+	if len(recipients) == 0 {
+		staff, err := h.Repo.ListStaff(ctx)
+		if err != nil {
+			log.Printf("⚠️  failed to list staff: %v", err)
+		}
+		for _, u := range staff {
+			if u.Role == domain.RoleAdmin {
+				recipients = append(recipients, u.ID)
+			}
+		}
+	}
+
+	for _, id := range recipients {
+		msg := tgbotapi.NewMessage(id, "🙋 Новая заявка на вступление\n\n"+formatJoinRequest(req))
+		msg.ReplyMarkup = joinKeyboard(req.ID)
+		util.SafeSend(h.Bot, msg)
+	}
+}
+
+// handleJoinCallback approves or denies a join request from its buttons
+func (h *TelegramHandler) handleJoinCallback(ctx context.Context, cb *tgbotapi.CallbackQuery, coach *domain.User, action string, id int) {
+	chatID := cb.Message.Chat.ID
+	messageID := cb.Message.MessageID
+
+	req, err := h.Repo.GetJoinRequest(ctx, id)
+	if err != nil || req.Status != domain.JoinPending {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "⚠️ Заявка уже рассмотрена."))
+		h.closeRequestMessage(chatID, messageID, cb.Message.Text, "⚠️ Заявка уже рассмотрена.")
+		return
+	}
+	if coach.Role != domain.RoleAdmin {
+		if ok, _ := h.Repo.CoachesTeam(ctx, coach.ID, req.TeamID); !ok {
+			util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, teamDeniedMessage(req.TeamID)))
+			return
+		}
+	}
+
+	switch action {
+	case joinApprove:
+		req, err = h.Repo.ApproveJoinRequest(ctx, id, coach.ID)
+		if err != nil {
+			util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "❌ Не удалось принять заявку."))
+			h.closeRequestMessage(chatID, messageID, cb.Message.Text, "❌ "+err.Error())
+			return
+		}
+		log.Printf("🏄 User %d (@%s) joined team #%d, approved by %d", req.UserID, req.Username, req.TeamID, coach.ID)
+		h.setChatMenu(req.UserID, domain.RoleAthlete)
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(req.UserID, fmt.Sprintf(
+			"✅ Тренер %s принял тебя в команду '%s'!\n\n%s", coach.Name, req.TeamName, helpText(domain.RoleAthlete))))
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, fmt.Sprintf("✅ Заявка #%d принята.", id)))
+		h.closeRequestMessage(chatID, messageID, cb.Message.Text,
+			fmt.Sprintf("✅ Принято тренером %s.", coach.Name))
+
+	case joinDeny:
+		req, err = h.Repo.DenyJoinRequest(ctx, id, coach.ID)
+		if err != nil {
+			util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "⚠️ Заявка уже рассмотрена."))
+			h.closeRequestMessage(chatID, messageID, cb.Message.Text, "⚠️ Заявка уже рассмотрена.")
+			return
+		}
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(req.UserID, fmt.Sprintf(
+			"🚫 Тренер отклонил твою заявку в команду '%s'. Если это ошибка, попроси у тренера новое приглашение.",
+			req.TeamName)))
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, fmt.Sprintf("🚫 Заявка #%d отклонена.", id)))
+		h.closeRequestMessage(chatID, messageID, cb.Message.Text,
+			fmt.Sprintf("🚫 Отклонено тренером %s.", coach.Name))
+
+	default:
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "❓ Неизвестное действие."))
+	}
+}
+
+// handleJoins lists join requests waiting in the coach's teams, each with its buttons
+func (h *TelegramHandler) handleJoins(ctx context.Context, chatID int64, user *domain.User) {
+	var reqs []domain.JoinRequest
+	var err error
+	if user.Role == domain.RoleAdmin {
+		reqs, err = h.Repo.ListJoinRequests(ctx)
+	} else {
+		reqs, err = h.Repo.ListJoinRequestsByCoach(ctx, user.ID)
+	}
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+	if len(reqs) == 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "📭 Заявок на вступление нет."))
+		return
+	}
+
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("🙋 Заявок на вступление: %d", len(reqs))))
+	for _, req := range reqs {
+		msg := tgbotapi.NewMessage(chatID, formatJoinRequest(req)+"\n🕒 "+req.CreatedAt.Format("02.01.2006 15:04"))
+		msg.ReplyMarkup = joinKeyboard(req.ID)
+		util.SafeSend(h.Bot, msg)
+	}
+}
@@ -0,0 +1,190 @@
+// internal/handler/notify.go
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"surf_bot/internal/domain"
+	repo "surf_bot/internal/repository"
+	"surf_bot/internal/util"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// notifyGrant tells an athlete about credited points together with their new
+// total and place in the team. title is the first line, e.g. "✅ Запрос #3 подтверждён!"
+func (h *TelegramHandler) notifyGrant(ctx context.Context, g *repo.Grant, title, coachName string) {
+	sign := "➕"
+	if g.Amount < 0 {
+		sign = "➖"
+	}
+	msg := fmt.Sprintf("%s\n\n%s %+d баллов\n📎 %s\n👤 Тренер: %s", title, sign, g.Amount, g.Reason, coachName)
+	if g.Requested != nil && *g.Requested != g.Amount {
+		msg += fmt.Sprintf("\n✏️ Ты просил %d, тренер начислил %d", *g.Requested, g.Amount)
+	}
+	if g.Comment != "" {
+		msg += "\n💬 " + g.Comment
+	}
+
+	score, err := h.Repo.GetUserScore(ctx, g.UserID)
+	if err == nil {
+		msg += fmt.Sprintf("\n\n🏅 Твой счёт: %d баллов", score)
+	}
+	if g.TeamID != nil {
+		if place, total, ok := h.teamPlace(ctx, g.UserID, *g.TeamID); ok {
+			msg += fmt.Sprintf("\n📊 Место в команде: %d из %d", place, total)
+		}
+	}
+
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(g.UserID, msg))
+}
+
+// notifyRejection tells an athlete why their request was rejected
+func (h *TelegramHandler) notifyRejection(athleteID int64, id int, coachName, reason string) {
+	msg := fmt.Sprintf("🚫 Твой запрос #%d отклонён тренером %s.\n💬 Причина: %s", id, coachName, reason)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(athleteID, msg))
+}
+
+// teamPlace returns the athlete's place in the team ranking and the team size
+func (h *TelegramHandler) teamPlace(ctx context.Context, userID int64, teamID int) (place, total int, ok bool) {
+	ranking, err := h.Repo.GetRankingByTeam(ctx, teamID)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	for i, entry := range ranking {
+		if entry.UserID == userID {
+			return i + 1, len(ranking), true
+		}
+	}
+	return 0, 0, false
+}
+
+// notifyCoaches tells the athlete's coaches about a new request, right away or
+// through the digest depending on each coach's setting
+func (h *TelegramHandler) notifyCoaches(ctx context.Context, athleteID int64, teamID *int, requestID int) {
+	recipients, err := h.Repo.ListRequestRecipients(ctx, athleteID, teamID)
+	if err != nil {
+		log.Printf("⚠️  failed to list coaches for request #%d: %v", requestID, err)
+		return
+	}
+
+	var req *repo.PendingRequest
+	for _, r := range recipients {
+		switch r.Mode {
+		case domain.NotifyDigest:
+			h.digest.Add(r.ID, requestID)
+		case domain.NotifyInstant:
+			if !h.digest.Instant(r.ID, requestID) {
+				continue // волна запросов — остаток окна придёт сводкой
+			}
+			if req == nil {
+				if req, err = h.Repo.GetPendingRequest(ctx, requestID); err != nil {
+					log.Printf("⚠️  failed to load request #%d: %v", requestID, err)
+					return
+				}
+			}
+			h.sendRequest(r.ID, *req, "🔔 Новый запрос на баллы", false)
+		}
+	}
+}
+
+// sendDigest delivers requests collected for a coach: one audible summary,
+// then each still pending request silently with its buttons
+func (h *TelegramHandler) sendDigest(coachID int64, requestIDs []int) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Config.Bot.UpdateTimeout.Std())
+	defer cancel()
+
+	var requests []repo.PendingRequest
+	for _, id := range requestIDs {
+		// пока копилась сводка, запрос могли уже рассмотреть
+		req, err := h.Repo.GetPendingRequest(ctx, id)
+		if err == nil {
+			requests = append(requests, *req)
+		}
+	}
+
+	switch len(requests) {
+	case 0:
+		return
+	case 1:
+		h.sendRequest(coachID, requests[0], "🔔 Новый запрос на баллы", false)
+		return
+	}
+
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(coachID,
+		fmt.Sprintf("🔔 Новых запросов на баллы: %d. Подтверди или отклони их кнопками ниже.", len(requests))))
+	for _, req := range requests {
+		h.sendRequest(coachID, req, "", true)
+	}
+}
+
+// sendRequest sends a pending request with approve/reject/edit buttons
+func (h *TelegramHandler) sendRequest(chatID int64, req repo.PendingRequest, title string, silent bool) {
+	text := formatPendingRequest(req)
+	if title != "" {
+		text = title + "\n\n" + text
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = pendingKeyboard(req.ID)
+	msg.DisableNotification = silent
+	util.SafeSend(h.Bot, msg)
+}
+
+var notifyModeNames = map[domain.NotifyMode]string{
+	domain.NotifyInstant: "сразу",
+	domain.NotifyDigest:  "сводкой",
+	domain.NotifyOff:     "выключены",
+}
+
+func (h *TelegramHandler) handleNotify(ctx context.Context, chatID int64, args string) {
+	mode := domain.NotifyMode(strings.ToLower(strings.TrimSpace(args)))
+
+	if mode == "" {
+		current, err := h.Repo.GetNotifyMode(ctx, chatID)
+		if err != nil {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+			return
+		}
+		msg := fmt.Sprintf("🔔 Уведомления о новых запросах: %s.\n\n"+
+			"/notify instant — сразу о каждом запросе%s\n"+
+			"/notify digest — сводкой раз в %s\n"+
+			"/notify off — не присылать",
+			notifyModeNames[current], h.burstNote(), formatWindow(h.Config.Notify.DigestWindow.Std()))
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+		return
+	}
+
+	if _, ok := notifyModeNames[mode]; !ok {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("notify")))
+		return
+	}
+
+	if err := h.Repo.SetNotifyMode(ctx, chatID, mode); err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Уведомления о новых запросах: %s.", notifyModeNames[mode])))
+}
+
+// burstNote explains when instant notifications fall back to a digest
+func (h *TelegramHandler) burstNote() string {
+	n := h.Config.Notify.BurstThreshold
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (после %d запросов за %s остальные придут сводкой)",
+		n, formatWindow(h.Config.Notify.DigestWindow.Std()))
+}
+
+// formatWindow renders a digest window like "15 мин"
+func formatWindow(d time.Duration) string {
+	if d%time.Minute == 0 {
+		return fmt.Sprintf("%d мин", int(d.Minutes()))
+	}
+	return d.String()
+}
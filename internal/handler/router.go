@@ -2,21 +2,31 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
 
 	"surf_bot/internal/domain"
+	repo "surf_bot/internal/repository"
 	"surf_bot/internal/util"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-func isCommand(text, cmd string) bool {
-	return strings.TrimSpace(text) == cmd
-}
+// HandleUpdate processes a single update. Every update gets its own deadline
+// so a slow query cannot hold a worker forever.
+func (h *TelegramHandler) HandleUpdate(ctx context.Context, update tgbotapi.Update) {
+	ctx, cancel := context.WithTimeout(ctx, h.Config.Bot.UpdateTimeout.Std())
+	defer cancel()
+
+	if update.CallbackQuery != nil {
+		h.handleCallback(ctx, update.CallbackQuery)
+		return
+	}
 
-func (h *TelegramHandler) HandleUpdate(update tgbotapi.Update) {
 	if update.Message == nil {
 		return
 	}
@@ -24,167 +34,158 @@ func (h *TelegramHandler) HandleUpdate(update tgbotapi.Update) {
 	chatID := update.Message.Chat.ID
 	text := update.Message.Text
 
-	user, _ := h.Repo.GetUserByID(chatID)
-
-	switch {
-	case strings.HasPrefix(text, "/start"):
-		args := strings.TrimSpace(strings.TrimPrefix(text, "/start"))
-    	h.handleStart(chatID, user, args, update.Message.From)
-
-	case isCommand(text, "/athlete"):
-		h.handleAthlete(chatID, update)
-
-	case strings.HasPrefix(text, "/coach"):
-		h.handleCoach(chatID, update)
-
-	case strings.HasPrefix(text, "/ranking"):
-		h.handleRanking(chatID, user, text)
-
-	case strings.HasPrefix(text, "/request"):
-		h.handleRequest(chatID, text, user)
-
-	case strings.HasPrefix(text, "/pending"):
-		h.handlePending(chatID, user, text)
-
-	case strings.HasPrefix(text, "/approve"):
-		h.handleApprove(chatID, text, user)
+	user, _ := h.Repo.GetUserByID(ctx, chatID)
 
-	case strings.HasPrefix(text, "/give"):
-		h.handleGive(chatID, text, user)
-
-	case strings.HasPrefix(text, "/athletes"):
-		h.handleAthletes(chatID, user, text)
+	name, args, ok := parseCommand(text)
+	if ok {
+		// команда отменяет ожидание ответа, иначе следующий текст применится к старому запросу
+		h.takePrompt(chatID)
+	} else if prompt, ok := h.takePrompt(chatID); ok {
+		h.handlePromptReply(ctx, chatID, text, user, prompt)
+		return
+	}
 
-	case strings.HasPrefix(text, "/reject"):
-		h.handleReject(chatID, text, user)
+	cmd, found := findCommand(name)
+	if !ok || !found {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❓ Неизвестная команда. Напиши /help."))
+		return
+	}
 
-	case strings.HasPrefix(text, "/history"):
-		h.handleHistory(chatID, text, user)
+	if !cmd.Allows(user) {
+		msg := deniedMessage(cmd, user)
+		if user == nil {
+			// заявка в команду ещё не одобрена — объясняем, чего ждать
+			if req, err := h.Repo.GetPendingJoinRequest(ctx, chatID); err == nil && req != nil {
+				msg = joinPendingMessage(req)
+			}
+		}
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+		return
+	}
 
-	case isCommand(text, "/my_score"):
-		h.handleMyScore(chatID, user)
-	
-	case isCommand(text, "/teams"):
-		h.handleTeams(chatID, user)
-	
-	case strings.HasPrefix(text, "/invite_link"):
-		h.handleInviteLink(chatID, text, user)
-	
-	case strings.HasPrefix(text, "/create_team"):
-		h.handleCreateTeam(chatID, text, user)
+	cmdText := "/" + cmd.Name
+	if args != "" {
+		cmdText += " " + args
+	}
 
-	case strings.HasPrefix(text, "/delete_team"):
-		h.handleDeleteTeam(chatID, text, user)
+	cmd.Handler(h, ctx, &commandContext{
+		ChatID:  chatID,
+		Text:    cmdText,
+		Args:    args,
+		User:    user,
+		Message: update.Message,
+	})
+}
 
-	case strings.HasPrefix(text, "/assign_team"):
-		h.handleAssignTeam(chatID, text, user)
+// SetBotCommands publishes the default command menu shown to unregistered chats.
+// Registered users get their own per-chat menu, see setChatMenu.
+func (h *TelegramHandler) SetBotCommands() error {
+	return h.Bot.SetCommands(tgbotapi.NewSetMyCommands(botCommands(roleGuest)...))
+}
 
-	default:
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❓ Неизвестная команда. Напиши /start."))
+// setChatMenu replaces the command menu of a single chat with the commands of the given role
+func (h *TelegramHandler) setChatMenu(chatID int64, role domain.Role) {
+	scope := tgbotapi.NewBotCommandScopeChat(chatID)
+	if err := h.Bot.SetCommands(tgbotapi.NewSetMyCommandsWithScope(scope, botCommands(role)...)); err != nil {
+		log.Printf("⚠️  failed to set chat commands: %v", err)
 	}
 }
 
-func (h *TelegramHandler) SetBotCommands() error {
-	commands := []tgbotapi.BotCommand{
-		{Command: "start", Description: "Начать работу с ботом"},
-		{Command: "athlete", Description: "Зарегистрироваться как спортсмен"},
-		{Command: "coach", Description: "Зарегистрироваться как тренер"},
-		{Command: "ranking", Description: "Посмотреть рейтинг своей команды"},
-		{Command: "request", Description: "Запросить баллы: /request <баллы> <причина>"},
-		{Command: "pending", Description: "Список ожидающих запросов"},
-		{Command: "approve", Description: "Подтвердить запрос: /approve <id>"},
-		{Command: "reject", Description: "Отклонить запрос: /reject <id>"},
-		{Command: "give", Description: "Начислить баллы: /give @username <баллы> <причина>"},
-		{Command: "athletes", Description: "Список спортсменов"},
-		{Command: "history", Description: "История начислений или /history @username"},
-		{Command: "my_score", Description: "Текущий счёт спортсмена"},
-		{Command: "create_team", Description: "Создать команду: /create_team <название>"},
-		{Command: "delete_team", Description: "Удалить команду: /delete_team <название>"},
-		{Command: "assign_team", Description: "Добавить в команду: /assign_team @username <team_id>"},
-		{Command: "teams", Description: "Список всех команд"},
-		{Command: "invite_link", Description: "Пригласить в команду: /invite_link <team_id>"},
-
-	}
-
-	cfg := tgbotapi.NewSetMyCommands(commands...)
-	_, err := h.Bot.Request(cfg)
-	return err
+func (h *TelegramHandler) handleHelp(chatID int64, user *domain.User) {
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, helpText(roleOf(user))))
 }
 
-func (h *TelegramHandler) handleStart(chatID int64, user *domain.User, args string, from *tgbotapi.User) {
+func (h *TelegramHandler) handleStart(ctx context.Context, chatID int64, user *domain.User, args string, from *tgbotapi.User) {
+	if user == nil && h.isConfiguredAdmin(chatID) {
+		admin := &domain.User{
+			ID:       chatID,
+			Name:     from.FirstName,
+			Username: from.UserName,
+			Role:     domain.RoleAdmin,
+		}
+		if err := h.Repo.RegisterAdmin(ctx, admin); err != nil {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка регистрации: "+err.Error()))
+			return
+		}
+		log.Printf("👑 User %d (@%s) registered as admin", chatID, from.UserName)
+		user = admin
+	}
+
 	if user == nil {
+		if token, ok := strings.CutPrefix(args, coachInvitePrefix); ok {
+			h.redeemCoachInvite(ctx, chatID, token, from)
+			return
+		}
+
+		if token, ok := strings.CutPrefix(args, teamInvitePrefix); ok {
+			h.redeemTeamInvite(ctx, chatID, token, from, nil)
+			return
+		}
+
 		if strings.HasPrefix(args, "team_") {
 			teamIDStr := strings.TrimPrefix(args, "team_")
 			teamID, err := strconv.Atoi(teamIDStr)
 			if err == nil && teamID > 0 {
 				// Проверка наличия команды
-				team, err := h.Repo.GetTeamByID(teamID)
+				team, err := h.Repo.GetTeamByID(ctx, teamID)
+				if err == nil && team.ArchivedAt != nil {
+					util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🗄 Эта команда в архиве и не принимает новых спортсменов."))
+					return
+				}
+				if err == nil && team.TokenInvites {
+					util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, tokenInvitesOnlyMessage))
+					return
+				}
 				if err == nil {
-					newUser := &domain.User{
-						ID:       chatID,
-						Name:     from.FirstName,
-						Username: from.UserName,
-						Role:     domain.RoleAthlete,
-					}
-					err = h.Repo.RegisterUser(newUser)
-					if err == nil {
-						_ = h.Repo.AssignUserToTeam(chatID, teamID)
-						util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID,
-							fmt.Sprintf("✅ Ты зарегистрирован как спортсмен в команде '%s'.", team.Name)))
-						return
-					}
+					h.applyToTeam(ctx, chatID, team, from)
+					return
 				}
 			}
 
 			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID,
-				"❌ Не удалось зарегистрироваться: неверный ID команды или ошибка регистрации."))
+				"❌ Не удалось зарегистрироваться: неверный ID команды."))
+			return
+		}
+
+		if req, err := h.Repo.GetPendingJoinRequest(ctx, chatID); err == nil && req != nil {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, joinPendingMessage(req)))
 			return
 		}
 
 		msg := "👋 Привет! Добро пожаловать в SurfCoinBot.\n\n" +
-			"Для начала укажи свою роль:\n" +
-			"• /athlete — если ты спортсмен\n" +
-			"• /coach <секретный_ключ> — если ты тренер\n\n" +
+			"Для начала укажи свою роль.\n" +
+			helpText(roleGuest) + "\n" +
 			"После этого ты сможешь использовать соответствующие команды."
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
 		return
 	}
 
-	// уже зарегистрирован
+	// уже зарегистрирован: спортсмен может вступить по приглашению ещё в одну команду
+	if token, ok := strings.CutPrefix(args, teamInvitePrefix); ok && user.Role == domain.RoleAthlete {
+		h.redeemTeamInvite(ctx, chatID, token, from, user)
+		return
+	}
+
+	var msg string
 	switch user.Role {
 	case domain.RoleAthlete:
-		msg := "👋 Привет, " + user.Name + "! Ты зарегистрирован как спортсмен.\n\n" +
-			"📋 Доступные команды:\n" +
-			"• /request <баллы> <причина> — отправить запрос на баллы\n" +
-			"• /my_score — посмотреть свой счёт\n" +
-			"• /ranking — общий рейтинг\n" +
-			"• /history — история начислений\n"
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
-
+		msg = "👋 Привет, " + user.Name + "! Ты зарегистрирован как спортсмен.\n\n"
 	case domain.RoleCoach:
-		msg := "👋 Добро пожаловать, тренер " + user.Name + "!\n\n" +
-			"📋 Доступные команды:\n" +
-			"• /pending — список запросов на подтверждение\n" +
-			"• /approve <id> — подтвердить запрос\n" +
-			"• /reject <id> — отклонить запрос\n" +
-			"• /give <баллы> @username <причина> — начислить баллы вручную\n" +
-			"• /athletes — список спортсменов\n" +
-			"• /ranking — рейтинг по командам\n" +
-			"• /history @username — история начислений спортсмена\n" +
-			"• /create_team <название> — создать команду\n" +
-			"• /delete_team <название> — удалить команду\n" +
-			"• /assign_team @username <team_id> — прикрепить спортсмена\n" +
-			"• /teams — список команд\n" +
-			"• /invite_link <team_id> — получить ссылку-приглашение"
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+		msg = "👋 Добро пожаловать, тренер " + user.Name + "!\n\n"
+	case domain.RoleAdmin:
+		msg = "👋 Добро пожаловать, администратор " + user.Name + "!\n\n"
 	}
+	if strings.HasPrefix(args, coachInvitePrefix) || strings.HasPrefix(args, teamInvitePrefix) {
+		msg = "ℹ️ Ты уже зарегистрирован, приглашение не использовано. Сменить роль может администратор.\n\n" + msg
+	}
+	h.setChatMenu(chatID, user.Role)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg+helpText(user.Role)))
 }
 
-
-func (h *TelegramHandler) handleAthlete(chatID int64, update tgbotapi.Update) {
-	args := strings.Fields(update.Message.Text)
+func (h *TelegramHandler) handleAthlete(ctx context.Context, chatID int64, text string, from *tgbotapi.User) {
+	args := strings.Fields(text)
 	if len(args) != 2 {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Формат: /athlete <id_команды>"))
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("athlete")))
 		return
 	}
 
@@ -194,62 +195,42 @@ func (h *TelegramHandler) handleAthlete(chatID int64, update tgbotapi.Update) {
 		return
 	}
 
-	team, err := h.Repo.GetTeamByID(teamID)
+	team, err := h.Repo.GetTeamByID(ctx, teamID)
 	if err != nil {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Команда не найдена: "+err.Error()))
 		return
 	}
-
-	existing, _ := h.Repo.GetUserByID(chatID)
-	if existing != nil {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID,
-			fmt.Sprintf("ℹ️ Ты уже зарегистрирован как %s.", existing.Role)))
+	if team.ArchivedAt != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🗄 Эта команда в архиве и не принимает новых спортсменов."))
 		return
 	}
-
-	name := update.Message.From.FirstName
-	username := update.Message.From.UserName
-
-	user := &domain.User{
-		ID:       chatID,
-		Name:     name,
-		Username: username,
-		Role:     domain.RoleAthlete,
-	}
-
-	err = h.Repo.RegisterUser(user)
-	if err != nil {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка регистрации: "+err.Error()))
+	if team.TokenInvites {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, tokenInvitesOnlyMessage))
 		return
 	}
 
-	err = h.Repo.AssignUserToTeam(chatID, team.ID)
-	if err != nil {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось добавить в команду: "+err.Error()))
-		return
-	}
-
-	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID,
-		fmt.Sprintf("✅ Ты зарегистрирован как спортсмен в команде '%s'.", team.Name)))
+	h.applyToTeam(ctx, chatID, team, from)
 }
 
-
-func (h *TelegramHandler) handleCoach(chatID int64, update tgbotapi.Update) {
-	providedKey := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/coach "))
-	if providedKey != h.SecretCoach {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Неверный секретный ключ."))
+func (h *TelegramHandler) handleCoach(ctx context.Context, chatID int64, providedKey string, from *tgbotapi.User) {
+	if h.Config.Coach.Secret == "" {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID,
+			"🚫 Регистрация тренеров по ключу отключена. Попроси администратора прислать приглашение."))
 		return
 	}
-
-	user, _ := h.Repo.GetUserByID(chatID)
-	if user != nil {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "ℹ️ Ты уже зарегистрирован как "+string(user.Role)+"."))
+	if ok, wait := h.keyFails.Allow(chatID); !ok {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, tooManyAttemptsMessage(wait)))
+		return
+	}
+	if !secretMatches(providedKey, h.Config.Coach.Secret) {
+		h.keyFails.Fail(chatID)
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Неверный секретный ключ."))
 		return
 	}
 
-	name := update.Message.From.FirstName
-	username := update.Message.From.UserName
-	err := h.Repo.RegisterUser(&domain.User{
+	name := from.FirstName
+	username := from.UserName
+	err := h.Repo.RegisterUser(ctx, &domain.User{
 		ID:       chatID,
 		Name:     name,
 		Username: username,
@@ -258,16 +239,12 @@ func (h *TelegramHandler) handleCoach(chatID int64, update tgbotapi.Update) {
 	if err != nil {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка регистрации: "+err.Error()))
 	} else {
+		h.setChatMenu(chatID, domain.RoleCoach)
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "✅ Ты зарегистрирован как тренер."))
 	}
 }
 
-func (h *TelegramHandler) handleRanking(chatID int64, user *domain.User, text string) {
-	if user == nil {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "Сначала зарегистрируйся через /start."))
-		return
-	}
-
+func (h *TelegramHandler) handleRanking(ctx context.Context, chatID int64, user *domain.User, text string) {
 	var (
 		teamID   int
 		teamName string
@@ -289,17 +266,27 @@ func (h *TelegramHandler) handleRanking(chatID int64, user *domain.User, text st
 	var ranking []domain.ScoreEntry
 	var err error
 
+	isCoach := user.Role == domain.RoleCoach
+
 	if teamID > 0 {
-		team, errTeam := h.Repo.GetTeamByID(teamID)
+		team, errTeam := h.Repo.GetTeamByID(ctx, teamID)
 		if errTeam != nil {
 			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Команда не найдена."))
 			return
 		}
+		if isCoach && !h.requireTeam(ctx, chatID, user, teamID) {
+			return
+		}
+		if user.Role == domain.RoleAthlete && !h.requireMember(ctx, chatID, user.ID, teamID) {
+			return
+		}
 		teamName = team.Name
 
-		ranking, err = h.Repo.GetRankingByTeam(teamID)
+		ranking, err = h.Repo.GetRankingByTeam(ctx, teamID)
+	} else if isCoach {
+		ranking, err = h.Repo.GetRankingByCoach(ctx, user.ID)
 	} else {
-		ranking, err = h.Repo.GetRanking()
+		ranking, err = h.Repo.GetRanking(ctx)
 	}
 
 	if err != nil {
@@ -319,6 +306,8 @@ func (h *TelegramHandler) handleRanking(chatID int64, user *domain.User, text st
 	title := "🏆 Общий рейтинг спортсменов:\n"
 	if teamID > 0 {
 		title = fmt.Sprintf("🏆 Рейтинг команды %s:\n", teamName)
+	} else if isCoach {
+		title = "🏆 Рейтинг спортсменов твоих команд (баллы, заработанные в них):\n"
 	}
 
 	msg := title
@@ -334,19 +323,18 @@ func (h *TelegramHandler) handleRanking(chatID int64, user *domain.User, text st
 	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
 }
 
-
-
 // handleRequest processes an athlete's points request.
-func (h *TelegramHandler) handleRequest(chatID int64, text string, user *domain.User) {
-	if user == nil || user.Role != domain.RoleAthlete {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Только спортсмены могут отправлять запросы на баллы."))
-		return
-	}
-
+// Without an amount it offers the team's activity catalog (see activity.go).
+func (h *TelegramHandler) handleRequest(ctx context.Context, chatID int64, text string, user *domain.User) {
 	// Parse command: /request <amount> <reason>
 	parts := strings.SplitN(text, " ", 3)
-	if len(parts) < 2 {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи количество баллов после команды /request."))
+	if len(parts) < 2 || strings.HasPrefix(parts[1], "team:") {
+		rest, teamArg, ok := cutTeamArg(strings.Join(parts[1:], " "))
+		if !ok || rest != "" {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("request")))
+			return
+		}
+		h.offerActivities(ctx, chatID, user, teamArg)
 		return
 	}
 	if len(parts) < 3 {
@@ -360,39 +348,65 @@ func (h *TelegramHandler) handleRequest(chatID int64, text string, user *domain.
 		return
 	}
 
-	reason := strings.TrimSpace(parts[2])
+	reason, teamArg, ok := cutTeamArg(parts[2])
+	if !ok {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи команду в виде team:<id>."))
+		return
+	}
 	if reason == "" {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи причину запроса."))
 		return
 	}
 
-	err = h.Repo.CreatePendingRequest(chatID, amount, reason)
+	teamID, ok := h.pointTeam(ctx, chatID, user, user, teamArg)
+	if !ok {
+		return
+	}
+	if teamID != nil {
+		team, err := h.Repo.GetTeamByID(ctx, *teamID)
+		if err != nil {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+			return
+		}
+		if !team.FreeRequests {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+				"🔒 В команде '%s' баллы запрашиваются только за занятия из каталога.", team.Name)))
+			h.offerActivities(ctx, chatID, user, team.ID)
+			return
+		}
+	}
+
+	id, err := h.Repo.CreatePendingRequest(ctx, chatID, amount, reason, teamID)
 	if err != nil {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось создать запрос: "+err.Error()))
 		return
 	}
 
 	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("📨 Запрос на %d баллов отправлен на подтверждение тренеру.", amount)))
+	h.notifyCoaches(ctx, chatID, teamID, id)
 }
 
-func (h *TelegramHandler) handlePending(chatID int64, user *domain.User, text string) {
-	if user == nil || user.Role != domain.RoleCoach {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Команда доступна только тренерам."))
-		return
-	}
-
+func (h *TelegramHandler) handlePending(ctx context.Context, chatID int64, user *domain.User, text string) {
 	args := strings.Fields(text)
-	var teamID *int = nil
+	var (
+		requests []repo.PendingRequest
+		err      error
+	)
 	if len(args) == 2 {
-		id, err := strconv.Atoi(args[1])
-		if err != nil || id <= 0 {
+		id, convErr := strconv.Atoi(args[1])
+		if convErr != nil || id <= 0 {
 			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный ID команды."))
 			return
 		}
-		teamID = &id
+		if !h.requireTeam(ctx, chatID, user, id) {
+			return
+		}
+		requests, err = h.Repo.GetPendingRequestsByTeam(ctx, &id)
+	} else if user.Role == domain.RoleAdmin {
+		requests, err = h.Repo.GetPendingRequests(ctx)
+	} else {
+		requests, err = h.Repo.GetPendingRequestsByCoach(ctx, user.ID)
 	}
-
-	requests, err := h.Repo.GetPendingRequestsByTeam(teamID)
 	if err != nil {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка при получении запросов: "+err.Error()))
 		return
@@ -403,81 +417,210 @@ func (h *TelegramHandler) handlePending(chatID int64, user *domain.User, text st
 		return
 	}
 
-	msg := "📋 Ожидающие запросы:\n"
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("📋 Ожидающие запросы: %d", len(requests))))
 	for _, req := range requests {
-		msg += fmt.Sprintf("ID: %d | 👤 %s (@%s) | ➕ %d баллов\n📎 %s\n\n",
-			req.ID, req.Name, req.Username, req.Amount, req.Reason)
+		msg := tgbotapi.NewMessage(chatID, formatPendingRequest(req))
+		msg.ReplyMarkup = pendingKeyboard(req.ID)
+		util.SafeSend(h.Bot, msg)
 	}
-	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
 }
 
-// Approves a pending point request
-func (h *TelegramHandler) handleApprove(chatID int64, text string, user *domain.User) {
-	if user == nil || user.Role != domain.RoleCoach {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Команда доступна только тренерам."))
+// Approves a pending point request, optionally with another amount, or several of them (see bulk.go)
+func (h *TelegramHandler) handleApprove(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.Fields(text)
+	if len(args) < 2 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("approve")))
 		return
 	}
 
-	idStr := strings.TrimSpace(strings.TrimPrefix(text, "/approve "))
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
+	id, err := strconv.Atoi(args[1])
+	if err != nil {
+		ids, rest, ok := h.bulkTargets(ctx, chatID, user, args[1:])
+		if !ok {
+			return
+		}
+		if len(rest) > 0 {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("approve")))
+			return
+		}
+		h.startBulk(ctx, chatID, user, actionApprove, ids, "")
+		return
+	}
+	if id <= 0 {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный ID запроса."))
 		return
 	}
 
-	err = h.Repo.ApproveRequest(id)
+	// /approve <id> <баллы> [комментарий] — подтвердить с другой суммой
+	var amount *int
+	comment := ""
+	if len(args) > 2 {
+		n, err := strconv.Atoi(args[2])
+		if err != nil || n <= 0 {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи количество баллов числом больше нуля.\n\n"+usageMessage("approve")))
+			return
+		}
+		amount = &n
+		comment = strings.Join(args[3:], " ")
+	}
+
+	if !h.requireRequest(ctx, chatID, user, id) {
+		return
+	}
+
+	grant, err := h.Repo.ApproveRequest(ctx, id, user.ID, amount, comment)
 	if err != nil {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось подтвердить запрос: "+err.Error()))
 		return
 	}
 
-	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Запрос #%d подтвержден. Баллы начислены.", id)))
+	h.notifyGrant(ctx, grant, fmt.Sprintf("✅ Твой запрос #%d подтверждён!", id), user.Name)
+	msg := fmt.Sprintf("✅ Запрос #%d подтвержден. Баллы начислены.", id)
+	if grant.Requested != nil && *grant.Requested != grant.Amount {
+		msg = fmt.Sprintf("✅ Запрос #%d подтвержден: начислено %d баллов вместо %d.", id, grant.Amount, *grant.Requested)
+	}
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
 }
 
 // Gives points directly to an athlete
-func (h *TelegramHandler) handleGive(chatID int64, text string, user *domain.User) {
-	if user == nil || user.Role != domain.RoleCoach {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Только тренеры могут использовать эту команду."))
-		return
-	}
-
+func (h *TelegramHandler) handleGive(ctx context.Context, chatID int64, text string, user *domain.User) {
 	args := strings.SplitN(strings.TrimPrefix(text, "/give "), " ", 3)
 	if len(args) < 3 {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Формат: /give <баллы> @имя <причина>"))
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("give")))
 		return
 	}
 
-	amount, err := strconv.Atoi(args[0])
+	// старый порядок аргументов: /give <баллы> @username <причина>
+	if strings.HasPrefix(args[1], "@") {
+		args[0], args[1] = args[1], args[0]
+	}
+
+	amount, err := strconv.Atoi(args[1])
 	if err != nil || amount <= 0 {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректное количество баллов."))
 		return
 	}
 
-	username := strings.TrimPrefix(args[1], "@")
-	reason := strings.TrimSpace(args[2])
+	username := strings.TrimPrefix(args[0], "@")
+	reason, teamArg, ok := cutTeamArg(args[2])
+	if !ok || reason == "" {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("give")))
+		return
+	}
+
+	var teamID *int
+	athlete, err := h.Repo.GetUserByUsername(ctx, username)
+	if err == nil && athlete != nil {
+		if !h.requireAthlete(ctx, chatID, user, athlete.ID) {
+			return
+		}
+		if teamID, ok = h.pointTeam(ctx, chatID, athlete, user, teamArg); !ok {
+			return
+		}
+	}
 
-	err = h.Repo.GivePoints(username, amount, reason)
+	grant, err := h.Repo.GivePoints(ctx, user.ID, username, amount, reason, teamID)
 	if err != nil {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка: "+err.Error()))
 		return
 	}
 
+	h.notifyGrant(ctx, grant, "🎁 Тренер начислил тебе баллы!", user.Name)
+
 	msg := fmt.Sprintf("✅ %d баллов начислены пользователю @%s.\n📎 %s", amount, username, reason)
 	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
 }
 
-// Updated handler for listing athletes with optional team ID
-func (h *TelegramHandler) handleAthletes(chatID int64, user *domain.User, text string) {
-	if user == nil || user.Role != domain.RoleCoach {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Команда доступна только тренерам."))
+// handleFine deducts points from an athlete as a penalty
+func (h *TelegramHandler) handleFine(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.SplitN(text, " ", 4)
+	if len(args) < 4 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("fine")))
+		return
+	}
+
+	amount, err := strconv.Atoi(args[2])
+	if err != nil || amount <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи, сколько баллов списать, числом больше нуля."))
+		return
+	}
+
+	reason, teamArg, ok := cutTeamArg(args[3])
+	if !ok || reason == "" {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("fine")))
+		return
+	}
+
+	username := strings.TrimPrefix(args[1], "@")
+	athlete, err := h.Repo.GetUserByUsername(ctx, username)
+	if err != nil || athlete == nil || athlete.Role != domain.RoleAthlete {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Спортсмен с таким username не найден."))
+		return
+	}
+	if !h.requireAthlete(ctx, chatID, user, athlete.ID) {
+		return
+	}
+	teamID, ok := h.pointTeam(ctx, chatID, athlete, user, teamArg)
+	if !ok {
+		return
+	}
+
+	grant, err := h.Repo.FinePoints(ctx, user.ID, athlete.ID, amount, reason, teamID)
+	if errors.Is(err, repo.ErrNegativeScore) {
+		msg := fmt.Sprintf("❗ Нельзя списать %d баллов у @%s: %s.", amount, username, err.Error())
+		if teamID != nil {
+			msg += fmt.Sprintf("\nРазрешить уход в минус: /allow_negative %d on", *teamID)
+		}
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+		return
+	}
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка: "+err.Error()))
 		return
 	}
 
+	h.notifyGrant(ctx, grant, "💸 Тренер списал у тебя баллы (штраф).", user.Name)
+
+	msg := fmt.Sprintf("💸 У @%s списано %d баллов.\n📎 %s", username, amount, reason)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+}
+
+// handleAllowNegative sets whether fines may take athletes of the team below zero
+func (h *TelegramHandler) handleAllowNegative(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.Fields(text)
+	if len(args) != 3 || (args[2] != "on" && args[2] != "off") {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("allow_negative")))
+		return
+	}
+
+	teamID, err := strconv.Atoi(args[1])
+	if err != nil || teamID <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный team_id."))
+		return
+	}
+	if !h.requireTeam(ctx, chatID, user, teamID) {
+		return
+	}
+
+	allow := args[2] == "on"
+	if err := h.Repo.SetTeamAllowNegative(ctx, teamID, allow); err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+
+	msg := fmt.Sprintf("✅ В команде #%d штрафы больше не уводят счёт ниже нуля.", teamID)
+	if allow {
+		msg = fmt.Sprintf("✅ В команде #%d штрафы могут уводить счёт в минус.", teamID)
+	}
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+}
+
+// Updated handler for listing athletes with optional team ID
+func (h *TelegramHandler) handleAthletes(ctx context.Context, chatID int64, user *domain.User, text string) {
 	args := strings.Fields(text)
 	var (
-		teamID *int = nil
 		athletes []domain.AthleteShort
-		err error
+		err      error
 	)
 
 	if len(args) == 2 {
@@ -486,10 +629,15 @@ func (h *TelegramHandler) handleAthletes(chatID int64, user *domain.User, text s
 			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный ID команды."))
 			return
 		}
-		teamID = &id
+		if !h.requireTeam(ctx, chatID, user, id) {
+			return
+		}
+		athletes, err = h.Repo.ListAthletesByTeam(ctx, &id)
+	} else if user.Role == domain.RoleAdmin {
+		athletes, err = h.Repo.ListAthletes(ctx)
+	} else {
+		athletes, err = h.Repo.ListAthletesByCoach(ctx, user.ID)
 	}
-
-	athletes, err = h.Repo.ListAthletesByTeam(teamID)
 	if err != nil {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось получить список: "+err.Error()))
 		return
@@ -508,38 +656,55 @@ func (h *TelegramHandler) handleAthletes(chatID int64, user *domain.User, text s
 	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
 }
 
-func (h *TelegramHandler) handleReject(chatID int64, text string, user *domain.User) {
-	if user == nil || user.Role != domain.RoleCoach {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Команда доступна только тренерам."))
+func (h *TelegramHandler) handleReject(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.SplitN(text, " ", 3)
+	if len(args) < 2 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("reject")))
 		return
 	}
 
-	idStr := strings.TrimSpace(strings.TrimPrefix(text, "/reject "))
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
+	id, err := strconv.Atoi(args[1])
+	if err != nil {
+		ids, rest, ok := h.bulkTargets(ctx, chatID, user, strings.Fields(text)[1:])
+		if !ok {
+			return
+		}
+		if len(rest) == 0 {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи причину отказа: спортсмены её увидят."))
+			return
+		}
+		h.startBulk(ctx, chatID, user, actionReject, ids, strings.Join(rest, " "))
+		return
+	}
+	if id <= 0 {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный ID запроса."))
 		return
 	}
 
-	fromID, err := h.Repo.RejectRequest(id)
+	if len(args) < 3 || strings.TrimSpace(args[2]) == "" {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи причину отказа: спортсмен её увидит."))
+		return
+	}
+	reason := strings.TrimSpace(args[2])
+
+	if !h.requireRequest(ctx, chatID, user, id) {
+		return
+	}
+
+	fromID, err := h.Repo.RejectRequest(ctx, id, user.ID, reason)
 	if err != nil {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось отклонить запрос: "+err.Error()))
 		return
 	}
 
 	// Уведомление спортсмену
-	util.SafeSend(h.Bot, tgbotapi.NewMessage(fromID, fmt.Sprintf("🚫 Ваш запрос #%d на баллы был отклонён тренером.", id)))
+	h.notifyRejection(fromID, id, user.Name, reason)
 
 	// Подтверждение тренеру
-	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("🚫 Запрос #%d отклонён и удалён.", id)))
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("🚫 Запрос #%d отклонён.", id)))
 }
 
-func (h *TelegramHandler) handleHistory(chatID int64, text string, user *domain.User) {
-	if user == nil {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Пользователь не найден."))
-		return
-	}
-
+func (h *TelegramHandler) handleHistory(ctx context.Context, chatID int64, text string, user *domain.User) {
 	args := strings.Fields(text)
 	var targetID int64
 	var targetName string
@@ -552,110 +717,182 @@ func (h *TelegramHandler) handleHistory(chatID int64, text string, user *domain.
 		}
 		targetID = chatID
 		targetUser = user
-	} else if len(args) == 2 && user.Role == domain.RoleCoach {
+	} else if len(args) == 2 && user.Role.IsStaff() {
 		username := strings.TrimPrefix(args[1], "@")
 		var err error
-		targetUser, err = h.Repo.GetUserByUsername(username)
+		targetUser, err = h.Repo.GetUserByUsername(ctx, username)
 		if err != nil || targetUser == nil || targetUser.Role != domain.RoleAthlete {
 			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Спортсмен с таким username не найден."))
 			return
 		}
+		if !h.requireAthlete(ctx, chatID, user, targetUser.ID) {
+			return
+		}
 		targetID = targetUser.ID
 		targetName = targetUser.Username
 	} else {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Формат: /history или /history @username (для тренера)"))
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("history")))
 		return
 	}
 
 	// Получаем историю начислений
-	history, err := h.Repo.GetUserHistory(targetID)
+	history, err := h.Repo.GetUserHistory(ctx, targetID)
 	if err != nil {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка при получении истории: "+err.Error()))
 		return
 	}
 
-	// Получаем команду
-	teamName := ""
-	if targetUser != nil {
-		teamName, err = h.Repo.GetUserTeamName(targetUser.ID)
-		if err != nil {
-			teamName = ""
-		}
-	}
+	// Получаем команды: текущие и прошлые
+	memberships, _ := h.Repo.ListMemberships(ctx, targetID)
 
 	if len(history) == 0 {
 		msg := "📭 Нет начислений."
-		if user.Role == domain.RoleCoach && targetID != chatID {
+		if user.Role.IsStaff() && targetID != chatID {
 			msg = fmt.Sprintf("📭 У пользователя @%s пока нет начислений.", targetName)
 		}
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
 		return
 	}
 
-	msg := "📜 История начислений"
-	if teamName != "" {
-		msg += fmt.Sprintf(" (команда: %s)", teamName)
+	msg := "📜 История начислений:\n"
+	if len(memberships) > 0 {
+		msg += "👥 " + formatMemberships(memberships) + "\n"
 	}
-	msg += ":\n\n"
+	msg += "\n"
 
 	for _, entry := range history {
-		msg += fmt.Sprintf("• ➕ %d баллов — %s\n", entry.Amount, entry.Reason)
+		msg += formatHistoryEntry(entry) + "\n"
 	}
 
 	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
 }
 
+var statusIcons = map[domain.PointStatus]string{
+	domain.PointPending:   "⏳",
+	domain.PointApproved:  "✅",
+	domain.PointRejected:  "🚫",
+	domain.PointCancelled: "↩️",
+	domain.PointExpired:   "⌛",
+}
+
+var statusNames = map[domain.PointStatus]string{
+	domain.PointPending:   "ждёт подтверждения",
+	domain.PointApproved:  "начислено",
+	domain.PointRejected:  "отклонено",
+	domain.PointCancelled: "отменено",
+	domain.PointExpired:   "истёк срок",
+}
 
-func (h *TelegramHandler) handleMyScore(chatID int64, user *domain.User) {
-	if user == nil || user.Role != domain.RoleAthlete {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Команда доступна только спортсменам."))
+// formatHistoryEntry renders one line of /history: what, when and who decided
+func formatHistoryEntry(e domain.PointRecord) string {
+	icon, status := statusIcons[e.Status], statusNames[e.Status]
+	amount := fmt.Sprintf("%d баллов", e.Amount)
+	if e.Requested != nil && *e.Requested != e.Amount {
+		amount += fmt.Sprintf(" (запрошено %d)", *e.Requested)
+	}
+	if e.IsFine() {
+		icon, status = "💸", "штраф"
+	}
+	if e.CorrectsID != nil {
+		icon, status = "🔧", fmt.Sprintf("исправление #%d", *e.CorrectsID)
+	}
+	line := fmt.Sprintf("• %s #%d: %s — %s", icon, e.ID, amount, e.Reason)
+
+	line += fmt.Sprintf("\n   %s, %s", status, e.CreatedAt.Format("02.01.2006"))
+	if e.DecidedAt != nil && e.Status != domain.PointPending {
+		line += " → " + e.DecidedAt.Format("02.01.2006")
+	}
+	if e.DecidedBy != nil && e.Status != domain.PointCancelled {
+		line += ", " + *e.DecidedBy
+	}
+	if e.Team != nil {
+		line += ", 👥 " + *e.Team
+	}
+	if e.Comment != nil {
+		line += "\n   💬 " + *e.Comment
+	}
+	return line
+}
+
+// formatMemberships lists teams with their dates: "A с 01.02.2026; B 01.01.2026–01.02.2026"
+func formatMemberships(memberships []domain.Membership) string {
+	parts := make([]string, len(memberships))
+	for i, m := range memberships {
+		if m.LeftAt == nil {
+			parts[i] = fmt.Sprintf("%s с %s", m.TeamName, m.JoinedAt.Format("02.01.2006"))
+		} else {
+			parts[i] = fmt.Sprintf("%s %s–%s", m.TeamName, m.JoinedAt.Format("02.01.2006"), m.LeftAt.Format("02.01.2006"))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (h *TelegramHandler) handleCancel(ctx context.Context, chatID int64, text string) {
+	args := strings.Fields(text)
+	if len(args) != 2 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("cancel")))
 		return
 	}
 
-	score, err := h.Repo.GetUserScore(chatID)
-	if err != nil {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка при получении счёта: "+err.Error()))
+	id, err := strconv.Atoi(args[1])
+	if err != nil || id <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный ID запроса."))
 		return
 	}
 
-	teamID, err := h.Repo.GetUserTeamID(chatID)
-	if err != nil || teamID == 0 {
-		msg := fmt.Sprintf("🏅 Твой текущий счёт: %d баллов\n\n📌 Ты не прикреплён ни к одной команде.", score)
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+	if err := h.Repo.CancelRequest(ctx, id, chatID); err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось отменить запрос: "+err.Error()))
 		return
 	}
 
-	// Получаем рейтинг по команде
-	ranking, err := h.Repo.GetRankingByTeam(teamID)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("↩️ Запрос #%d отменён.", id)))
+}
+
+func (h *TelegramHandler) handleMyScore(ctx context.Context, chatID int64, user *domain.User) {
+	score, err := h.Repo.GetUserScore(ctx, chatID)
 	if err != nil {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка при получении рейтинга команды: "+err.Error()))
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка при получении счёта: "+err.Error()))
 		return
 	}
 
-	place := -1
-	for i, entry := range ranking {
-		if entry.UserID == chatID {
-			place = i + 1
-			break
-		}
+	teams, err := h.Repo.ListUserTeams(ctx, chatID)
+	if err != nil || len(teams) == 0 {
+		msg := fmt.Sprintf("🏅 Твой текущий счёт: %d баллов\n\n📌 Ты не прикреплён ни к одной команде.", score)
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+		return
 	}
 
 	msg := fmt.Sprintf("🏅 Твой текущий счёт: %d баллов", score)
-	if place > 0 {
-		msg += fmt.Sprintf("\n📊 Ты на %d месте в своей команде.", place)
+	for _, team := range teams {
+		// в рейтинге команды только баллы, заработанные за неё
+		ranking, err := h.Repo.GetRankingByTeam(ctx, team.ID)
+		if err != nil {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка при получении рейтинга команды: "+err.Error()))
+			return
+		}
+		for i, entry := range ranking {
+			if entry.UserID == chatID {
+				msg += fmt.Sprintf("\n📊 %s: %d место из %d, %d баллов за команду", team.Name, i+1, len(ranking), entry.Score)
+				break
+			}
+		}
 	}
 
 	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
 }
 
-
-func (h *TelegramHandler) handleTeams(chatID int64, user *domain.User) {
-	if user == nil || user.Role != domain.RoleCoach {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Команда доступна только тренерам."))
+func (h *TelegramHandler) handleTeams(ctx context.Context, chatID int64, user *domain.User, text string) {
+	args := strings.Fields(text)
+	if len(args) > 1 {
+		if args[1] != "archived" {
+			util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("teams")))
+			return
+		}
+		h.handleArchivedTeams(ctx, chatID)
 		return
 	}
 
-	teams, err := h.Repo.ListTeams()
+	teams, err := h.Repo.ListTeams(ctx)
 	if err != nil {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось получить список команд: "+err.Error()))
 		return
@@ -666,131 +903,155 @@ func (h *TelegramHandler) handleTeams(chatID int64, user *domain.User) {
 		return
 	}
 
-	msg := "📦 Список команд:\n\n"
-	for _, t := range teams {
-		msg += fmt.Sprintf("• %s (ID: %d)\n", t.Name, t.ID)
-	}
-	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
-}
-
-func (h *TelegramHandler) handleInviteLink(chatID int64, text string, user *domain.User) {
-	if user == nil || user.Role != domain.RoleCoach {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Команда доступна только тренерам."))
+	own, err := h.Repo.ListCoachTeams(ctx, user.ID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось получить список команд: "+err.Error()))
 		return
 	}
-
-	args := strings.Fields(text)
-	if len(args) != 2 {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Формат: /invite_link <team_id>"))
-		return
+	mine := make(map[int]bool, len(own))
+	for _, t := range own {
+		mine[t.ID] = true
 	}
 
-	teamID, err := strconv.Atoi(args[1])
-	if err != nil || teamID <= 0 {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный числовой team_id."))
-		return
+	msg := "📦 Список команд (⭐ — твои):\n\n"
+	for _, t := range teams {
+		mark := "•"
+		if mine[t.ID] {
+			mark = "⭐"
+		}
+		msg += fmt.Sprintf("%s %s (ID: %d)\n", mark, t.Name, t.ID)
 	}
-
-	link := fmt.Sprintf("https://t.me/%s?start=team_%d", h.Bot.Self.UserName, teamID)
-
-	msg := fmt.Sprintf("🔗 Ссылка для приглашения в команду #%d:\n%s", teamID, link)
-
-	button := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("Присоединиться", link),
-		),
-	)
-
-	message := tgbotapi.NewMessage(chatID, msg)
-	message.ReplyMarkup = button
-
-	util.SafeSend(h.Bot, message)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
 }
 
-
-func (h *TelegramHandler) handleCreateTeam(chatID int64, text string, user *domain.User) {
-	if user == nil || user.Role != domain.RoleCoach {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Только тренеры могут создавать команды."))
-		return
-	}
-
+func (h *TelegramHandler) handleCreateTeam(ctx context.Context, chatID int64, text string, user *domain.User) {
 	args := strings.Fields(text)
 	if len(args) < 2 {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Формат: /create_team <название>"))
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("create_team")))
 		return
 	}
 
 	name := strings.Join(args[1:], " ")
-	err := h.Repo.CreateTeam(name)
+	err := h.Repo.CreateTeam(ctx, name, user.ID)
 	if err != nil {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось создать команду: "+err.Error()))
 		return
 	}
 
-	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Команда \"%s\" создана.", name)))
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Команда \"%s\" создана. Ты её тренер.", name)))
 }
 
-func (h *TelegramHandler) handleDeleteTeam(chatID int64, text string, user *domain.User) {
-	if user == nil || user.Role != domain.RoleCoach {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Только тренеры могут удалять команды."))
+// handleAssignTeam adds an athlete to one more team; their other teams are kept
+func (h *TelegramHandler) handleAssignTeam(ctx context.Context, chatID int64, text string, user *domain.User) {
+	athlete, teamID, ok := h.parseTeamMemberArgs(ctx, chatID, text, "assign_team")
+	// добавлять можно только своих спортсменов и только в свои команды
+	if !ok || !h.requireTeam(ctx, chatID, user, teamID) || !h.requireActiveTeam(ctx, chatID, teamID) ||
+		!h.requireAthlete(ctx, chatID, user, athlete.ID) {
 		return
 	}
 
-	args := strings.Fields(text)
-	if len(args) != 2 {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Формат: /delete_team <id команды>"))
+	err := h.Repo.AssignUserToTeam(ctx, athlete.ID, teamID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось назначить команду: "+err.Error()))
 		return
 	}
 
-	teamID, err := strconv.Atoi(args[1])
-	if err != nil || teamID <= 0 {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный team_id."))
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID,
+		fmt.Sprintf("✅ Пользователь @%s добавлен в команду #%d.", athlete.Username, teamID)))
+}
+
+// handleUnassignTeam ends an athlete's membership; points earned for the team stay in its history
+func (h *TelegramHandler) handleUnassignTeam(ctx context.Context, chatID int64, text string, user *domain.User) {
+	athlete, teamID, ok := h.parseTeamMemberArgs(ctx, chatID, text, "unassign_team")
+	if !ok || !h.requireTeam(ctx, chatID, user, teamID) {
 		return
 	}
-	err = h.Repo.DeleteTeam(teamID)
-	if err != nil {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось удалить команду: "+err.Error()))
+
+	if err := h.Repo.RemoveUserFromTeam(ctx, athlete.ID, teamID); err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
 		return
 	}
 
-	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("🗑 Команда удалена.")))
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID,
+		fmt.Sprintf("✅ Пользователь @%s выведен из команды #%d.", athlete.Username, teamID)))
 }
 
-func (h *TelegramHandler) handleAssignTeam(chatID int64, text string, user *domain.User) {
-	if user == nil || user.Role != domain.RoleCoach {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Только тренеры могут назначать команду."))
-		return
-	}
-
+// parseTeamMemberArgs parses "@username <team_id>" of an athlete
+func (h *TelegramHandler) parseTeamMemberArgs(ctx context.Context, chatID int64, text, command string) (*domain.User, int, bool) {
 	args := strings.Fields(text)
-	if len(args) <= 3 {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Формат: /assign_team @username <team_id>"))
-		return
+	if len(args) != 3 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage(command)))
+		return nil, 0, false
 	}
 
-	username := strings.TrimPrefix(args[1], "@")
 	teamID, err := strconv.Atoi(args[2])
 	if err != nil || teamID <= 0 {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный team_id."))
-		return
+		return nil, 0, false
 	}
 
-	athlete, err := h.Repo.GetUserByUsername(username)
+	athlete, err := h.Repo.GetUserByUsername(ctx, strings.TrimPrefix(args[1], "@"))
 	if err != nil || athlete == nil {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Пользователь не найден."))
-		return
+		return nil, 0, false
 	}
 	if athlete.Role != domain.RoleAthlete {
 		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Только спортсменов можно добавлять в команды."))
+		return nil, 0, false
+	}
+	return athlete, teamID, true
+}
+
+func (h *TelegramHandler) handleAddCoach(ctx context.Context, chatID int64, text string, user *domain.User) {
+	teamID, coach, ok := h.parseTeamCoachArgs(ctx, chatID, text, "add_coach")
+	if !ok || !h.requireTeam(ctx, chatID, user, teamID) {
 		return
 	}
 
-	err = h.Repo.AssignUserToTeam(athlete.ID, teamID)
-	if err != nil {
-		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось назначить команду: "+err.Error()))
+	if err := h.Repo.AddTeamCoach(ctx, teamID, coach.ID); err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
 		return
 	}
 
-	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID,
-		fmt.Sprintf("✅ Пользователь @%s добавлен в команду #%d.", username, teamID)))
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ @%s теперь тренер команды #%d.", coach.Username, teamID)))
+	if coach.ID != user.ID {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(coach.ID,
+			fmt.Sprintf("📦 Теперь ты тренер команды #%d. Добавлено тренером %s.", teamID, user.Name)))
+	}
+}
+
+func (h *TelegramHandler) handleRemoveCoach(ctx context.Context, chatID int64, text string, user *domain.User) {
+	teamID, coach, ok := h.parseTeamCoachArgs(ctx, chatID, text, "remove_coach")
+	if !ok || !h.requireTeam(ctx, chatID, user, teamID) {
+		return
+	}
+
+	if err := h.Repo.RemoveTeamCoach(ctx, teamID, coach.ID); err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("🗑 @%s больше не тренер команды #%d.", coach.Username, teamID)))
+}
+
+// parseTeamCoachArgs parses "<team_id> @username" and looks the coach up
+func (h *TelegramHandler) parseTeamCoachArgs(ctx context.Context, chatID int64, text, command string) (int, *domain.User, bool) {
+	args := strings.Fields(text)
+	if len(args) != 3 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage(command)))
+		return 0, nil, false
+	}
+
+	teamID, err := strconv.Atoi(args[1])
+	if err != nil || teamID <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный team_id."))
+		return 0, nil, false
+	}
+
+	coach, err := h.Repo.GetUserByUsername(ctx, strings.TrimPrefix(args[2], "@"))
+	if err != nil || coach == nil || !coach.Role.IsStaff() {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Тренер с таким username не найден."))
+		return 0, nil, false
+	}
+	return teamID, coach, true
 }
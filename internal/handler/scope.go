@@ -0,0 +1,180 @@
+// internal/handler/scope.go
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"surf_bot/internal/domain"
+	repo "surf_bot/internal/repository"
+	"surf_bot/internal/util"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Coaches act only within the teams they run. An athlete without a team is
+// in scope of every coach so that somebody can assign them. A pending request
+// recorded for a team belongs to that team's coaches. Admins are not limited.
+
+// requireTeam reports whether the coach runs the team and tells them otherwise
+func (h *TelegramHandler) requireTeam(ctx context.Context, chatID int64, coach *domain.User, teamID int) bool {
+	if coach.Role == domain.RoleAdmin {
+		return true
+	}
+	ok, err := h.Repo.CoachesTeam(ctx, coach.ID, teamID)
+	if err != nil {
+		log.Printf("⚠️  failed to check team #%d of coach %d: %v", teamID, coach.ID, err)
+	}
+	if !ok {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, teamDeniedMessage(teamID)))
+	}
+	return ok
+}
+
+// requireMember reports whether the athlete is in the team and tells them otherwise
+func (h *TelegramHandler) requireMember(ctx context.Context, chatID int64, athleteID int64, teamID int) bool {
+	teams, err := h.Repo.ListUserTeams(ctx, athleteID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return false
+	}
+	for _, t := range teams {
+		if t.ID == teamID {
+			return true
+		}
+	}
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("❗ Ты не состоишь в команде #%d.", teamID)))
+	return false
+}
+
+// requireAthlete reports whether the athlete is in the coach's scope and tells them otherwise
+func (h *TelegramHandler) requireAthlete(ctx context.Context, chatID int64, coach *domain.User, athleteID int64) bool {
+	ok := h.coachesAthlete(ctx, coach, athleteID)
+	if !ok {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, athleteDeniedMessage))
+	}
+	return ok
+}
+
+// requireRequest checks that a pending request is in the coach's scope
+func (h *TelegramHandler) requireRequest(ctx context.Context, chatID int64, coach *domain.User, requestID int) bool {
+	req, err := h.Repo.GetPendingRequest(ctx, requestID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Запрос #%d не найден или уже обработан.", requestID)))
+		return false
+	}
+	ok := h.coachesRequest(ctx, coach, req)
+	if !ok {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, athleteDeniedMessage))
+	}
+	return ok
+}
+
+// coachesRequest reports whether the coach runs the request's team,
+// or coaches its author when the request has no team
+func (h *TelegramHandler) coachesRequest(ctx context.Context, coach *domain.User, req *repo.PendingRequest) bool {
+	return h.coachesEntry(ctx, coach, req.UserID, req.TeamID)
+}
+
+// coachesEntry applies the same rule to any point entry of the athlete
+func (h *TelegramHandler) coachesEntry(ctx context.Context, coach *domain.User, athleteID int64, teamID *int) bool {
+	if teamID == nil || coach.Role == domain.RoleAdmin {
+		return h.coachesAthlete(ctx, coach, athleteID)
+	}
+	ok, err := h.Repo.CoachesTeam(ctx, coach.ID, *teamID)
+	if err != nil {
+		log.Printf("⚠️  failed to check team #%d of coach %d: %v", *teamID, coach.ID, err)
+	}
+	return ok
+}
+
+// pointTeam picks the team a new point entry is recorded for: the one given
+// with team:<id> or the athlete's only team. When a coach gives points, only
+// the teams they run are considered. Returns ok=false after telling the user
+// what is wrong; a nil team means the athlete is in no team.
+func (h *TelegramHandler) pointTeam(ctx context.Context, chatID int64, athlete *domain.User, by *domain.User, teamID int) (*int, bool) {
+	teams, err := h.Repo.ListUserTeams(ctx, athlete.ID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return nil, false
+	}
+
+	notMember, manyTeams := "@"+athlete.Username+" не состоит", "@"+athlete.Username+" состоит"
+	if by.ID == athlete.ID {
+		notMember, manyTeams = "Ты не состоишь", "Ты состоишь"
+	}
+
+	if teamID > 0 {
+		for _, t := range teams {
+			if t.ID == teamID {
+				return &teamID, true
+			}
+		}
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("❗ %s в команде #%d.", notMember, teamID)))
+		return nil, false
+	}
+
+	candidates := teams
+	if by.Role == domain.RoleCoach {
+		candidates = nil
+		for _, t := range teams {
+			if ok, _ := h.Repo.CoachesTeam(ctx, by.ID, t.ID); ok {
+				candidates = append(candidates, t)
+			}
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, true
+	case 1:
+		return &candidates[0].ID, true
+	}
+
+	names := make([]string, len(candidates))
+	for i, t := range candidates {
+		names[i] = fmt.Sprintf("%s (team:%d)", t.Name, t.ID)
+	}
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"❗ %s в нескольких командах: %s.\nДобавь к команде team:<id>, чтобы указать, за какую команду баллы.",
+		manyTeams, strings.Join(names, ", "))))
+	return nil, false
+}
+
+// cutTeamArg removes a "team:<id>" word from args and returns the rest and the ID (0 if absent)
+func cutTeamArg(args string) (rest string, teamID int, ok bool) {
+	words := strings.Fields(args)
+	kept := words[:0]
+	for _, w := range words {
+		if idStr, found := strings.CutPrefix(w, "team:"); found {
+			id, err := strconv.Atoi(idStr)
+			if err != nil || id <= 0 {
+				return "", 0, false
+			}
+			teamID = id
+			continue
+		}
+		kept = append(kept, w)
+	}
+	return strings.Join(kept, " "), teamID, true
+}
+
+func (h *TelegramHandler) coachesAthlete(ctx context.Context, coach *domain.User, athleteID int64) bool {
+	if coach.Role == domain.RoleAdmin {
+		return true
+	}
+	ok, err := h.Repo.CoachesAthlete(ctx, coach.ID, athleteID)
+	if err != nil {
+		log.Printf("⚠️  failed to check athlete %d of coach %d: %v", athleteID, coach.ID, err)
+	}
+	return ok
+}
+
+func teamDeniedMessage(teamID int) string {
+	return fmt.Sprintf("🚫 Ты не тренер команды #%d. Попроси её тренера добавить тебя: /add_coach %d @username", teamID, teamID)
+}
+
+const athleteDeniedMessage = "🚫 Этот спортсмен не из твоих команд."
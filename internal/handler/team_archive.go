@@ -0,0 +1,255 @@
+// internal/handler/team_archive.go
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"surf_bot/internal/domain"
+	repo "surf_bot/internal/repository"
+	"surf_bot/internal/util"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Actions of the /delete_team confirmation buttons: "team:<action>:<id>".
+// Archiving is the default; a team is deleted for good only if it never had
+// athletes or points.
+const (
+	teamArchive = "archive"
+	teamDelete  = "delete"
+	teamCancel  = "cancel"
+)
+
+func teamArchivedMessage(team *domain.Team) string {
+	return fmt.Sprintf("🗄 Команда '%s' в архиве. Администратор может вернуть её командой /restore_team %d.", team.Name, team.ID)
+}
+
+// requireActiveTeam reports whether the team exists and is not archived, and tells the user otherwise
+func (h *TelegramHandler) requireActiveTeam(ctx context.Context, chatID int64, teamID int) bool {
+	team, err := h.Repo.GetTeamByID(ctx, teamID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Команда #%d не найдена.", teamID)))
+		return false
+	}
+	if team.ArchivedAt != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, teamArchivedMessage(team)))
+		return false
+	}
+	return true
+}
+
+func teamDeleteKeyboard(id int, impact *domain.TeamImpact) tgbotapi.InlineKeyboardMarkup {
+	row := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🗄 Архивировать", fmt.Sprintf("%s:%s:%d", callbackTeam, teamArchive, id)),
+	)
+	if !impact.HasHistory() {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить навсегда", fmt.Sprintf("%s:%s:%d", callbackTeam, teamDelete, id)))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✖️ Отмена", fmt.Sprintf("%s:%s:%d", callbackTeam, teamCancel, id)),
+	))
+}
+
+func formatTeamImpact(team *domain.Team, impact *domain.TeamImpact) string {
+	text := fmt.Sprintf("⚠️ Команда '%s' (ID: %d)\n\n", team.Name, team.ID) +
+		fmt.Sprintf("👥 Спортсменов сейчас: %d\n", impact.Members) +
+		fmt.Sprintf("🚪 Бывших спортсменов: %d\n", impact.FormerMembers) +
+		fmt.Sprintf("📊 Записей о баллах: %d\n", impact.PointEntries) +
+		fmt.Sprintf("⏳ Запросов на баллы в ожидании: %d\n", impact.PendingRequests) +
+		fmt.Sprintf("🙋 Заявок на вступление: %d\n", impact.PendingJoins) +
+		fmt.Sprintf("🔗 Действующих приглашений: %d\n\n", impact.ActiveInvites)
+
+	text += "🗄 Архив: команда пропадёт из списков, приглашения будут отозваны, заявки на вступление " +
+		"и запросы на баллы отклонены. Рейтинг и история сохранятся, команду можно вернуть через /restore_team."
+	if impact.HasHistory() {
+		text += "\n\nУ команды есть история, поэтому удалить её насовсем нельзя."
+	} else {
+		text += "\n\n🗑 Удаление: команда исчезнет без возможности восстановления, заявки на вступление будут закрыты."
+	}
+	if impact.Members > 0 {
+		text += fmt.Sprintf("\n\n💡 Чтобы сначала перевести спортсменов в другую команду: /move_athletes %d <team_id>", team.ID)
+	}
+	return text
+}
+
+// handleDeleteTeam shows what the team has and asks to archive or delete it
+func (h *TelegramHandler) handleDeleteTeam(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.Fields(text)
+	if len(args) != 2 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("delete_team")))
+		return
+	}
+
+	teamID, err := strconv.Atoi(args[1])
+	if err != nil || teamID <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный team_id."))
+		return
+	}
+	if !h.requireTeam(ctx, chatID, user, teamID) {
+		return
+	}
+
+	team, err := h.Repo.GetTeamByID(ctx, teamID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Команда не найдена: "+err.Error()))
+		return
+	}
+	if team.ArchivedAt != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, teamArchivedMessage(team)))
+		return
+	}
+	impact, err := h.Repo.GetTeamImpact(ctx, teamID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, formatTeamImpact(team, impact))
+	msg.ReplyMarkup = teamDeleteKeyboard(teamID, impact)
+	util.SafeSend(h.Bot, msg)
+}
+
+// handleTeamCallback archives or deletes a team after the confirmation of /delete_team
+func (h *TelegramHandler) handleTeamCallback(ctx context.Context, cb *tgbotapi.CallbackQuery, user *domain.User, action string, id int) {
+	chatID := cb.Message.Chat.ID
+	messageID := cb.Message.MessageID
+
+	if !hasRole(user, adminRoles...) {
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "🚫 Действие доступно только администраторам."))
+		return
+	}
+
+	switch action {
+	case teamArchive:
+		closure, err := h.Repo.ArchiveTeam(ctx, id, user.ID)
+		if err != nil {
+			util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "❌ Не удалось архивировать команду."))
+			h.closeRequestMessage(chatID, messageID, cb.Message.Text, "❌ "+err.Error())
+			return
+		}
+		log.Printf("🗄 Team #%d archived by %d, %d join requests denied, %d point requests rejected",
+			id, user.ID, len(closure.DeniedJoins), len(closure.RejectedRequests))
+		h.notifyTeamClosure(closure, user.Name)
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "🗄 Команда в архиве."))
+		h.closeRequestMessage(chatID, messageID, cb.Message.Text,
+			fmt.Sprintf("🗄 Команда отправлена в архив (%s). Вернуть: /restore_team %d", user.Name, id))
+
+	case teamDelete:
+		closure, err := h.Repo.DeleteTeam(ctx, id)
+		if errors.Is(err, repo.ErrTeamHasHistory) {
+			util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "⚠️ Команду можно только архивировать."))
+			h.closeRequestMessage(chatID, messageID, cb.Message.Text, "⚠️ "+err.Error()+".")
+			return
+		}
+		if err != nil {
+			util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "❌ Не удалось удалить команду."))
+			h.closeRequestMessage(chatID, messageID, cb.Message.Text, "❌ "+err.Error())
+			return
+		}
+		log.Printf("🗑 Team #%d deleted by %d, %d join requests dropped", id, user.ID, len(closure.DeniedJoins))
+		h.notifyTeamClosure(closure, user.Name)
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "🗑 Команда удалена."))
+		h.closeRequestMessage(chatID, messageID, cb.Message.Text, fmt.Sprintf("🗑 Команда удалена (%s).", user.Name))
+
+	case teamCancel:
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, ""))
+		h.closeRequestMessage(chatID, messageID, cb.Message.Text, "✖️ Отменено, команда не изменилась.")
+
+	default:
+		util.SafeAnswer(h.Bot, tgbotapi.NewCallback(cb.ID, "❓ Неизвестное действие."))
+	}
+}
+
+// notifyTeamClosure tells applicants and athletes whose requests were closed together with the team
+func (h *TelegramHandler) notifyTeamClosure(closure *repo.TeamClosure, adminName string) {
+	for _, req := range closure.DeniedJoins {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(req.UserID, fmt.Sprintf(
+			"🚫 Команда '%s' больше не набирает спортсменов, твоя заявка закрыта.", req.TeamName)))
+	}
+	for _, req := range closure.RejectedRequests {
+		h.notifyRejection(req.UserID, req.ID, adminName, repo.ArchivedRequestComment)
+	}
+}
+
+func (h *TelegramHandler) handleRestoreTeam(ctx context.Context, chatID int64, text string) {
+	args := strings.Fields(text)
+	if len(args) != 2 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("restore_team")))
+		return
+	}
+
+	teamID, err := strconv.Atoi(args[1])
+	if err != nil || teamID <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный team_id."))
+		return
+	}
+	if err := h.Repo.RestoreTeam(ctx, teamID); err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"♻️ Команда #%d восстановлена. Старые приглашения отозваны — создай новое через /invite_link.", teamID)))
+}
+
+// handleMoveAthletes moves all current athletes of one team to another in one step
+func (h *TelegramHandler) handleMoveAthletes(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.Fields(text)
+	if len(args) != 3 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("move_athletes")))
+		return
+	}
+
+	fromID, errFrom := strconv.Atoi(args[1])
+	toID, errTo := strconv.Atoi(args[2])
+	if errFrom != nil || errTo != nil || fromID <= 0 || toID <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректные team_id."))
+		return
+	}
+	if fromID == toID {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Команды должны различаться."))
+		return
+	}
+	if !h.requireTeam(ctx, chatID, user, fromID) || !h.requireTeam(ctx, chatID, user, toID) ||
+		!h.requireActiveTeam(ctx, chatID, toID) {
+		return
+	}
+
+	moved, err := h.Repo.MoveTeamMembers(ctx, fromID, toID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+	if moved == 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("📭 В команде #%d нет спортсменов.", fromID)))
+		return
+	}
+
+	log.Printf("👥 %d athletes moved from team #%d to #%d by %d", moved, fromID, toID, user.ID)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"✅ Спортсменов переведено из команды #%d в #%d: %d. Баллы за прежнюю команду остались в её истории.",
+		fromID, toID, moved)))
+}
+
+func (h *TelegramHandler) handleArchivedTeams(ctx context.Context, chatID int64) {
+	teams, err := h.Repo.ListArchivedTeams(ctx)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось получить список команд: "+err.Error()))
+		return
+	}
+	if len(teams) == 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "📭 В архиве нет команд."))
+		return
+	}
+
+	msg := "🗄 Команды в архиве:\n\n"
+	for _, t := range teams {
+		msg += fmt.Sprintf("• %s (ID: %d) — с %s\n", t.Name, t.ID, t.ArchivedAt.Format("02.01.2006"))
+	}
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, msg))
+}
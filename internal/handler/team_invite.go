@@ -0,0 +1,279 @@
+// internal/handler/team_invite.go
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"surf_bot/internal/domain"
+	repo "surf_bot/internal/repository"
+	"surf_bot/internal/util"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// teamInvitePrefix starts the /start payload of a team invitation link
+const teamInvitePrefix = "join_"
+
+const tokenInvitesOnlyMessage = "🚫 В эту команду можно вступить только по ссылке-приглашению. Попроси её у тренера."
+
+// inviteOptions are the optional limits of /invite_link
+type inviteOptions struct {
+	TTL           time.Duration // 0 — бессрочно
+	MaxUses       int           // 0 — без ограничений
+	NeedsApproval bool
+}
+
+// parseInviteOptions parses "ttl:7d", "uses:20" and "approval"
+func parseInviteOptions(args []string) (inviteOptions, error) {
+	var opts inviteOptions
+	for _, arg := range args {
+		key, value, _ := strings.Cut(strings.ToLower(arg), ":")
+		switch key {
+		case "ttl":
+			d, err := parseTTL(value)
+			if err != nil || d <= 0 {
+				return opts, fmt.Errorf("неверный срок %q, пример: ttl:7d или ttl:12h", value)
+			}
+			opts.TTL = d
+		case "uses":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return opts, fmt.Errorf("неверное число использований %q, пример: uses:20", value)
+			}
+			opts.MaxUses = n
+		case "approval":
+			opts.NeedsApproval = true
+		default:
+			return opts, fmt.Errorf("неизвестный параметр %q", arg)
+		}
+	}
+	return opts, nil
+}
+
+// parseTTL accepts Go durations and whole days ("7d")
+func parseTTL(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func (h *TelegramHandler) handleInviteLink(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.Fields(text)
+	if len(args) < 2 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("invite_link")))
+		return
+	}
+
+	teamID, err := strconv.Atoi(args[1])
+	if err != nil || teamID <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный числовой team_id."))
+		return
+	}
+
+	opts, err := parseInviteOptions(args[2:])
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ "+err.Error()+"\n\n"+usageMessage("invite_link")))
+		return
+	}
+
+	if !h.requireTeam(ctx, chatID, user, teamID) || !h.requireActiveTeam(ctx, chatID, teamID) {
+		return
+	}
+
+	team, err := h.Repo.GetTeamByID(ctx, teamID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Команда не найдена: "+err.Error()))
+		return
+	}
+
+	token, err := util.NewToken()
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Не удалось создать приглашение: "+err.Error()))
+		return
+	}
+
+	invite := &domain.TeamInvite{
+		TeamID:        teamID,
+		TokenHash:     util.HashToken(token),
+		CreatedBy:     &user.ID,
+		NeedsApproval: opts.NeedsApproval,
+	}
+	if opts.TTL > 0 {
+		expiresAt := time.Now().Add(opts.TTL)
+		invite.ExpiresAt = &expiresAt
+	}
+	if opts.MaxUses > 0 {
+		invite.MaxUses = &opts.MaxUses
+	}
+
+	id, err := h.Repo.CreateTeamInvite(ctx, invite)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+	invite.ID = id
+
+	link := fmt.Sprintf("https://t.me/%s?start=%s%s", h.Bot.Username(), teamInvitePrefix, token)
+	msg := fmt.Sprintf("🔗 Приглашение #%d в команду '%s'\n%s\n\n%s\n\n"+
+		"Ссылка показывается один раз. Отозвать: /revoke_invite %d",
+		id, team.Name, inviteLimits(invite), link, id)
+	if !team.TokenInvites {
+		msg += "\n\nℹ️ Старые ссылки вида team_" + strconv.Itoa(teamID) + " для этой команды больше не работают."
+	}
+
+	message := tgbotapi.NewMessage(chatID, msg)
+	message.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL("Присоединиться", link),
+		),
+	)
+	util.SafeSend(h.Bot, message)
+}
+
+func (h *TelegramHandler) handleInvites(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.Fields(text)
+	if len(args) != 2 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("invites")))
+		return
+	}
+
+	teamID, err := strconv.Atoi(args[1])
+	if err != nil || teamID <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный числовой team_id."))
+		return
+	}
+
+	if !h.requireTeam(ctx, chatID, user, teamID) {
+		return
+	}
+
+	invites, err := h.Repo.ListTeamInvites(ctx, teamID)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+	if len(invites) == 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("📭 У команды #%d нет активных приглашений.", teamID)))
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🔗 Активные приглашения команды #%d:\n\n", teamID))
+	for _, inv := range invites {
+		sb.WriteString(fmt.Sprintf("#%d от %s — %s\n", inv.ID, inv.CreatedAt.Format("02.01.2006"), inviteLimits(&inv)))
+	}
+	sb.WriteString("\nОтозвать: /revoke_invite <id>")
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, sb.String()))
+}
+
+func (h *TelegramHandler) handleRevokeInvite(ctx context.Context, chatID int64, text string, user *domain.User) {
+	args := strings.Fields(text)
+	if len(args) != 2 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, usageMessage("revoke_invite")))
+		return
+	}
+
+	id, err := strconv.Atoi(args[1])
+	if err != nil || id <= 0 {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❗ Укажи корректный ID приглашения."))
+		return
+	}
+
+	invite, err := h.Repo.GetTeamInvite(ctx, id)
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Приглашение #%d не найдено.", id)))
+		return
+	}
+	if !h.requireTeam(ctx, chatID, user, invite.TeamID) {
+		return
+	}
+
+	if err := h.Repo.RevokeTeamInvite(ctx, id); err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("🗑 Приглашение #%d отозвано.", id)))
+}
+
+// redeemTeamInvite registers an unknown chat as an athlete of the invite's team,
+// or adds a registered athlete (user != nil) to one more team
+func (h *TelegramHandler) redeemTeamInvite(ctx context.Context, chatID int64, token string, from *tgbotapi.User, user *domain.User) {
+	if ok, wait := h.keyFails.Allow(chatID); !ok {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, tooManyAttemptsMessage(wait)))
+		return
+	}
+
+	applicant := user
+	if applicant == nil {
+		applicant = &domain.User{
+			ID:       chatID,
+			Name:     from.FirstName,
+			Username: from.UserName,
+			Role:     domain.RoleAthlete,
+		}
+	}
+	req, err := h.Repo.RedeemTeamInvite(ctx, util.HashToken(token), applicant)
+	if errors.Is(err, repo.ErrJoinPending) && user != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "⏳ У тебя уже есть заявка в команду на рассмотрении. Дождись решения тренера."))
+		return
+	}
+	if errors.Is(err, repo.ErrJoinPending) {
+		h.replyJoinPending(ctx, chatID)
+		return
+	}
+	if errors.Is(err, repo.ErrAlreadyMember) {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "ℹ️ Ты уже состоишь в этой команде, приглашение не использовано."))
+		return
+	}
+	if errors.Is(err, repo.ErrInviteInvalid) {
+		h.keyFails.Fail(chatID)
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "🚫 Приглашение недействительно: оно истекло, отозвано или исчерпано."))
+		return
+	}
+	if err != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, "❌ Ошибка регистрации: "+err.Error()))
+		return
+	}
+
+	if req.Status == domain.JoinPending {
+		h.joinRequested(ctx, chatID, req)
+		return
+	}
+
+	log.Printf("🏄 User %d (@%s) joined team #%d by invite #%d", chatID, from.UserName, req.TeamID, *req.InviteID)
+	if user != nil {
+		util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Теперь ты в команде '%s'.", req.TeamName)))
+		return
+	}
+	h.setChatMenu(chatID, domain.RoleAthlete)
+	util.SafeSend(h.Bot, tgbotapi.NewMessage(chatID,
+		fmt.Sprintf("✅ Ты зарегистрирован как спортсмен в команде '%s'.", req.TeamName)))
+}
+
+// inviteLimits describes expiry, uses and approval of an invite
+func inviteLimits(inv *domain.TeamInvite) string {
+	parts := []string{"бессрочно"}
+	if inv.ExpiresAt != nil {
+		parts[0] = "до " + inv.ExpiresAt.Format("02.01.2006 15:04")
+	}
+	if inv.MaxUses != nil {
+		parts = append(parts, fmt.Sprintf("использовано %d из %d", inv.Uses, *inv.MaxUses))
+	} else {
+		parts = append(parts, fmt.Sprintf("использовано %d раз", inv.Uses))
+	}
+	if inv.NeedsApproval {
+		parts = append(parts, "с одобрением тренера")
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,76 @@
+// internal/handler/throttle.go
+package handler
+
+import (
+	"sync"
+	"time"
+)
+
+// throttle limits failed attempts per chat within a sliding window. A global
+// budget across all chats stops guessing spread over many accounts.
+type throttle struct {
+	max      int // на один чат
+	maxTotal int // на все чаты вместе
+	window   time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	fails map[int64][]time.Time
+	all   []time.Time
+}
+
+func newThrottle(max, maxTotal int, window time.Duration) *throttle {
+	return &throttle{
+		max:      max,
+		maxTotal: maxTotal,
+		window:   window,
+		now:      time.Now,
+		fails:    make(map[int64][]time.Time),
+	}
+}
+
+// Allow reports whether the chat may try again and, if not, how long to wait
+func (t *throttle) Allow(chatID int64) (bool, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if fails := t.recent(chatID, now); len(fails) >= t.max {
+		return false, fails[0].Add(t.window).Sub(now)
+	}
+	t.all = t.prune(t.all, now)
+	if len(t.all) >= t.maxTotal {
+		return false, t.all[0].Add(t.window).Sub(now)
+	}
+	return true, 0
+}
+
+// Fail records a failed attempt
+func (t *throttle) Fail(chatID int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.fails[chatID] = append(t.recent(chatID, now), now)
+	t.all = append(t.prune(t.all, now), now)
+}
+
+// recent drops attempts of the chat that left the window; the caller holds t.mu
+func (t *throttle) recent(chatID int64, now time.Time) []time.Time {
+	fails := t.prune(t.fails[chatID], now)
+	if len(fails) == 0 {
+		delete(t.fails, chatID)
+	} else {
+		t.fails[chatID] = fails
+	}
+	return fails
+}
+
+// prune drops attempts that left the window
+func (t *throttle) prune(fails []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(fails) && now.Sub(fails[i]) >= t.window {
+		i++
+	}
+	return fails[i:]
+}
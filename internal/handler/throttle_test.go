@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestThrottle(max, maxTotal int) (*throttle, *time.Time) {
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	t := newThrottle(max, maxTotal, 10*time.Minute)
+	t.now = func() time.Time { return now }
+	return t, &now
+}
+
+func TestThrottlePerChat(t *testing.T) {
+	th, now := newTestThrottle(2, 100)
+
+	th.Fail(1)
+	*now = now.Add(4 * time.Minute)
+	th.Fail(1)
+	if ok, wait := th.Allow(1); ok || wait != 6*time.Minute {
+		t.Fatalf("Allow after 2 fails = %t, %s; want false, 6m", ok, wait)
+	}
+	if ok, _ := th.Allow(2); !ok {
+		t.Fatal("another chat is throttled")
+	}
+
+	// первая попытка выходит из окна — можно снова
+	*now = now.Add(6 * time.Minute)
+	if ok, _ := th.Allow(1); !ok {
+		t.Fatal("chat is still throttled after the window")
+	}
+	th.Fail(1)
+	if ok, wait := th.Allow(1); ok || wait != 4*time.Minute {
+		t.Fatalf("Allow = %t, %s; want false, 4m", ok, wait)
+	}
+
+	*now = now.Add(10 * time.Minute)
+	if ok, _ := th.Allow(1); !ok {
+		t.Fatal("chat is still throttled after every attempt expired")
+	}
+	if len(th.fails) != 0 {
+		t.Fatalf("expired attempts kept: %v", th.fails)
+	}
+}
+
+func TestThrottleGlobalBudget(t *testing.T) {
+	th, now := newTestThrottle(2, 3)
+
+	for chat := int64(1); chat <= 3; chat++ {
+		th.Fail(chat)
+		*now = now.Add(time.Minute)
+	}
+	if ok, wait := th.Allow(4); ok || wait != 7*time.Minute {
+		t.Fatalf("Allow of a new chat over the budget = %t, %s; want false, 7m", ok, wait)
+	}
+
+	*now = now.Add(7 * time.Minute)
+	if ok, _ := th.Allow(4); !ok {
+		t.Fatal("global budget did not recover after the window")
+	}
+}
@@ -0,0 +1,60 @@
+// internal/messenger/messenger.go
+package messenger
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Messenger is the part of the Telegram Bot API the handlers talk to.
+// Handlers depend on it instead of *tgbotapi.BotAPI so they can run against Recorder.
+type Messenger interface {
+	Send(msg tgbotapi.MessageConfig) (tgbotapi.Message, error)
+	Edit(edit tgbotapi.EditMessageTextConfig) error
+	AnswerCallback(cb tgbotapi.CallbackConfig) error
+	SendDocument(doc tgbotapi.DocumentConfig) error
+	SendPhoto(photo tgbotapi.PhotoConfig) error
+	SetCommands(cfg tgbotapi.SetMyCommandsConfig) error
+	Username() string
+}
+
+// Telegram sends everything through a real bot
+type Telegram struct {
+	Bot *tgbotapi.BotAPI
+}
+
+func NewTelegram(bot *tgbotapi.BotAPI) *Telegram {
+	return &Telegram{Bot: bot}
+}
+
+func (t *Telegram) Send(msg tgbotapi.MessageConfig) (tgbotapi.Message, error) {
+	return t.Bot.Send(msg)
+}
+
+func (t *Telegram) Edit(edit tgbotapi.EditMessageTextConfig) error {
+	_, err := t.Bot.Request(edit)
+	return err
+}
+
+func (t *Telegram) AnswerCallback(cb tgbotapi.CallbackConfig) error {
+	_, err := t.Bot.Request(cb)
+	return err
+}
+
+func (t *Telegram) SendDocument(doc tgbotapi.DocumentConfig) error {
+	_, err := t.Bot.Send(doc)
+	return err
+}
+
+func (t *Telegram) SendPhoto(photo tgbotapi.PhotoConfig) error {
+	_, err := t.Bot.Send(photo)
+	return err
+}
+
+func (t *Telegram) SetCommands(cfg tgbotapi.SetMyCommandsConfig) error {
+	_, err := t.Bot.Request(cfg)
+	return err
+}
+
+func (t *Telegram) Username() string {
+	return t.Bot.Self.UserName
+}
@@ -0,0 +1,121 @@
+// internal/messenger/recorder.go
+package messenger
+
+import (
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Kind of an outgoing call captured by Recorder
+type Kind string
+
+const (
+	KindMessage  Kind = "message"
+	KindEdit     Kind = "edit"
+	KindCallback Kind = "callback"
+	KindDocument Kind = "document"
+	KindPhoto    Kind = "photo"
+	KindCommands Kind = "commands"
+)
+
+// Record is a single outgoing call
+type Record struct {
+	Kind      Kind
+	ChatID    int64
+	MessageID int
+	Text      string // текст сообщения, подпись к файлу или ответ на callback
+	Markup    interface{}
+	Commands  []tgbotapi.BotCommand
+}
+
+// Recorder is an in-memory Messenger that keeps every outgoing call instead of sending it
+type Recorder struct {
+	BotName string
+
+	mu      sync.Mutex
+	records []Record
+	nextID  int
+}
+
+func NewRecorder(botName string) *Recorder {
+	return &Recorder{BotName: botName}
+}
+
+func (r *Recorder) add(rec Record) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rec.MessageID == 0 {
+		r.nextID++
+		rec.MessageID = r.nextID
+	}
+	r.records = append(r.records, rec)
+	return rec.MessageID
+}
+
+func (r *Recorder) Send(msg tgbotapi.MessageConfig) (tgbotapi.Message, error) {
+	id := r.add(Record{Kind: KindMessage, ChatID: msg.ChatID, Text: msg.Text, Markup: msg.ReplyMarkup})
+	return tgbotapi.Message{MessageID: id, Chat: &tgbotapi.Chat{ID: msg.ChatID}, Text: msg.Text}, nil
+}
+
+func (r *Recorder) Edit(edit tgbotapi.EditMessageTextConfig) error {
+	var markup interface{}
+	if edit.ReplyMarkup != nil {
+		markup = *edit.ReplyMarkup
+	}
+	r.add(Record{Kind: KindEdit, ChatID: edit.ChatID, MessageID: edit.MessageID, Text: edit.Text, Markup: markup})
+	return nil
+}
+
+func (r *Recorder) AnswerCallback(cb tgbotapi.CallbackConfig) error {
+	r.add(Record{Kind: KindCallback, Text: cb.Text})
+	return nil
+}
+
+func (r *Recorder) SendDocument(doc tgbotapi.DocumentConfig) error {
+	r.add(Record{Kind: KindDocument, ChatID: doc.ChatID, Text: doc.Caption})
+	return nil
+}
+
+func (r *Recorder) SendPhoto(photo tgbotapi.PhotoConfig) error {
+	r.add(Record{Kind: KindPhoto, ChatID: photo.ChatID, Text: photo.Caption})
+	return nil
+}
+
+func (r *Recorder) SetCommands(cfg tgbotapi.SetMyCommandsConfig) error {
+	var chatID int64
+	if cfg.Scope != nil {
+		chatID = cfg.Scope.ChatID
+	}
+	r.add(Record{Kind: KindCommands, ChatID: chatID, Commands: cfg.Commands})
+	return nil
+}
+
+func (r *Recorder) Username() string {
+	return r.BotName
+}
+
+// Records returns a copy of everything recorded so far
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Record(nil), r.records...)
+}
+
+// Texts returns texts of plain messages sent to the chat, in order
+func (r *Recorder) Texts(chatID int64) []string {
+	var texts []string
+	for _, rec := range r.Records() {
+		if rec.Kind == KindMessage && rec.ChatID == chatID {
+			texts = append(texts, rec.Text)
+		}
+	}
+	return texts
+}
+
+// Reset forgets all recorded calls
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = nil
+}
@@ -0,0 +1,1065 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"surf_bot/internal/domain"
+	"surf_bot/internal/testdb"
+)
+
+// The contract suite describes the behaviour both implementations share.
+// Handler tests run on MemoryRepository, so it must not drift from PostgreSQL.
+
+func TestMemoryRepositoryContract(t *testing.T) {
+	runRepositoryContract(t, func(t *testing.T) Repository { return NewMemoryRepository() })
+}
+
+func TestUserRepositoryContract(t *testing.T) {
+	runRepositoryContract(t, func(t *testing.T) Repository { return NewUserRepository(testdb.Migrated(t)) })
+}
+
+func runRepositoryContract(t *testing.T, newRepo func(t *testing.T) Repository) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, f *fixture)
+	}{
+		{"users", testUsers},
+		{"request lifecycle", testRequestLifecycle},
+		{"teams", testTeams},
+		{"rankings", testRankings},
+		{"coach scope", testCoachScope},
+		{"roles", testRoles},
+		{"coach invites", testCoachInvites},
+		{"join requests", testJoinRequests},
+		{"team closure", testTeamClosure},
+		{"fines", testFines},
+		{"amount override", testAmountOverride},
+		{"corrections", testCorrections},
+		{"activities", testActivities},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, &fixture{t: t, ctx: context.Background(), r: newRepo(t)})
+		})
+	}
+}
+
+type fixture struct {
+	t   *testing.T
+	ctx context.Context
+	r   Repository
+}
+
+func (f *fixture) must(err error) {
+	f.t.Helper()
+	if err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// user registers a user whose name is their username
+func (f *fixture) user(id int64, username string, role domain.Role) *domain.User {
+	f.t.Helper()
+	u := &domain.User{ID: id, Name: username, Username: username, Role: role}
+	f.must(f.r.RegisterUser(f.ctx, u))
+	return u
+}
+
+func (f *fixture) team(name string, coachID int64) int {
+	f.t.Helper()
+	f.must(f.r.CreateTeam(f.ctx, name, coachID))
+	team, err := f.r.GetTeamByName(f.ctx, name)
+	f.must(err)
+	return team.ID
+}
+
+func (f *fixture) join(userID int64, teamID int) {
+	f.t.Helper()
+	f.must(f.r.AssignUserToTeam(f.ctx, userID, teamID))
+}
+
+// request creates a pending request and returns its ID
+func (f *fixture) request(fromID int64, amount int, reason string, teamID *int) int {
+	f.t.Helper()
+	id, err := f.r.CreatePendingRequest(f.ctx, fromID, amount, reason, teamID)
+	f.must(err)
+	return id
+}
+
+func (f *fixture) give(coachID int64, username string, amount int, reason string, teamID *int) *Grant {
+	f.t.Helper()
+	g, err := f.r.GivePoints(f.ctx, coachID, username, amount, reason, teamID)
+	f.must(err)
+	return g
+}
+
+func (f *fixture) score(userID int64) int {
+	f.t.Helper()
+	score, err := f.r.GetUserScore(f.ctx, userID)
+	f.must(err)
+	return score
+}
+
+func (f *fixture) userTeams(userID int64) []string {
+	f.t.Helper()
+	teams, err := f.r.ListUserTeams(f.ctx, userID)
+	f.must(err)
+	return teamNames(teams)
+}
+
+// history renders the user's point entries, newest first
+func (f *fixture) history(userID int64) []string {
+	f.t.Helper()
+	records, err := f.r.GetUserHistory(f.ctx, userID)
+	f.must(err)
+	lines := make([]string, len(records))
+	for i, rec := range records {
+		lines[i] = fmt.Sprintf("#%d %s %+d by=%s comment=%s team=%s requested=%s corrects=%s",
+			rec.ID, rec.Status, rec.Amount, deref(rec.DecidedBy), deref(rec.Comment), deref(rec.Team),
+			deref(rec.Requested), deref(rec.CorrectsID))
+	}
+	return lines
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func deref[T any](p *T) string {
+	if p == nil {
+		return "-"
+	}
+	return fmt.Sprint(*p)
+}
+
+func teamNames(teams []domain.Team) []string {
+	names := make([]string, len(teams))
+	for i, t := range teams {
+		names[i] = t.Name
+	}
+	return names
+}
+
+func scores(ranking []domain.ScoreEntry) []string {
+	lines := make([]string, len(ranking))
+	for i, e := range ranking {
+		lines[i] = fmt.Sprintf("%s:%d", e.Name, e.Score)
+	}
+	return lines
+}
+
+func requestIDs(reqs []PendingRequest) []int {
+	ids := make([]int, len(reqs))
+	for i, r := range reqs {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func joinIDs(reqs []domain.JoinRequest) []int {
+	ids := make([]int, len(reqs))
+	for i, r := range reqs {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func athleteNames(athletes []domain.AthleteShort) []string {
+	names := make([]string, len(athletes))
+	for i, a := range athletes {
+		names[i] = a.Name
+	}
+	return names
+}
+
+func assertEqual[T any](t *testing.T, what string, got, want T) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("%s = %+v, want %+v", what, got, want)
+	}
+}
+
+// assertErr checks that err is set and, when target is given, wraps it
+func assertErr(t *testing.T, what string, err, target error) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("%s: expected an error", what)
+	}
+	if target != nil && !errors.Is(err, target) {
+		t.Fatalf("%s: error %v does not wrap %v", what, err, target)
+	}
+}
+
+func testUsers(t *testing.T, f *fixture) {
+	athlete := f.user(10, "Kelly", domain.RoleAthlete)
+	coach := f.user(20, "coach", domain.RoleCoach)
+
+	// повторная регистрация не меняет ни имя, ни роль
+	f.must(f.r.RegisterUser(f.ctx, &domain.User{ID: athlete.ID, Name: "other", Username: "other", Role: domain.RoleCoach}))
+	got, err := f.r.GetUserByID(f.ctx, athlete.ID)
+	f.must(err)
+	assertEqual(t, "GetUserByID", got, athlete)
+
+	got, err = f.r.GetUserByUsername(f.ctx, "kelly")
+	f.must(err)
+	assertEqual(t, "GetUserByUsername ignores case", got.ID, athlete.ID)
+	_, err = f.r.GetUserByUsername(f.ctx, "nobody")
+	assertErr(t, "GetUserByUsername(missing)", err, sql.ErrNoRows)
+	if u, _ := f.r.GetUserByID(f.ctx, 404); u != nil {
+		t.Fatalf("GetUserByID(missing) = %+v", u)
+	}
+
+	assertEqual(t, "score of a new athlete", f.score(athlete.ID), 0)
+	_, err = f.r.GetUserScore(f.ctx, coach.ID)
+	assertErr(t, "GetUserScore(coach)", err, sql.ErrNoRows)
+
+	athletes, err := f.r.ListAthletes(f.ctx)
+	f.must(err)
+	assertEqual(t, "ListAthletes", athleteNames(athletes), []string{"Kelly"})
+
+	mode, err := f.r.GetNotifyMode(f.ctx, coach.ID)
+	f.must(err)
+	assertEqual(t, "default notify mode", mode, domain.NotifyInstant)
+	f.must(f.r.SetNotifyMode(f.ctx, coach.ID, domain.NotifyDigest))
+	mode, err = f.r.GetNotifyMode(f.ctx, coach.ID)
+	f.must(err)
+	assertEqual(t, "notify mode", mode, domain.NotifyDigest)
+
+	second := f.user(21, "second", domain.RoleCoach)
+	recipients, err := f.r.ListRequestRecipients(f.ctx, athlete.ID, nil)
+	f.must(err)
+	assertEqual(t, "ListRequestRecipients", recipients, []Recipient{
+		{ID: coach.ID, Mode: domain.NotifyDigest}, {ID: second.ID, Mode: domain.NotifyInstant},
+	})
+	f.must(f.r.SetNotifyMode(f.ctx, second.ID, domain.NotifyOff))
+	recipients, err = f.r.ListRequestRecipients(f.ctx, athlete.ID, nil)
+	f.must(err)
+	assertEqual(t, "recipients with notifications off", recipients, []Recipient{{ID: coach.ID, Mode: domain.NotifyDigest}})
+}
+
+func testRequestLifecycle(t *testing.T, f *fixture) {
+	athlete := f.user(10, "kelly", domain.RoleAthlete)
+	other := f.user(11, "slater", domain.RoleAthlete)
+	coach := f.user(20, "coach", domain.RoleCoach)
+
+	approved := f.request(athlete.ID, 10, "тренировка", nil)
+	rejected := f.request(athlete.ID, 5, "без фото", nil)
+	cancelled := f.request(athlete.ID, 3, "передумал", nil)
+	expired := f.request(athlete.ID, 2, "забыли", nil)
+	edited := f.request(athlete.ID, 7, "доска", nil)
+
+	req, err := f.r.GetPendingRequest(f.ctx, approved)
+	f.must(err)
+	assertEqual(t, "GetPendingRequest", *req, PendingRequest{
+		ID: approved, UserID: athlete.ID, Name: "kelly", Username: "kelly", Amount: 10, Reason: "тренировка",
+	})
+	all, err := f.r.GetPendingRequests(f.ctx)
+	f.must(err)
+	assertEqual(t, "GetPendingRequests", requestIDs(all), []int{approved, rejected, cancelled, expired, edited})
+
+	g, err := f.r.ApproveRequest(f.ctx, approved, coach.ID, nil, "")
+	f.must(err)
+	assertEqual(t, "ApproveRequest", *g, Grant{PointID: approved, UserID: athlete.ID, Amount: 10, Reason: "тренировка"})
+	assertEqual(t, "score after approval", f.score(athlete.ID), 10)
+	_, err = f.r.ApproveRequest(f.ctx, approved, coach.ID, nil, "")
+	assertErr(t, "approve twice", err, sql.ErrNoRows)
+	_, err = f.r.RejectRequest(f.ctx, approved, coach.ID, "поздно")
+	assertErr(t, "reject approved", err, sql.ErrNoRows)
+	_, err = f.r.GetPendingRequest(f.ctx, approved)
+	assertErr(t, "GetPendingRequest(approved)", err, sql.ErrNoRows)
+
+	fromID, err := f.r.RejectRequest(f.ctx, rejected, coach.ID, "нет фото")
+	f.must(err)
+	assertEqual(t, "RejectRequest author", fromID, athlete.ID)
+
+	assertErr(t, "cancel someone else's request", f.r.CancelRequest(f.ctx, cancelled, other.ID), nil)
+	f.must(f.r.CancelRequest(f.ctx, cancelled, athlete.ID))
+	assertErr(t, "cancel twice", f.r.CancelRequest(f.ctx, cancelled, athlete.ID), nil)
+
+	f.must(f.r.UpdatePendingAmount(f.ctx, edited, 8))
+	req, err = f.r.GetPendingRequest(f.ctx, edited)
+	f.must(err)
+	assertEqual(t, "edited amount", req.Amount, 8)
+	g, err = f.r.ApproveRequest(f.ctx, edited, coach.ID, nil, "")
+	f.must(err)
+	assertEqual(t, "approve edited", g.Amount, 8)
+	assertErr(t, "edit approved", f.r.UpdatePendingAmount(f.ctx, edited, 1), nil)
+
+	n, err := f.r.ExpirePendingRequests(f.ctx, time.Hour)
+	f.must(err)
+	assertEqual(t, "expired within an hour", n, int64(0))
+	time.Sleep(10 * time.Millisecond)
+	n, err = f.r.ExpirePendingRequests(f.ctx, 0)
+	f.must(err)
+	assertEqual(t, "expired", n, int64(1))
+
+	all, err = f.r.GetPendingRequests(f.ctx)
+	f.must(err)
+	assertEqual(t, "pending after decisions", len(all), 0)
+
+	_, err = f.r.GivePoints(f.ctx, coach.ID, "nobody", 5, "нет такого", nil)
+	assertErr(t, "GivePoints to nobody", err, sql.ErrNoRows)
+	_, err = f.r.GivePoints(f.ctx, coach.ID, "coach", 5, "не спортсмен", nil)
+	assertErr(t, "GivePoints to a coach", err, sql.ErrNoRows)
+	given := f.give(coach.ID, "KELLY", 2, "соревнования", nil)
+	assertEqual(t, "GivePoints", *given, Grant{PointID: given.PointID, UserID: athlete.ID, Amount: 2, Reason: "соревнования"})
+	assertEqual(t, "score", f.score(athlete.ID), 20)
+
+	assertEqual(t, "history", f.history(athlete.ID), []string{
+		fmt.Sprintf("#%d approved +2 by=coach comment=- team=- requested=- corrects=-", given.PointID),
+		fmt.Sprintf("#%d approved +8 by=coach comment=- team=- requested=7 corrects=-", edited),
+		fmt.Sprintf("#%d expired +2 by=- comment=- team=- requested=- corrects=-", expired),
+		fmt.Sprintf("#%d cancelled +3 by=kelly comment=- team=- requested=- corrects=-", cancelled),
+		fmt.Sprintf("#%d rejected +5 by=coach comment=нет фото team=- requested=- corrects=-", rejected),
+		fmt.Sprintf("#%d approved +10 by=coach comment=- team=- requested=- corrects=-", approved),
+	})
+	records, err := f.r.GetUserHistory(f.ctx, athlete.ID)
+	f.must(err)
+	for _, rec := range records {
+		if rec.DecidedAt == nil || rec.CreatedAt.IsZero() {
+			t.Fatalf("entry #%d has no timestamps: %+v", rec.ID, rec)
+		}
+	}
+}
+
+func testTeams(t *testing.T, f *fixture) {
+	c1 := f.user(20, "alpha", domain.RoleCoach)
+	c2 := f.user(21, "beta", domain.RoleCoach)
+	x := f.user(10, "ann", domain.RoleAthlete)
+	y := f.user(11, "bob", domain.RoleAthlete)
+	free := f.user(12, "cid", domain.RoleAthlete)
+	a := f.team("A", c1.ID)
+	b := f.team("B", c1.ID)
+	f.team("C", c2.ID)
+
+	assertErr(t, "duplicate team", f.r.CreateTeam(f.ctx, "A", c2.ID), nil)
+	assertErr(t, "assign to a missing team", f.r.AssignUserToTeam(f.ctx, x.ID, 9999), nil)
+	teams, err := f.r.ListTeams(f.ctx)
+	f.must(err)
+	assertEqual(t, "ListTeams", teamNames(teams), []string{"A", "B", "C"})
+
+	f.join(x.ID, a)
+	f.join(x.ID, a) // повторное добавление ничего не меняет
+	f.join(x.ID, b)
+	f.join(y.ID, b)
+	assertEqual(t, "ListUserTeams", f.userTeams(x.ID), []string{"A", "B"})
+	assertEqual(t, "teams of a free athlete", len(f.userTeams(free.ID)), 0)
+
+	athletes, err := f.r.ListAthletesByTeam(f.ctx, &a)
+	f.must(err)
+	assertEqual(t, "ListAthletesByTeam", athleteNames(athletes), []string{"ann"})
+	athletes, err = f.r.ListAthletesByTeam(f.ctx, nil)
+	f.must(err)
+	assertEqual(t, "ListAthletesByTeam(nil)", athleteNames(athletes), []string{"ann", "bob", "cid"})
+
+	f.request(x.ID, 1, "", &a)
+	f.request(y.ID, 2, "", &b)
+	f.request(free.ID, 3, "", nil)
+	reqs, err := f.r.GetPendingRequestsByTeam(f.ctx, &b)
+	f.must(err)
+	assertEqual(t, "GetPendingRequestsByTeam", len(reqs), 1)
+	assertEqual(t, "request of a team member", reqs[0].UserID, y.ID)
+
+	f.must(f.r.RemoveUserFromTeam(f.ctx, x.ID, b))
+	assertErr(t, "remove twice", f.r.RemoveUserFromTeam(f.ctx, x.ID, b), nil)
+	assertEqual(t, "teams after leaving", f.userTeams(x.ID), []string{"A"})
+	f.join(x.ID, b)
+	memberships, err := f.r.ListMemberships(f.ctx, x.ID)
+	f.must(err)
+	var lines []string
+	for _, m := range memberships {
+		lines = append(lines, fmt.Sprintf("%s current=%t", m.TeamName, m.LeftAt == nil))
+	}
+	assertEqual(t, "ListMemberships", lines, []string{"B current=true", "A current=true", "B current=false"})
+
+	_, err = f.r.DeleteTeam(f.ctx, a)
+	assertErr(t, "delete a team with members", err, nil)
+	c := f.team("D", c1.ID)
+	_, err = f.r.DeleteTeam(f.ctx, c)
+	f.must(err)
+	_, err = f.r.GetTeamByID(f.ctx, c)
+	assertErr(t, "GetTeamByID(deleted)", err, sql.ErrNoRows)
+}
+
+func testRankings(t *testing.T, f *fixture) {
+	c1 := f.user(20, "alpha", domain.RoleCoach)
+	c2 := f.user(21, "beta", domain.RoleCoach)
+	x := f.user(10, "ann", domain.RoleAthlete)
+	y := f.user(11, "bob", domain.RoleAthlete)
+	z := f.user(12, "cid", domain.RoleAthlete)
+	f.user(13, "dan", domain.RoleAthlete)
+	a := f.team("A", c1.ID)
+	b := f.team("B", c1.ID)
+	c := f.team("C", c2.ID)
+	f.join(x.ID, a)
+	f.join(x.ID, b)
+	f.join(y.ID, a)
+	f.join(z.ID, c)
+
+	f.give(c1.ID, "ann", 5, "", &a)
+	f.give(c1.ID, "ann", 3, "", &b)
+	f.give(c1.ID, "ann", 100, "", nil)
+	f.give(c2.ID, "ann", 7, "", &c) // ann не в C: эти баллы не видны ни в одном рейтинге команд
+	bobA := f.give(c1.ID, "bob", 4, "", &a)
+	f.give(c2.ID, "cid", 6, "", &c)
+	f.request(y.ID, 100, "", &a) // ожидающие запросы не учитываются
+
+	rank := func(what string, get func() ([]domain.ScoreEntry, error), want ...string) {
+		t.Helper()
+		ranking, err := get()
+		f.must(err)
+		if want == nil {
+			want = []string{}
+		}
+		assertEqual(t, what, scores(ranking), want)
+	}
+	byTeam := func(id int) func() ([]domain.ScoreEntry, error) {
+		return func() ([]domain.ScoreEntry, error) { return f.r.GetRankingByTeam(f.ctx, id) }
+	}
+	byCoach := func(id int64) func() ([]domain.ScoreEntry, error) {
+		return func() ([]domain.ScoreEntry, error) { return f.r.GetRankingByCoach(f.ctx, id) }
+	}
+
+	rank("GetRanking", func() ([]domain.ScoreEntry, error) { return f.r.GetRanking(f.ctx) }, "ann:115", "cid:6", "bob:4", "dan:0")
+	rank("team A", byTeam(a), "ann:5", "bob:4")
+	rank("team B", byTeam(b), "ann:3")
+	rank("team C", byTeam(c), "cid:6")
+	rank("coach of A and B", byCoach(c1.ID), "ann:8", "bob:4")
+	rank("coach of C", byCoach(c2.ID), "cid:6")
+
+	f.must(f.r.RemoveUserFromTeam(f.ctx, x.ID, b))
+	rank("coach after ann left B", byCoach(c1.ID), "ann:5", "bob:4")
+	f.must(f.r.RemoveUserFromTeam(f.ctx, y.ID, a))
+	rank("team A after bob left", byTeam(a), "ann:5")
+	rank("coach after bob left", byCoach(c1.ID), "ann:5")
+
+	// баллы за время вне команды в её рейтинг не входят, а исправление идёт за исходным начислением
+	f.give(c1.ID, "bob", 9, "", &a)
+	_, err := f.r.CorrectGrant(f.ctx, c1.ID, bobA.PointID, 6, "")
+	f.must(err)
+	f.join(y.ID, a)
+	f.give(c1.ID, "bob", 1, "", &a)
+	rank("team A after bob came back", byTeam(a), "bob:7", "ann:5")
+	rank("coach after bob came back", byCoach(c1.ID), "bob:7", "ann:5")
+
+	// архивная команда помнит всех, кто в ней был, но выпадает из рейтинга тренера
+	_, err = f.r.ArchiveTeam(f.ctx, a, c1.ID)
+	f.must(err)
+	rank("archived team A", byTeam(a), "bob:7", "ann:5")
+	rank("coach after archiving", byCoach(c1.ID))
+}
+
+func testCoachScope(t *testing.T, f *fixture) {
+	c1 := f.user(20, "alpha", domain.RoleCoach)
+	c2 := f.user(21, "beta", domain.RoleCoach)
+	x := f.user(10, "ann", domain.RoleAthlete)
+	y := f.user(11, "bob", domain.RoleAthlete)
+	free := f.user(12, "cid", domain.RoleAthlete)
+	a := f.team("A", c1.ID)
+	b := f.team("B", c1.ID)
+	c := f.team("C", c2.ID)
+	f.join(x.ID, a)
+	f.join(y.ID, c)
+
+	teams, err := f.r.ListCoachTeams(f.ctx, c1.ID)
+	f.must(err)
+	assertEqual(t, "ListCoachTeams", teamNames(teams), []string{"A", "B"})
+	for _, tc := range []struct {
+		coach, athlete int64
+		want           bool
+	}{
+		{c1.ID, x.ID, true},
+		{c2.ID, x.ID, false},
+		{c2.ID, free.ID, true}, // спортсмен без команды доступен всем тренерам
+	} {
+		ok, err := f.r.CoachesAthlete(f.ctx, tc.coach, tc.athlete)
+		f.must(err)
+		assertEqual(t, fmt.Sprintf("CoachesAthlete(%d, %d)", tc.coach, tc.athlete), ok, tc.want)
+	}
+	_, err = f.r.CoachesAthlete(f.ctx, c1.ID, 404)
+	assertErr(t, "CoachesAthlete(missing)", err, sql.ErrNoRows)
+	ok, err := f.r.CoachesTeam(f.ctx, c2.ID, a)
+	f.must(err)
+	assertEqual(t, "CoachesTeam(beta, A)", ok, false)
+
+	athletes, err := f.r.ListAthletesByCoach(f.ctx, c2.ID)
+	f.must(err)
+	assertEqual(t, "ListAthletesByCoach", athleteNames(athletes), []string{"bob", "cid"})
+
+	f.request(x.ID, 1, "", &a)
+	f.request(y.ID, 2, "", &c)
+	freeReq := f.request(free.ID, 3, "", nil)
+	reqs, err := f.r.GetPendingRequestsByCoach(f.ctx, c2.ID)
+	f.must(err)
+	assertEqual(t, "GetPendingRequestsByCoach", len(reqs), 2)
+	assertEqual(t, "request of a free athlete", reqs[1].ID, freeReq)
+
+	recipients, err := f.r.ListRequestRecipients(f.ctx, x.ID, &a)
+	f.must(err)
+	assertEqual(t, "recipients of a team request", recipients, []Recipient{{ID: c1.ID, Mode: domain.NotifyInstant}})
+	recipients, err = f.r.ListRequestRecipients(f.ctx, free.ID, nil)
+	f.must(err)
+	assertEqual(t, "recipients of a free athlete", len(recipients), 2)
+
+	f.give(c1.ID, "ann", 5, "", &a)
+	f.give(c2.ID, "bob", 4, "", &c)
+	f.give(c2.ID, "cid", 9, "", nil)
+	ranking, err := f.r.GetRankingByCoach(f.ctx, c2.ID)
+	f.must(err)
+	assertEqual(t, "GetRankingByCoach", scores(ranking), []string{"bob:4"})
+
+	f.must(f.r.AddTeamCoach(f.ctx, b, c2.ID))
+	f.must(f.r.AddTeamCoach(f.ctx, b, c2.ID)) // повторное добавление ничего не меняет
+	ok, err = f.r.CoachesTeam(f.ctx, c2.ID, b)
+	f.must(err)
+	assertEqual(t, "CoachesTeam after AddTeamCoach", ok, true)
+	assertErr(t, "AddTeamCoach(missing team)", f.r.AddTeamCoach(f.ctx, 9999, c2.ID), nil)
+	f.must(f.r.RemoveTeamCoach(f.ctx, b, c1.ID))
+	assertErr(t, "remove the last coach", f.r.RemoveTeamCoach(f.ctx, b, c2.ID), nil)
+	assertErr(t, "remove a non-coach", f.r.RemoveTeamCoach(f.ctx, b, c1.ID), nil)
+}
+
+func testRoles(t *testing.T, f *fixture) {
+	admin := f.user(1, "boss", domain.RoleAdmin)
+	c1 := f.user(20, "alpha", domain.RoleCoach)
+	c2 := f.user(21, "beta", domain.RoleCoach)
+	athlete := f.user(10, "kelly", domain.RoleAthlete)
+	wave := f.team("Волна", c1.ID)
+	surf := f.team("Прибой", c1.ID)
+
+	_, err := f.r.SetUserRole(f.ctx, c1.ID, domain.RoleAthlete, &admin.ID)
+	assertErr(t, "demote the only coach", err, ErrLastCoach)
+	u, err := f.r.GetUserByID(f.ctx, c1.ID)
+	f.must(err)
+	assertEqual(t, "role after refusal", u.Role, domain.RoleCoach)
+
+	f.must(f.r.AddTeamCoach(f.ctx, wave, c2.ID))
+	_, err = f.r.SetUserRole(f.ctx, c1.ID, domain.RoleAthlete, &admin.ID)
+	assertErr(t, "demote the only coach of another team", err, ErrLastCoach)
+	ok, err := f.r.CoachesTeam(f.ctx, c1.ID, wave)
+	f.must(err)
+	assertEqual(t, "still coaches after refusal", ok, true)
+
+	f.must(f.r.AddTeamCoach(f.ctx, surf, c2.ID))
+	f.must(f.r.AddTeamCoach(f.ctx, surf, c2.ID))
+	coaches, err := f.r.ListTeamCoaches(f.ctx, surf)
+	f.must(err)
+	assertEqual(t, "ListTeamCoaches", coaches, []int64{c1.ID, c2.ID})
+	assertErr(t, "remove a non-coach", f.r.RemoveTeamCoach(f.ctx, surf, athlete.ID), nil)
+
+	old, err := f.r.SetUserRole(f.ctx, c1.ID, domain.RoleAthlete, &admin.ID)
+	f.must(err)
+	assertEqual(t, "old role", old, domain.RoleCoach)
+	ok, err = f.r.CoachesTeam(f.ctx, c1.ID, wave)
+	f.must(err)
+	assertEqual(t, "demoted coach left the team", ok, false)
+	assertEqual(t, "score of the demoted coach", f.score(c1.ID), 0)
+	assertErr(t, "remove the last coach", f.r.RemoveTeamCoach(f.ctx, wave, c2.ID), nil)
+
+	old, err = f.r.SetUserRole(f.ctx, c1.ID, domain.RoleAthlete, nil)
+	f.must(err)
+	assertEqual(t, "no-op change", old, domain.RoleAthlete)
+	old, err = f.r.SetUserRole(f.ctx, athlete.ID, domain.RoleCoach, nil)
+	f.must(err)
+	assertEqual(t, "promoted from", old, domain.RoleAthlete)
+	_, err = f.r.SetUserRole(f.ctx, 404, domain.RoleCoach, nil)
+	assertErr(t, "SetUserRole(missing)", err, sql.ErrNoRows)
+
+	staff, err := f.r.ListStaff(f.ctx)
+	f.must(err)
+	var names []string
+	for _, s := range staff {
+		names = append(names, s.Name)
+	}
+	assertEqual(t, "ListStaff", names, []string{"boss", "beta", "kelly"})
+
+	changes, err := f.r.ListRoleChanges(f.ctx, 10)
+	f.must(err)
+	var lines []string
+	for _, c := range changes {
+		lines = append(lines, fmt.Sprintf("%s %s->%s by=%s", c.Username, c.OldRole, c.NewRole, deref(c.ChangedBy)))
+	}
+	assertEqual(t, "ListRoleChanges", lines, []string{"kelly athlete->coach by=-", "alpha coach->athlete by=boss"})
+
+	// администратор из ADMIN_IDS регистрируется сразу с ролью, запись в журнале без автора
+	f.must(f.r.RegisterAdmin(f.ctx, &domain.User{ID: 2, Name: "chief", Username: "chief", Role: domain.RoleAthlete}))
+	f.must(f.r.RegisterAdmin(f.ctx, &domain.User{ID: 2, Name: "chief", Username: "chief"}))
+	f.must(f.r.RegisterAdmin(f.ctx, athlete))
+	u, err = f.r.GetUserByID(f.ctx, 2)
+	f.must(err)
+	assertEqual(t, "registered admin", u.Role, domain.RoleAdmin)
+	u, err = f.r.GetUserByID(f.ctx, athlete.ID)
+	f.must(err)
+	assertEqual(t, "registered user keeps the role", u.Role, domain.RoleCoach)
+	changes, err = f.r.ListRoleChanges(f.ctx, 1)
+	f.must(err)
+	assertEqual(t, "admin registration", fmt.Sprintf("%s %q->%s by=%s", changes[0].Username, changes[0].OldRole, changes[0].NewRole,
+		deref(changes[0].ChangedBy)), `chief ""->admin by=-`)
+
+	changes, err = f.r.ListRoleChanges(f.ctx, 1)
+	f.must(err)
+	assertEqual(t, "ListRoleChanges limit", len(changes), 1)
+
+	ranking, err := f.r.GetRanking(f.ctx)
+	f.must(err)
+	assertEqual(t, "ranking after role changes", scores(ranking), []string{"alpha:0"})
+}
+
+func testCoachInvites(t *testing.T, f *fixture) {
+	admin := f.user(1, "boss", domain.RoleAdmin)
+	f.must(f.r.CreateCoachInvite(f.ctx, "c-hash", admin.ID, time.Now().Add(time.Hour)))
+	f.must(f.r.CreateCoachInvite(f.ctx, "c-old", admin.ID, time.Now().Add(-time.Hour)))
+
+	f.must(f.r.RedeemCoachInvite(f.ctx, "c-hash", &domain.User{ID: 40, Name: "trainer", Username: "trainer"}))
+	u, err := f.r.GetUserByID(f.ctx, 40)
+	f.must(err)
+	assertEqual(t, "redeemed coach", u, &domain.User{ID: 40, Name: "trainer", Username: "trainer", Role: domain.RoleCoach})
+	_, err = f.r.GetUserScore(f.ctx, 40)
+	assertErr(t, "coach score", err, sql.ErrNoRows)
+
+	err = f.r.RedeemCoachInvite(f.ctx, "c-hash", &domain.User{ID: 41, Name: "late", Username: "late"})
+	assertErr(t, "used invite", err, ErrInviteInvalid)
+	err = f.r.RedeemCoachInvite(f.ctx, "c-old", &domain.User{ID: 41, Name: "late", Username: "late"})
+	assertErr(t, "expired invite", err, ErrInviteInvalid)
+	err = f.r.RedeemCoachInvite(f.ctx, "nope", &domain.User{ID: 41, Name: "late", Username: "late"})
+	assertErr(t, "unknown invite", err, ErrInviteInvalid)
+	if u, _ := f.r.GetUserByID(f.ctx, 41); u != nil {
+		t.Fatalf("failed redeem registered %+v", u)
+	}
+
+	f.must(f.r.CreateCoachInvite(f.ctx, "c-again", admin.ID, time.Now().Add(time.Hour)))
+	err = f.r.RedeemCoachInvite(f.ctx, "c-again", &domain.User{ID: 40, Name: "trainer", Username: "trainer"})
+	assertErr(t, "redeem by a registered user", err, nil)
+}
+
+func testJoinRequests(t *testing.T, f *fixture) {
+	coach := f.user(20, "coach", domain.RoleCoach)
+	stranger := f.user(21, "stranger", domain.RoleCoach)
+	wave := f.team("Волна", coach.ID)
+	surf := f.team("Прибой", coach.ID)
+
+	invite := func(hash string, teamID int, maxUses *int, expiresAt *time.Time, approval bool) int {
+		t.Helper()
+		id, err := f.r.CreateTeamInvite(f.ctx, &domain.TeamInvite{
+			TeamID: teamID, TokenHash: hash, CreatedBy: &coach.ID, MaxUses: maxUses, ExpiresAt: expiresAt, NeedsApproval: approval,
+		})
+		f.must(err)
+		return id
+	}
+
+	once := invite("h-once", wave, ptr(1), nil, false)
+	team, err := f.r.GetTeamByID(f.ctx, wave)
+	f.must(err)
+	assertEqual(t, "TokenInvites", team.TokenInvites, true)
+
+	newbie := &domain.User{ID: 30, Name: "newbie", Username: "newbie"}
+	req, err := f.r.RedeemTeamInvite(f.ctx, "h-once", newbie)
+	f.must(err)
+	if req.Status != domain.JoinApproved || req.TeamID != wave || req.TeamName != "Волна" ||
+		req.InviteID == nil || *req.InviteID != once || req.DecidedAt == nil {
+		t.Fatalf("RedeemTeamInvite = %+v", req)
+	}
+	u, err := f.r.GetUserByID(f.ctx, newbie.ID)
+	f.must(err)
+	assertEqual(t, "registered by invite", u, &domain.User{ID: 30, Name: "newbie", Username: "newbie", Role: domain.RoleAthlete})
+	assertEqual(t, "score", f.score(newbie.ID), 0)
+	assertEqual(t, "teams", f.userTeams(newbie.ID), []string{"Волна"})
+
+	got, err := f.r.GetTeamInvite(f.ctx, once)
+	f.must(err)
+	assertEqual(t, "uses", got.Uses, 1)
+	active, err := f.r.ListTeamInvites(f.ctx, wave)
+	f.must(err)
+	assertEqual(t, "used up invite is not listed", len(active), 0)
+
+	guest := &domain.User{ID: 31, Name: "guest", Username: "guest"}
+	_, err = f.r.RedeemTeamInvite(f.ctx, "h-once", guest)
+	assertErr(t, "used up invite", err, ErrInviteInvalid)
+	_, err = f.r.RedeemTeamInvite(f.ctx, "nope", guest)
+	assertErr(t, "unknown invite", err, ErrInviteInvalid)
+	revoked := invite("h-revoked", wave, nil, nil, false)
+	f.must(f.r.RevokeTeamInvite(f.ctx, revoked))
+	assertErr(t, "revoke twice", f.r.RevokeTeamInvite(f.ctx, revoked), nil)
+	_, err = f.r.RedeemTeamInvite(f.ctx, "h-revoked", guest)
+	assertErr(t, "revoked invite", err, ErrInviteInvalid)
+	invite("h-expired", wave, nil, ptr(time.Now().Add(-time.Hour)), false)
+	_, err = f.r.RedeemTeamInvite(f.ctx, "h-expired", guest)
+	assertErr(t, "expired invite", err, ErrInviteInvalid)
+	if u, _ := f.r.GetUserByID(f.ctx, guest.ID); u != nil {
+		t.Fatalf("invalid invite registered %+v", u)
+	}
+
+	open := invite("h-open", wave, nil, nil, false)
+	_, err = f.r.RedeemTeamInvite(f.ctx, "h-open", newbie)
+	assertErr(t, "invite into the athlete's own team", err, ErrAlreadyMember)
+	got, err = f.r.GetTeamInvite(f.ctx, open)
+	f.must(err)
+	assertEqual(t, "uses after ErrAlreadyMember", got.Uses, 0)
+
+	// зарегистрированный спортсмен вступает ещё в одну команду и сохраняет баллы
+	f.give(coach.ID, "newbie", 5, "тренировка", &wave)
+	invite("h-approve", surf, nil, nil, true)
+	req, err = f.r.RedeemTeamInvite(f.ctx, "h-approve", newbie)
+	f.must(err)
+	if req.Status != domain.JoinPending || req.TeamID != surf || req.DecidedAt != nil {
+		t.Fatalf("RedeemTeamInvite(needs approval) = %+v", req)
+	}
+	pending, err := f.r.GetPendingJoinRequest(f.ctx, newbie.ID)
+	f.must(err)
+	if pending == nil || pending.ID != req.ID {
+		t.Fatalf("GetPendingJoinRequest = %+v, want #%d", pending, req.ID)
+	}
+	assertEqual(t, "teams while pending", f.userTeams(newbie.ID), []string{"Волна"})
+
+	_, err = f.r.RedeemTeamInvite(f.ctx, "h-approve", newbie)
+	assertErr(t, "second pending request by invite", err, ErrJoinPending)
+	_, err = f.r.CreateJoinRequest(f.ctx, newbie, surf)
+	assertErr(t, "second pending request", err, ErrJoinPending)
+
+	byCoach, err := f.r.ListJoinRequestsByCoach(f.ctx, coach.ID)
+	f.must(err)
+	assertEqual(t, "ListJoinRequestsByCoach", joinIDs(byCoach), []int{req.ID})
+	byCoach, err = f.r.ListJoinRequestsByCoach(f.ctx, stranger.ID)
+	f.must(err)
+	assertEqual(t, "requests of another coach", len(byCoach), 0)
+
+	approved, err := f.r.ApproveJoinRequest(f.ctx, req.ID, coach.ID)
+	f.must(err)
+	if approved.Status != domain.JoinApproved || approved.DecidedBy == nil || *approved.DecidedBy != coach.ID {
+		t.Fatalf("ApproveJoinRequest = %+v", approved)
+	}
+	assertEqual(t, "teams after approval", f.userTeams(newbie.ID), []string{"Волна", "Прибой"})
+	assertEqual(t, "score kept", f.score(newbie.ID), 5)
+	_, err = f.r.ApproveJoinRequest(f.ctx, req.ID, coach.ID)
+	assertErr(t, "approve twice", err, nil)
+	pending, err = f.r.GetPendingJoinRequest(f.ctx, newbie.ID)
+	if pending != nil || err != nil {
+		t.Fatalf("GetPendingJoinRequest after approval = %+v, %v", pending, err)
+	}
+
+	// заявка без приглашения: пока её рассматривают, пользователя нет
+	jr, err := f.r.CreateJoinRequest(f.ctx, guest, wave)
+	f.must(err)
+	if jr.Status != domain.JoinPending || jr.InviteID != nil {
+		t.Fatalf("CreateJoinRequest = %+v", jr)
+	}
+	if u, _ := f.r.GetUserByID(f.ctx, guest.ID); u != nil {
+		t.Fatalf("pending applicant registered %+v", u)
+	}
+	all, err := f.r.ListJoinRequests(f.ctx)
+	f.must(err)
+	assertEqual(t, "ListJoinRequests", joinIDs(all), []int{jr.ID})
+
+	// приглашение без подтверждения действует, даже если другая заявка ждёт решения
+	invite("h-open-surf", surf, nil, nil, false)
+	_, err = f.r.RedeemTeamInvite(f.ctx, "h-open-surf", guest)
+	f.must(err)
+	assertEqual(t, "teams of the guest", f.userTeams(guest.ID), []string{"Прибой"})
+
+	denied, err := f.r.DenyJoinRequest(f.ctx, jr.ID, coach.ID)
+	f.must(err)
+	assertEqual(t, "denied status", denied.Status, domain.JoinDenied)
+	assertEqual(t, "teams after denial", f.userTeams(guest.ID), []string{"Прибой"})
+	stored, err := f.r.GetJoinRequest(f.ctx, jr.ID)
+	f.must(err)
+	assertEqual(t, "stored status", stored.Status, domain.JoinDenied)
+
+	// пока заявка ждала, заявитель стал тренером по приглашению
+	late := &domain.User{ID: 50, Name: "late", Username: "late"}
+	lateReq, err := f.r.CreateJoinRequest(f.ctx, late, wave)
+	f.must(err)
+	f.must(f.r.CreateCoachInvite(f.ctx, "c-late", coach.ID, time.Now().Add(time.Hour)))
+	f.must(f.r.RedeemCoachInvite(f.ctx, "c-late", late))
+	_, err = f.r.ApproveJoinRequest(f.ctx, lateReq.ID, coach.ID)
+	assertErr(t, "approve a coach", err, ErrNotAthlete)
+	assertEqual(t, "teams of the coach", len(f.userTeams(late.ID)), 0)
+	stored, err = f.r.GetJoinRequest(f.ctx, lateReq.ID)
+	f.must(err)
+	assertEqual(t, "status after refusal", stored.Status, domain.JoinPending)
+
+	_, err = f.r.GetJoinRequest(f.ctx, 9999)
+	assertErr(t, "GetJoinRequest(missing)", err, sql.ErrNoRows)
+}
+
+func testTeamClosure(t *testing.T, f *fixture) {
+	admin := f.user(1, "boss", domain.RoleAdmin)
+	coach := f.user(20, "coach", domain.RoleCoach)
+	athlete := f.user(10, "kelly", domain.RoleAthlete)
+	wave := f.team("Волна", coach.ID)
+	surf := f.team("Прибой", coach.ID)
+	f.join(athlete.ID, wave)
+	_, err := f.r.CreateTeamInvite(f.ctx, &domain.TeamInvite{TeamID: wave, TokenHash: "h-wave", CreatedBy: &coach.ID})
+	f.must(err)
+	pending := f.request(athlete.ID, 5, "тренировка", &wave)
+	jr, err := f.r.CreateJoinRequest(f.ctx, &domain.User{ID: 30, Name: "guest", Username: "guest"}, wave)
+	f.must(err)
+
+	impact, err := f.r.GetTeamImpact(f.ctx, wave)
+	f.must(err)
+	assertEqual(t, "GetTeamImpact", *impact, domain.TeamImpact{Members: 1, PendingRequests: 1, PendingJoins: 1, ActiveInvites: 1, PointEntries: 1})
+
+	_, err = f.r.DeleteTeam(f.ctx, wave)
+	assertErr(t, "delete a team with history", err, ErrTeamHasHistory)
+
+	closure, err := f.r.ArchiveTeam(f.ctx, wave, admin.ID)
+	f.must(err)
+	if len(closure.DeniedJoins) != 1 {
+		t.Fatalf("DeniedJoins = %+v", closure.DeniedJoins)
+	}
+	if j := closure.DeniedJoins[0]; j.ID != jr.ID || j.UserID != 30 || j.TeamName != "Волна" || j.Status != domain.JoinDenied || j.DecidedAt == nil {
+		t.Fatalf("denied join = %+v", j)
+	}
+	assertEqual(t, "RejectedRequests", closure.RejectedRequests, []PendingRequest{{
+		ID: pending, UserID: athlete.ID, Name: "kelly", Username: "kelly",
+		Amount: 5, Reason: "тренировка", TeamID: &wave, TeamName: "Волна",
+	}})
+	assertEqual(t, "history", f.history(athlete.ID), []string{
+		fmt.Sprintf("#%d rejected +5 by=boss comment=%s team=Волна requested=- corrects=-", pending, ArchivedRequestComment),
+	})
+
+	impact, err = f.r.GetTeamImpact(f.ctx, wave)
+	f.must(err)
+	assertEqual(t, "impact after archiving", *impact, domain.TeamImpact{Members: 1, PointEntries: 1})
+	teams, err := f.r.ListTeams(f.ctx)
+	f.must(err)
+	assertEqual(t, "ListTeams", teamNames(teams), []string{"Прибой"})
+	archived, err := f.r.ListArchivedTeams(f.ctx)
+	f.must(err)
+	assertEqual(t, "ListArchivedTeams", teamNames(archived), []string{"Волна"})
+	assertEqual(t, "teams of a member", len(f.userTeams(athlete.ID)), 0)
+	_, err = f.r.ArchiveTeam(f.ctx, wave, admin.ID)
+	assertErr(t, "archive twice", err, nil)
+	_, err = f.r.ArchiveTeam(f.ctx, 9999, admin.ID)
+	assertErr(t, "archive a missing team", err, nil)
+
+	f.must(f.r.RestoreTeam(f.ctx, wave))
+	assertErr(t, "restore twice", f.r.RestoreTeam(f.ctx, wave), nil)
+	assertEqual(t, "teams after restore", f.userTeams(athlete.ID), []string{"Волна"})
+	invites, err := f.r.ListTeamInvites(f.ctx, wave)
+	f.must(err)
+	assertEqual(t, "invites stay revoked", len(invites), 0)
+
+	moved, err := f.r.MoveTeamMembers(f.ctx, wave, surf)
+	f.must(err)
+	assertEqual(t, "moved", moved, 1)
+	assertEqual(t, "teams after move", f.userTeams(athlete.ID), []string{"Прибой"})
+	impact, err = f.r.GetTeamImpact(f.ctx, wave)
+	f.must(err)
+	assertEqual(t, "impact of the emptied team", *impact, domain.TeamImpact{FormerMembers: 1, PointEntries: 1})
+	_, err = f.r.MoveTeamMembers(f.ctx, wave, 9999)
+	assertErr(t, "move to a missing team", err, nil)
+
+	// пустую команду можно удалить; заявки в неё закрываются вместе с ней
+	foam := f.team("Пена", coach.ID)
+	late, err := f.r.CreateJoinRequest(f.ctx, &domain.User{ID: 31, Name: "late", Username: "late"}, foam)
+	f.must(err)
+	closure, err = f.r.DeleteTeam(f.ctx, foam)
+	f.must(err)
+	if len(closure.DeniedJoins) != 1 || closure.DeniedJoins[0].ID != late.ID || closure.DeniedJoins[0].TeamName != "Пена" {
+		t.Fatalf("DeleteTeam closure = %+v", closure)
+	}
+	assertEqual(t, "no rejected requests on delete", len(closure.RejectedRequests), 0)
+	_, err = f.r.GetTeamByID(f.ctx, foam)
+	assertErr(t, "GetTeamByID(deleted)", err, sql.ErrNoRows)
+	if j, err := f.r.GetPendingJoinRequest(f.ctx, 31); j != nil || err != nil {
+		t.Fatalf("join request survived the team: %+v, %v", j, err)
+	}
+	_, err = f.r.DeleteTeam(f.ctx, foam)
+	assertErr(t, "delete twice", err, sql.ErrNoRows)
+}
+
+func testFines(t *testing.T, f *fixture) {
+	athlete := f.user(10, "kelly", domain.RoleAthlete)
+	coach := f.user(20, "coach", domain.RoleCoach)
+	team := f.team("Волна", coach.ID)
+	f.join(athlete.ID, team)
+	f.give(coach.ID, "kelly", 5, "тренировка", &team)
+	f.give(coach.ID, "kelly", 3, "субботник", nil)
+
+	// всего 8 баллов, из них за команду 5
+	_, err := f.r.FinePoints(f.ctx, coach.ID, athlete.ID, 6, "опоздание", &team)
+	assertErr(t, "fine above the team score", err, ErrNegativeScore)
+	g, err := f.r.FinePoints(f.ctx, coach.ID, athlete.ID, 5, "опоздание", &team)
+	f.must(err)
+	assertEqual(t, "FinePoints", *g, Grant{PointID: g.PointID, UserID: athlete.ID, Amount: -5, Reason: "опоздание", TeamID: &team})
+	assertEqual(t, "score after fine", f.score(athlete.ID), 3)
+
+	_, err = f.r.FinePoints(f.ctx, coach.ID, athlete.ID, 4, "мусор", nil)
+	assertErr(t, "fine without a team above the total", err, ErrNegativeScore)
+	_, err = f.r.FinePoints(f.ctx, coach.ID, athlete.ID, 3, "мусор", nil)
+	f.must(err)
+	assertEqual(t, "score after fine without a team", f.score(athlete.ID), 0)
+
+	f.must(f.r.SetTeamAllowNegative(f.ctx, team, true))
+	got, err := f.r.GetTeamByID(f.ctx, team)
+	f.must(err)
+	assertEqual(t, "AllowNegative", got.AllowNegative, true)
+	_, err = f.r.FinePoints(f.ctx, coach.ID, athlete.ID, 2, "грубость", &team)
+	f.must(err)
+	assertEqual(t, "negative score", f.score(athlete.ID), -2)
+
+	ranking, err := f.r.GetRankingByTeam(f.ctx, team)
+	f.must(err)
+	assertEqual(t, "team ranking", scores(ranking), []string{"kelly:-2"})
+
+	records, err := f.r.GetUserHistory(f.ctx, athlete.ID)
+	f.must(err)
+	if !records[0].IsFine() {
+		t.Fatalf("latest entry is not a fine: %+v", records[0])
+	}
+
+	assertErr(t, "SetTeamAllowNegative(missing)", f.r.SetTeamAllowNegative(f.ctx, 9999, true), nil)
+	_, err = f.r.FinePoints(f.ctx, coach.ID, athlete.ID, 1, "чужая команда", ptr(9999))
+	assertErr(t, "fine for a missing team", err, sql.ErrNoRows)
+	_, err = f.r.FinePoints(f.ctx, coach.ID, coach.ID, 1, "тренер", nil)
+	assertErr(t, "fine a coach", err, sql.ErrNoRows)
+}
+
+func testCorrections(t *testing.T, f *fixture) {
+	athlete := f.user(10, "kelly", domain.RoleAthlete)
+	coach := f.user(20, "coach", domain.RoleCoach)
+	team := f.team("Волна", coach.ID)
+	f.join(athlete.ID, team)
+
+	_, err := f.r.GivePoints(f.ctx, coach.ID, "coach", 5, "не спортсмен", nil)
+	assertErr(t, "GivePoints to a coach", err, sql.ErrNoRows)
+	_, err = f.r.GivePoints(f.ctx, coach.ID, "nobody", 5, "нет такого", nil)
+	assertErr(t, "GivePoints to nobody", err, sql.ErrNoRows)
+
+	g := f.give(coach.ID, "KELLY", 10, "соревнования", &team)
+	want := Grant{PointID: g.PointID, UserID: athlete.ID, Amount: 10, Reason: "соревнования", TeamID: &team}
+	assertEqual(t, "GivePoints", *g, want)
+	got, err := f.r.GetGrant(f.ctx, g.PointID)
+	f.must(err)
+	assertEqual(t, "GetGrant", *got, want)
+
+	c, err := f.r.CorrectGrant(f.ctx, coach.ID, g.PointID, 3, "ошибка в протоколе")
+	f.must(err)
+	assertEqual(t, "CorrectGrant", *c, Correction{
+		Grant:      Grant{PointID: c.PointID, UserID: athlete.ID, Amount: -7, Reason: "соревнования", TeamID: &team},
+		OriginalID: g.PointID, OldAmount: 10, NewAmount: 3,
+	})
+	got, err = f.r.GetGrant(f.ctx, g.PointID)
+	f.must(err)
+	assertEqual(t, "corrected amount", got.Amount, 3)
+
+	_, err = f.r.CorrectGrant(f.ctx, coach.ID, g.PointID, 3, "")
+	assertErr(t, "correct to the same amount", err, nil)
+	_, err = f.r.GetGrant(f.ctx, c.PointID)
+	assertErr(t, "GetGrant(correction)", err, sql.ErrNoRows)
+	_, err = f.r.CorrectGrant(f.ctx, coach.ID, c.PointID, 1, "")
+	assertErr(t, "correct a correction", err, sql.ErrNoRows)
+
+	revoke, err := f.r.CorrectGrant(f.ctx, coach.ID, g.PointID, 0, "")
+	f.must(err)
+	assertEqual(t, "revoke", [3]int{revoke.OldAmount, revoke.NewAmount, revoke.Amount}, [3]int{3, 0, -3})
+	assertEqual(t, "score after revoke", f.score(athlete.ID), 0)
+
+	ranking, err := f.r.GetRankingByTeam(f.ctx, team)
+	f.must(err)
+	assertEqual(t, "team ranking", scores(ranking), []string{"kelly:0"})
+
+	// начисление из запроса исправляется так же и не тянет за собой запрошенную сумму
+	id := f.request(athlete.ID, 5, "тренировка", nil)
+	_, err = f.r.GetGrant(f.ctx, id)
+	assertErr(t, "GetGrant(pending)", err, sql.ErrNoRows)
+	_, err = f.r.ApproveRequest(f.ctx, id, coach.ID, ptr(4), "хватит")
+	f.must(err)
+	got, err = f.r.GetGrant(f.ctx, id)
+	f.must(err)
+	assertEqual(t, "GetGrant(approved request)", *got, Grant{PointID: id, UserID: athlete.ID, Amount: 4, Reason: "тренировка"})
+
+	assertEqual(t, "history", f.history(athlete.ID), []string{
+		fmt.Sprintf("#%d approved +4 by=coach comment=хватит team=- requested=5 corrects=-", id),
+		fmt.Sprintf("#%d approved -3 by=coach comment=- team=Волна requested=- corrects=%d", revoke.PointID, g.PointID),
+		fmt.Sprintf("#%d approved -7 by=coach comment=ошибка в протоколе team=Волна requested=- corrects=%d", c.PointID, g.PointID),
+		fmt.Sprintf("#%d approved +10 by=coach comment=- team=Волна requested=- corrects=-", g.PointID),
+	})
+}
+
+func testAmountOverride(t *testing.T, f *fixture) {
+	athlete := f.user(10, "kelly", domain.RoleAthlete)
+	coach := f.user(20, "coach", domain.RoleCoach)
+
+	edited := f.request(athlete.ID, 10, "доска", nil)
+	f.must(f.r.UpdatePendingAmount(f.ctx, edited, 10)) // та же сумма: запоминать нечего
+	f.must(f.r.UpdatePendingAmount(f.ctx, edited, 7))
+	f.must(f.r.UpdatePendingAmount(f.ctx, edited, 8))
+	req, err := f.r.GetPendingRequest(f.ctx, edited)
+	f.must(err)
+	assertEqual(t, "edited amount", req.Amount, 8)
+
+	g, err := f.r.ApproveRequest(f.ctx, edited, coach.ID, nil, "")
+	f.must(err)
+	assertEqual(t, "approve edited", *g, Grant{PointID: edited, UserID: athlete.ID, Amount: 8, Reason: "доска", Requested: ptr(10)})
+	assertErr(t, "edit approved", f.r.UpdatePendingAmount(f.ctx, edited, 1), nil)
+
+	overridden := f.request(athlete.ID, 4, "гидрик", nil)
+	g, err = f.r.ApproveRequest(f.ctx, overridden, coach.ID, ptr(6), "за старание")
+	f.must(err)
+	assertEqual(t, "approve with override", *g, Grant{PointID: overridden, UserID: athlete.ID, Amount: 6, Reason: "гидрик",
+		Requested: ptr(4), Comment: "за старание"})
+
+	same := f.request(athlete.ID, 5, "лайкра", nil)
+	g, err = f.r.ApproveRequest(f.ctx, same, coach.ID, ptr(5), "")
+	f.must(err)
+	assertEqual(t, "approve with the requested amount", *g, Grant{PointID: same, UserID: athlete.ID, Amount: 5, Reason: "лайкра"})
+
+	assertEqual(t, "score", f.score(athlete.ID), 19)
+	assertEqual(t, "history", f.history(athlete.ID), []string{
+		fmt.Sprintf("#%d approved +5 by=coach comment=- team=- requested=- corrects=-", same),
+		fmt.Sprintf("#%d approved +6 by=coach comment=за старание team=- requested=4 corrects=-", overridden),
+		fmt.Sprintf("#%d approved +8 by=coach comment=- team=- requested=10 corrects=-", edited),
+	})
+}
+
+func testActivities(t *testing.T, f *fixture) {
+	coach := f.user(20, "coach", domain.RoleCoach)
+	wave := f.team("Волна", coach.ID)
+	surf := f.team("Прибой", coach.ID)
+
+	add := func(teamID int, name string, amount int) (int, error) {
+		return f.r.AddActivity(f.ctx, &domain.Activity{TeamID: teamID, Name: name, Amount: amount, CreatedBy: &coach.ID})
+	}
+	yoga, err := add(wave, "Yoga", 5)
+	f.must(err)
+	_, err = add(wave, "Contest", 20)
+	f.must(err)
+	_, err = add(wave, "yoga", 3)
+	assertErr(t, "duplicate name in another case", err, ErrActivityExists)
+	_, err = add(surf, "Yoga", 4)
+	f.must(err)
+
+	list, err := f.r.ListActivities(f.ctx, wave)
+	f.must(err)
+	var lines []string
+	for _, a := range list {
+		lines = append(lines, fmt.Sprintf("%s:%d", a.Name, a.Amount))
+	}
+	assertEqual(t, "ListActivities", lines, []string{"Yoga:5", "Contest:20"})
+
+	got, err := f.r.GetActivity(f.ctx, yoga)
+	f.must(err)
+	if got.ID != yoga || got.TeamID != wave || got.Name != "Yoga" || got.Amount != 5 ||
+		got.CreatedBy == nil || *got.CreatedBy != coach.ID || got.CreatedAt.IsZero() {
+		t.Fatalf("GetActivity = %+v", got)
+	}
+
+	f.must(f.r.RemoveActivity(f.ctx, yoga))
+	assertErr(t, "remove twice", f.r.RemoveActivity(f.ctx, yoga), nil)
+	_, err = f.r.GetActivity(f.ctx, yoga)
+	assertErr(t, "GetActivity(removed)", err, sql.ErrNoRows)
+	list, err = f.r.ListActivities(f.ctx, wave)
+	f.must(err)
+	assertEqual(t, "activities after removal", len(list), 1)
+
+	f.must(f.r.SetTeamFreeRequests(f.ctx, wave, false))
+	team, err := f.r.GetTeamByID(f.ctx, wave)
+	f.must(err)
+	assertEqual(t, "FreeRequests", team.FreeRequests, false)
+	assertErr(t, "SetTeamFreeRequests(missing)", f.r.SetTeamFreeRequests(f.ctx, 9999, true), nil)
+}
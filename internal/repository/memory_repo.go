@@ -0,0 +1,1510 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"surf_bot/internal/domain"
+)
+
+// MemoryRepository keeps all data in memory. It follows the same rules as
+// UserRepository and is meant for tests and local runs without PostgreSQL.
+type MemoryRepository struct {
+	mu sync.Mutex
+
+	users  map[int64]*memUser
+	scores map[int64]int
+	teams  map[int]domain.Team
+	points []*memPoint
+
+	memberships []*memMembership
+
+	teamCoaches map[int]map[int64]bool // team_id -> тренеры команды
+	roleChanges []domain.RoleChange
+	invites     map[string]*memInvite // token_hash -> приглашение тренера
+	teamInvites []*domain.TeamInvite
+	joins       []*domain.JoinRequest
+	activities  []domain.Activity
+
+	nextTeamID     int
+	nextPointID    int
+	nextActivityID int
+}
+
+var _ Repository = (*MemoryRepository)(nil)
+
+type memUser struct {
+	domain.User
+	NotifyMode domain.NotifyMode
+}
+
+type memMembership struct {
+	UserID   int64
+	TeamID   int
+	JoinedAt time.Time
+	LeftAt   *time.Time
+}
+
+type memInvite struct {
+	CreatedBy int64
+	ExpiresAt time.Time
+	UsedBy    *int64
+}
+
+type memPoint struct {
+	ID         int
+	FromID     int64
+	TeamID     *int
+	Amount     int
+	Reason     string
+	Status     domain.PointStatus
+	Requested  *int
+	CorrectsID *int
+	CreatedAt  time.Time
+	DecidedAt  *time.Time
+	DecidedBy  *int64
+	Comment    string
+}
+
+// decide moves a point entry out of pending
+func (p *memPoint) decide(status domain.PointStatus, by *int64, comment string) {
+	now := time.Now()
+	p.Status = status
+	p.DecidedAt = &now
+	p.DecidedBy = by
+	p.Comment = comment
+}
+
+func (p *memPoint) grant() *Grant {
+	return &Grant{PointID: p.ID, UserID: p.FromID, Amount: p.Amount, Reason: p.Reason, TeamID: p.TeamID,
+		Requested: p.Requested, Comment: p.Comment}
+}
+
+// setAmount changes the amount of a pending request, remembering what the athlete asked for
+func (p *memPoint) setAmount(amount int) {
+	if p.Requested == nil && amount != p.Amount {
+		requested := p.Amount
+		p.Requested = &requested
+	}
+	p.Amount = amount
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		users:  make(map[int64]*memUser),
+		scores: make(map[int64]int),
+		teams:  make(map[int]domain.Team),
+
+		teamCoaches: make(map[int]map[int64]bool),
+		invites:     make(map[string]*memInvite),
+	}
+}
+
+func (r *MemoryRepository) GetUserByID(ctx context.Context, id int64) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	user := u.User
+	return &user, nil
+}
+
+func (r *MemoryRepository) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := r.findByUsername(username)
+	if u == nil {
+		return nil, sql.ErrNoRows
+	}
+	user := u.User
+	return &user, nil
+}
+
+func (r *MemoryRepository) findByUsername(username string) *memUser {
+	for _, u := range r.users {
+		if strings.EqualFold(u.Username, username) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) RegisterUser(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[user.ID]; ok {
+		return nil // already exists
+	}
+
+	r.users[user.ID] = &memUser{User: *user, NotifyMode: domain.NotifyInstant}
+	if user.Role == domain.RoleAthlete {
+		r.scores[user.ID] = 0
+	}
+	return nil
+}
+
+func (r *MemoryRepository) RegisterAdmin(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[user.ID]; ok {
+		return nil // already exists
+	}
+
+	admin := *user
+	admin.Role = domain.RoleAdmin
+	r.users[user.ID] = &memUser{User: admin, NotifyMode: domain.NotifyInstant}
+	r.roleChanges = append(r.roleChanges, domain.RoleChange{
+		ID:        len(r.roleChanges) + 1,
+		UserID:    user.ID,
+		Username:  user.Username,
+		NewRole:   domain.RoleAdmin,
+		CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+func (r *MemoryRepository) ListAthletes(ctx context.Context) ([]domain.AthleteShort, error) {
+	return r.ListAthletesByTeam(ctx, nil)
+}
+
+func (r *MemoryRepository) ListAthletesByTeam(ctx context.Context, teamID *int) ([]domain.AthleteShort, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var athletes []domain.AthleteShort
+	for _, u := range r.users {
+		if u.Role != domain.RoleAthlete {
+			continue
+		}
+		if teamID != nil && !r.isMember(u.ID, *teamID) {
+			continue
+		}
+		athletes = append(athletes, domain.AthleteShort{ID: u.ID, Name: u.Name, Username: u.Username})
+	}
+	sort.Slice(athletes, func(i, j int) bool { return athletes[i].Name < athletes[j].Name })
+	return athletes, nil
+}
+
+func (r *MemoryRepository) ListAthletesByCoach(ctx context.Context, coachID int64) ([]domain.AthleteShort, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var athletes []domain.AthleteShort
+	for _, u := range r.users {
+		if u.Role == domain.RoleAthlete && r.inCoachScope(coachID, u) {
+			athletes = append(athletes, domain.AthleteShort{ID: u.ID, Name: u.Name, Username: u.Username})
+		}
+	}
+	sort.Slice(athletes, func(i, j int) bool { return athletes[i].Name < athletes[j].Name })
+	return athletes, nil
+}
+
+func (r *MemoryRepository) GetTeamByName(ctx context.Context, name string) (*domain.Team, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.teams {
+		if t.Name == name {
+			team := t
+			return &team, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *MemoryRepository) GetTeamByID(ctx context.Context, id int) (*domain.Team, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	team, ok := r.teams[id]
+	if !ok {
+		return nil, fmt.Errorf("команда с ID %d не найдена: %w", id, sql.ErrNoRows)
+	}
+	return &team, nil
+}
+
+func (r *MemoryRepository) CreateTeam(ctx context.Context, name string, coachID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.teams {
+		if t.Name == name {
+			return fmt.Errorf("команда %q уже существует", name)
+		}
+	}
+	r.nextTeamID++
+	r.teams[r.nextTeamID] = domain.Team{ID: r.nextTeamID, Name: name, FreeRequests: true}
+	r.teamCoaches[r.nextTeamID] = map[int64]bool{coachID: true}
+	return nil
+}
+
+func (r *MemoryRepository) AddTeamCoach(ctx context.Context, teamID int, coachID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teams[teamID]; !ok {
+		return fmt.Errorf("не удалось добавить тренера в команду: команда %d не найдена", teamID)
+	}
+	if r.teamCoaches[teamID] == nil {
+		r.teamCoaches[teamID] = make(map[int64]bool)
+	}
+	r.teamCoaches[teamID][coachID] = true
+	return nil
+}
+
+func (r *MemoryRepository) RemoveTeamCoach(ctx context.Context, teamID int, coachID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	coaches := r.teamCoaches[teamID]
+	if !coaches[coachID] {
+		return fmt.Errorf("тренер не ведёт команду #%d", teamID)
+	}
+	if len(coaches) == 1 {
+		return fmt.Errorf("нельзя оставить команду #%d без тренера", teamID)
+	}
+	delete(coaches, coachID)
+	return nil
+}
+
+func (r *MemoryRepository) ListCoachTeams(ctx context.Context, coachID int64) ([]domain.Team, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var teams []domain.Team
+	for id, t := range r.teams {
+		if r.teamCoaches[id][coachID] && t.ArchivedAt == nil {
+			teams = append(teams, t)
+		}
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Name < teams[j].Name })
+	return teams, nil
+}
+
+func (r *MemoryRepository) CoachesTeam(ctx context.Context, coachID int64, teamID int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.teamCoaches[teamID][coachID], nil
+}
+
+func (r *MemoryRepository) CoachesAthlete(ctx context.Context, coachID int64, athleteID int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[athleteID]
+	if !ok {
+		return false, sql.ErrNoRows
+	}
+	return r.inCoachScope(coachID, u), nil
+}
+
+// inCoachScope mirrors coachScope: the athlete has no team or is in one of the coach's teams
+func (r *MemoryRepository) inCoachScope(coachID int64, u *memUser) bool {
+	teams := r.activeTeams(u.ID)
+	if len(teams) == 0 {
+		return true
+	}
+	for _, id := range teams {
+		if r.teamCoaches[id][coachID] {
+			return true
+		}
+	}
+	return false
+}
+
+// requestInScope mirrors requestScope
+func (r *MemoryRepository) requestInScope(coachID int64, p *memPoint) bool {
+	if p.TeamID != nil {
+		return r.teamCoaches[*p.TeamID][coachID]
+	}
+	return r.inCoachScope(coachID, r.users[p.FromID])
+}
+
+// activeTeams returns IDs of the teams the user is currently in, in joining order
+func (r *MemoryRepository) activeTeams(userID int64) []int {
+	var ids []int
+	for _, m := range r.memberships {
+		if m.UserID == userID && m.LeftAt == nil {
+			ids = append(ids, m.TeamID)
+		}
+	}
+	return ids
+}
+
+func (r *MemoryRepository) isMember(userID int64, teamID int) bool {
+	return slices.Contains(r.activeTeams(userID), teamID)
+}
+
+func (r *MemoryRepository) DeleteTeam(ctx context.Context, teamID int) (*TeamClosure, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teams[teamID]; !ok {
+		return nil, fmt.Errorf("команда с ID %d не найдена: %w", teamID, sql.ErrNoRows)
+	}
+	if r.teamImpact(teamID).HasHistory() {
+		return nil, ErrTeamHasHistory
+	}
+
+	var closure TeamClosure
+	for _, j := range r.joins {
+		if j.TeamID == teamID && j.Status == domain.JoinPending {
+			closure.DeniedJoins = append(closure.DeniedJoins, *j)
+		}
+	}
+	delete(r.teams, teamID)
+	delete(r.teamCoaches, teamID)
+	// как ON DELETE CASCADE в базе
+	r.teamInvites = slices.DeleteFunc(r.teamInvites, func(inv *domain.TeamInvite) bool { return inv.TeamID == teamID })
+	r.joins = slices.DeleteFunc(r.joins, func(j *domain.JoinRequest) bool { return j.TeamID == teamID })
+	r.activities = slices.DeleteFunc(r.activities, func(a domain.Activity) bool { return a.TeamID == teamID })
+	return &closure, nil
+}
+
+func (r *MemoryRepository) GetTeamImpact(ctx context.Context, teamID int) (*domain.TeamImpact, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	impact := r.teamImpact(teamID)
+	return &impact, nil
+}
+
+func (r *MemoryRepository) teamImpact(teamID int) domain.TeamImpact {
+	var impact domain.TeamImpact
+	current := r.teamMembers(teamID)
+	former := map[int64]bool{}
+	for _, m := range r.memberships {
+		if m.TeamID == teamID && m.LeftAt != nil && !slices.Contains(current, m.UserID) {
+			former[m.UserID] = true
+		}
+	}
+	impact.Members = len(current)
+	impact.FormerMembers = len(former)
+
+	for _, p := range r.points {
+		if p.TeamID != nil && *p.TeamID == teamID {
+			impact.PointEntries++
+			if p.Status == domain.PointPending {
+				impact.PendingRequests++
+			}
+		}
+	}
+	for _, j := range r.joins {
+		if j.TeamID == teamID && j.Status == domain.JoinPending {
+			impact.PendingJoins++
+		}
+	}
+	now := time.Now()
+	for _, inv := range r.teamInvites {
+		if inv.TeamID == teamID && inv.Active(now) {
+			impact.ActiveInvites++
+		}
+	}
+	return impact
+}
+
+func (r *MemoryRepository) ArchiveTeam(ctx context.Context, teamID int, adminID int64) (*TeamClosure, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	team, ok := r.teams[teamID]
+	if !ok || team.ArchivedAt != nil {
+		return nil, fmt.Errorf("команда #%d не найдена или уже в архиве", teamID)
+	}
+	now := time.Now()
+	team.ArchivedAt = &now
+	r.teams[teamID] = team
+
+	for _, inv := range r.teamInvites {
+		if inv.TeamID == teamID && inv.RevokedAt == nil {
+			inv.RevokedAt = &now
+		}
+	}
+
+	var closure TeamClosure
+	for _, j := range r.joins {
+		if j.TeamID == teamID && j.Status == domain.JoinPending {
+			j.Status = domain.JoinDenied
+			j.DecidedAt = &now
+			closure.DeniedJoins = append(closure.DeniedJoins, *j)
+		}
+	}
+	for _, p := range r.points {
+		if p.TeamID != nil && *p.TeamID == teamID && p.Status == domain.PointPending {
+			p.decide(domain.PointRejected, &adminID, ArchivedRequestComment)
+			closure.RejectedRequests = append(closure.RejectedRequests, r.toPendingRequest(p))
+		}
+	}
+	return &closure, nil
+}
+
+func (r *MemoryRepository) RestoreTeam(ctx context.Context, teamID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	team, ok := r.teams[teamID]
+	if !ok || team.ArchivedAt == nil {
+		return fmt.Errorf("команда #%d не найдена в архиве", teamID)
+	}
+	team.ArchivedAt = nil
+	r.teams[teamID] = team
+	return nil
+}
+
+func (r *MemoryRepository) MoveTeamMembers(ctx context.Context, fromTeamID, toTeamID int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teams[toTeamID]; !ok {
+		return 0, fmt.Errorf("не удалось перевести спортсменов: команда %d не найдена", toTeamID)
+	}
+	moved := r.teamMembers(fromTeamID)
+	now := time.Now()
+	for _, m := range r.memberships {
+		if m.TeamID == fromTeamID && m.LeftAt == nil {
+			m.LeftAt = &now
+		}
+	}
+	for _, id := range moved {
+		if !r.isMember(id, toTeamID) {
+			r.memberships = append(r.memberships, &memMembership{UserID: id, TeamID: toTeamID, JoinedAt: now})
+		}
+	}
+	return len(moved), nil
+}
+
+func (r *MemoryRepository) SetTeamAllowNegative(ctx context.Context, teamID int, allow bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	team, ok := r.teams[teamID]
+	if !ok {
+		return fmt.Errorf("команда #%d не найдена", teamID)
+	}
+	team.AllowNegative = allow
+	r.teams[teamID] = team
+	return nil
+}
+
+func (r *MemoryRepository) SetTeamFreeRequests(ctx context.Context, teamID int, allow bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	team, ok := r.teams[teamID]
+	if !ok {
+		return fmt.Errorf("команда #%d не найдена", teamID)
+	}
+	team.FreeRequests = allow
+	r.teams[teamID] = team
+	return nil
+}
+
+func (r *MemoryRepository) ListTeams(ctx context.Context) ([]domain.Team, error) {
+	teams := r.listTeams(false)
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Name < teams[j].Name })
+	return teams, nil
+}
+
+func (r *MemoryRepository) ListArchivedTeams(ctx context.Context) ([]domain.Team, error) {
+	teams := r.listTeams(true)
+	sort.Slice(teams, func(i, j int) bool { return teams[i].ArchivedAt.After(*teams[j].ArchivedAt) })
+	return teams, nil
+}
+
+func (r *MemoryRepository) listTeams(archived bool) []domain.Team {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	teams := make([]domain.Team, 0, len(r.teams))
+	for _, t := range r.teams {
+		if (t.ArchivedAt != nil) == archived {
+			teams = append(teams, t)
+		}
+	}
+	return teams
+}
+
+func (r *MemoryRepository) AssignUserToTeam(ctx context.Context, userID int64, teamID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teams[teamID]; !ok {
+		return fmt.Errorf("не удалось назначить команду пользователю: команда %d не найдена", teamID)
+	}
+	if _, ok := r.users[userID]; ok && !r.isMember(userID, teamID) {
+		r.memberships = append(r.memberships, &memMembership{UserID: userID, TeamID: teamID, JoinedAt: time.Now()})
+	}
+	return nil
+}
+
+func (r *MemoryRepository) RemoveUserFromTeam(ctx context.Context, userID int64, teamID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range r.memberships {
+		if m.UserID == userID && m.TeamID == teamID && m.LeftAt == nil {
+			now := time.Now()
+			m.LeftAt = &now
+			return nil
+		}
+	}
+	return fmt.Errorf("пользователь не состоит в команде #%d", teamID)
+}
+
+func (r *MemoryRepository) ListUserTeams(ctx context.Context, userID int64) ([]domain.Team, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var teams []domain.Team
+	for _, id := range r.activeTeams(userID) {
+		if t := r.teams[id]; t.ArchivedAt == nil {
+			teams = append(teams, t)
+		}
+	}
+	return teams, nil
+}
+
+func (r *MemoryRepository) ListMemberships(ctx context.Context, userID int64) ([]domain.Membership, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var memberships []domain.Membership
+	for _, m := range r.memberships {
+		if m.UserID == userID {
+			memberships = append(memberships, domain.Membership{
+				TeamID:   m.TeamID,
+				TeamName: r.teams[m.TeamID].Name,
+				JoinedAt: m.JoinedAt,
+				LeftAt:   m.LeftAt,
+			})
+		}
+	}
+	sort.SliceStable(memberships, func(i, j int) bool {
+		a, b := memberships[i], memberships[j]
+		if (a.LeftAt == nil) != (b.LeftAt == nil) {
+			return a.LeftAt == nil
+		}
+		return a.JoinedAt.After(b.JoinedAt)
+	})
+	return memberships, nil
+}
+
+func (r *MemoryRepository) CreatePendingRequest(ctx context.Context, fromID int64, amount int, reason string, teamID *int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[fromID]; !ok {
+		return 0, fmt.Errorf("failed to insert point request: пользователь %d не найден", fromID)
+	}
+	return r.addPoint(fromID, amount, reason, teamID).ID, nil
+}
+
+func (r *MemoryRepository) ListRequestRecipients(ctx context.Context, athleteID int64, teamID *int) ([]Recipient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	athlete, ok := r.users[athleteID]
+	if !ok {
+		return nil, nil
+	}
+
+	var recipients []Recipient
+	for _, u := range r.users {
+		if !u.Role.IsStaff() || u.NotifyMode == domain.NotifyOff || u.ID == athleteID {
+			continue
+		}
+		if (teamID != nil && r.teamCoaches[*teamID][u.ID]) || (teamID == nil && r.inCoachScope(u.ID, athlete)) {
+			recipients = append(recipients, Recipient{ID: u.ID, Mode: u.NotifyMode})
+		}
+	}
+	sort.Slice(recipients, func(i, j int) bool { return recipients[i].ID < recipients[j].ID })
+	return recipients, nil
+}
+
+func (r *MemoryRepository) GetNotifyMode(ctx context.Context, userID int64) (domain.NotifyMode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return "", fmt.Errorf("не удалось получить настройки уведомлений: %w", sql.ErrNoRows)
+	}
+	return u.NotifyMode, nil
+}
+
+func (r *MemoryRepository) SetNotifyMode(ctx context.Context, userID int64, mode domain.NotifyMode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if u, ok := r.users[userID]; ok {
+		u.NotifyMode = mode
+	}
+	return nil
+}
+
+// addPoint stores a new pending entry and returns it
+func (r *MemoryRepository) addPoint(fromID int64, amount int, reason string, teamID *int) *memPoint {
+	r.nextPointID++
+	p := &memPoint{
+		ID:        r.nextPointID,
+		FromID:    fromID,
+		TeamID:    teamID,
+		Amount:    amount,
+		Reason:    reason,
+		Status:    domain.PointPending,
+		CreatedAt: time.Now(),
+	}
+	r.points = append(r.points, p)
+	return p
+}
+
+// pendingPoint returns a pending request by ID or nil
+func (r *MemoryRepository) pendingPoint(id int) *memPoint {
+	for _, p := range r.points {
+		if p.ID == id && p.Status == domain.PointPending {
+			return p
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) toPendingRequest(p *memPoint) PendingRequest {
+	u := r.users[p.FromID]
+	req := PendingRequest{
+		ID:       p.ID,
+		UserID:   p.FromID,
+		Name:     u.Name,
+		Username: u.Username,
+		Amount:   p.Amount,
+		Reason:   p.Reason,
+		TeamID:   p.TeamID,
+	}
+	if p.TeamID != nil {
+		req.TeamName = r.teams[*p.TeamID].Name
+	}
+	return req
+}
+
+func (r *MemoryRepository) GetPendingRequest(ctx context.Context, id int) (*PendingRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.pendingPoint(id)
+	if p == nil {
+		return nil, fmt.Errorf("запрос не найден или уже обработан: %w", sql.ErrNoRows)
+	}
+	req := r.toPendingRequest(p)
+	return &req, nil
+}
+
+func (r *MemoryRepository) GetPendingRequests(ctx context.Context) ([]PendingRequest, error) {
+	return r.GetPendingRequestsByTeam(ctx, nil)
+}
+
+func (r *MemoryRepository) GetPendingRequestsByTeam(ctx context.Context, teamID *int) ([]PendingRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var requests []PendingRequest
+	for _, p := range r.points {
+		if p.Status != domain.PointPending {
+			continue
+		}
+		if teamID != nil && (p.TeamID == nil || *p.TeamID != *teamID) {
+			continue
+		}
+		requests = append(requests, r.toPendingRequest(p))
+	}
+	return requests, nil
+}
+
+func (r *MemoryRepository) GetPendingRequestsByCoach(ctx context.Context, coachID int64) ([]PendingRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var requests []PendingRequest
+	for _, p := range r.points {
+		if p.Status == domain.PointPending && r.requestInScope(coachID, p) {
+			requests = append(requests, r.toPendingRequest(p))
+		}
+	}
+	return requests, nil
+}
+
+func (r *MemoryRepository) UpdatePendingAmount(ctx context.Context, id int, amount int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.pendingPoint(id)
+	if p == nil {
+		return fmt.Errorf("запрос не найден или уже обработан")
+	}
+	p.setAmount(amount)
+	return nil
+}
+
+func (r *MemoryRepository) ApproveRequest(ctx context.Context, id int, coachID int64, amount *int, comment string) (*Grant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.pendingPoint(id)
+	if p == nil {
+		return nil, fmt.Errorf("запрос не найден или уже обработан: %w", sql.ErrNoRows)
+	}
+	if amount != nil {
+		p.setAmount(*amount)
+	}
+	if _, ok := r.scores[p.FromID]; ok {
+		r.scores[p.FromID] += p.Amount
+	}
+	p.decide(domain.PointApproved, &coachID, comment)
+	return p.grant(), nil
+}
+
+func (r *MemoryRepository) RejectRequest(ctx context.Context, id int, coachID int64, comment string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.pendingPoint(id)
+	if p == nil {
+		return 0, fmt.Errorf("запрос не найден или уже обработан: %w", sql.ErrNoRows)
+	}
+	p.decide(domain.PointRejected, &coachID, comment)
+	return p.FromID, nil
+}
+
+func (r *MemoryRepository) CancelRequest(ctx context.Context, id int, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.pendingPoint(id)
+	if p == nil || p.FromID != userID {
+		return fmt.Errorf("запрос не найден или уже обработан")
+	}
+	p.decide(domain.PointCancelled, &userID, "")
+	return nil
+}
+
+func (r *MemoryRepository) ExpirePendingRequests(ctx context.Context, maxAge time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	deadline := time.Now().Add(-maxAge)
+	for _, p := range r.points {
+		if p.Status == domain.PointPending && p.CreatedAt.Before(deadline) {
+			p.decide(domain.PointExpired, nil, "")
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *MemoryRepository) GivePoints(ctx context.Context, coachID int64, toUsername string, amount int, reason string, teamID *int) (*Grant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := r.findByUsername(toUsername)
+	if u == nil || u.Role != domain.RoleAthlete {
+		return nil, fmt.Errorf("спортсмен с именем %s не найден: %w", toUsername, sql.ErrNoRows)
+	}
+
+	if _, ok := r.scores[u.ID]; ok {
+		r.scores[u.ID] += amount
+	}
+	p := r.addPoint(u.ID, amount, reason, teamID)
+	p.decide(domain.PointApproved, &coachID, "")
+	return p.grant(), nil
+}
+
+func (r *MemoryRepository) FinePoints(ctx context.Context, coachID int64, athleteID int64, amount int, reason string, teamID *int) (*Grant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	score, ok := r.scores[athleteID]
+	if !ok {
+		return nil, fmt.Errorf("не удалось получить счёт спортсмена: %w", sql.ErrNoRows)
+	}
+
+	allowNegative := false
+	available := score
+	if teamID != nil {
+		team, ok := r.teams[*teamID]
+		if !ok {
+			return nil, fmt.Errorf("не удалось получить команду: %w", sql.ErrNoRows)
+		}
+		allowNegative = team.AllowNegative
+		available = min(score, r.teamScore(athleteID, *teamID))
+	}
+	if !allowNegative && available < amount {
+		return nil, fmt.Errorf("%w: доступно %d баллов", ErrNegativeScore, max(available, 0))
+	}
+
+	r.scores[athleteID] -= amount
+	p := r.addPoint(athleteID, -amount, reason, teamID)
+	p.decide(domain.PointApproved, &coachID, "")
+	return p.grant(), nil
+}
+
+// teamScore sums the athlete's approved points earned for the team while being its member
+func (r *MemoryRepository) teamScore(userID int64, teamID int) int {
+	score := 0
+	for _, p := range r.points {
+		if p.FromID == userID && p.Status == domain.PointApproved && p.TeamID != nil && *p.TeamID == teamID &&
+			r.earnedInTeam(p) {
+			score += p.Amount
+		}
+	}
+	return score
+}
+
+// earnedInTeam reports whether the point was recorded while its author was a member of its team;
+// a correction counts when the point it corrects does
+func (r *MemoryRepository) earnedInTeam(p *memPoint) bool {
+	at := p.CreatedAt
+	if p.CorrectsID != nil {
+		for _, o := range r.points {
+			if o.ID == *p.CorrectsID {
+				at = o.CreatedAt
+				break
+			}
+		}
+	}
+	for _, m := range r.memberships {
+		if m.UserID == p.FromID && m.TeamID == *p.TeamID && !at.Before(m.JoinedAt) && (m.LeftAt == nil || at.Before(*m.LeftAt)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryRepository) GetGrant(ctx context.Context, pointID int) (*Grant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	g := r.correctableGrant(pointID)
+	if g == nil {
+		return nil, fmt.Errorf("начисление #%d не найдено: %w", pointID, sql.ErrNoRows)
+	}
+	return g, nil
+}
+
+// correctableGrant mirrors grantColumns: an approved original entry with its corrections applied
+func (r *MemoryRepository) correctableGrant(pointID int) *Grant {
+	var g *Grant
+	for _, p := range r.points {
+		if p.ID == pointID && p.Status == domain.PointApproved && p.CorrectsID == nil {
+			g = &Grant{PointID: p.ID, UserID: p.FromID, Amount: p.Amount, Reason: p.Reason, TeamID: p.TeamID}
+		}
+	}
+	if g == nil {
+		return nil
+	}
+	for _, p := range r.points {
+		if p.CorrectsID != nil && *p.CorrectsID == pointID {
+			g.Amount += p.Amount
+		}
+	}
+	return g
+}
+
+func (r *MemoryRepository) CorrectGrant(ctx context.Context, coachID int64, pointID int, newAmount int, comment string) (*Correction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	orig := r.correctableGrant(pointID)
+	if orig == nil {
+		return nil, fmt.Errorf("начисление #%d не найдено: %w", pointID, sql.ErrNoRows)
+	}
+	if orig.Amount == newAmount {
+		return nil, fmt.Errorf("в начислении #%d уже %d баллов", pointID, newAmount)
+	}
+
+	delta := newAmount - orig.Amount
+	if _, ok := r.scores[orig.UserID]; ok {
+		r.scores[orig.UserID] += delta
+	}
+	p := r.addPoint(orig.UserID, delta, orig.Reason, orig.TeamID)
+	p.CorrectsID = &pointID
+	p.decide(domain.PointApproved, &coachID, comment)
+	// как в базе: комментарий исправления хранится в истории, но не в возвращаемой записи
+	g := Grant{PointID: p.ID, UserID: p.FromID, Amount: p.Amount, Reason: p.Reason, TeamID: p.TeamID}
+	return &Correction{Grant: g, OriginalID: pointID, OldAmount: orig.Amount, NewAmount: newAmount}, nil
+}
+
+func (r *MemoryRepository) GetUserHistory(ctx context.Context, userID int64) ([]domain.PointRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var history []domain.PointRecord
+	for i := len(r.points) - 1; i >= 0; i-- {
+		p := r.points[i]
+		if p.FromID != userID {
+			continue
+		}
+		rec := domain.PointRecord{
+			ID:        p.ID,
+			Amount:    p.Amount,
+			Reason:    p.Reason,
+			Status:    p.Status,
+			CreatedAt: p.CreatedAt,
+			DecidedAt: p.DecidedAt,
+		}
+		if p.DecidedBy != nil {
+			if d, ok := r.users[*p.DecidedBy]; ok {
+				name := d.Name
+				rec.DecidedBy = &name
+			}
+		}
+		if p.Comment != "" {
+			comment := p.Comment
+			rec.Comment = &comment
+		}
+		if p.TeamID != nil {
+			if t, ok := r.teams[*p.TeamID]; ok {
+				rec.Team = &t.Name
+			}
+		}
+		rec.Requested = p.Requested
+		rec.CorrectsID = p.CorrectsID
+		history = append(history, rec)
+	}
+	return history, nil
+}
+
+func (r *MemoryRepository) GetUserScore(ctx context.Context, userID int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	score, ok := r.scores[userID]
+	if !ok {
+		return 0, fmt.Errorf("не удалось получить счёт: %w", sql.ErrNoRows)
+	}
+	return score, nil
+}
+
+func (r *MemoryRepository) GetRanking(ctx context.Context) ([]domain.ScoreEntry, error) {
+	return r.ranking(), nil
+}
+
+// GetRankingByTeam mirrors UserRepository.GetRankingByTeam
+func (r *MemoryRepository) GetRankingByTeam(ctx context.Context, teamID int) ([]domain.ScoreEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members := r.teamMembers(teamID)
+	if r.teams[teamID].ArchivedAt != nil {
+		// в архивной команде рейтинг сохраняется для всех, кто в ней был
+		members = nil
+		for _, m := range r.memberships {
+			if m.TeamID == teamID && !slices.Contains(members, m.UserID) {
+				members = append(members, m.UserID)
+			}
+		}
+	}
+
+	var ranking []domain.ScoreEntry
+	for _, id := range members {
+		u := r.users[id]
+		if u.Role != domain.RoleAthlete {
+			continue
+		}
+		ranking = append(ranking, domain.ScoreEntry{UserID: id, Name: u.Name, Username: u.Username, Score: r.teamScore(id, teamID)})
+	}
+	sortRanking(ranking)
+	return ranking, nil
+}
+
+// teamMembers returns IDs of the team's current members
+func (r *MemoryRepository) teamMembers(teamID int) []int64 {
+	var ids []int64
+	for _, m := range r.memberships {
+		if m.TeamID == teamID && m.LeftAt == nil {
+			ids = append(ids, m.UserID)
+		}
+	}
+	return ids
+}
+
+// GetRankingByCoach mirrors UserRepository.GetRankingByCoach
+func (r *MemoryRepository) GetRankingByCoach(ctx context.Context, coachID int64) ([]domain.ScoreEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ranking []domain.ScoreEntry
+	for id, u := range r.users {
+		if u.Role != domain.RoleAthlete {
+			continue
+		}
+		coached, score := false, 0
+		for _, teamID := range r.activeTeams(id) {
+			if r.teamCoaches[teamID][coachID] && r.teams[teamID].ArchivedAt == nil {
+				coached = true
+				score += r.teamScore(id, teamID)
+			}
+		}
+		if coached {
+			ranking = append(ranking, domain.ScoreEntry{UserID: id, Name: u.Name, Username: u.Username, Score: score})
+		}
+	}
+	sortRanking(ranking)
+	return ranking, nil
+}
+
+// ranking returns all athletes ordered by score DESC, name ASC
+func (r *MemoryRepository) ranking() []domain.ScoreEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ranking []domain.ScoreEntry
+	for id, score := range r.scores {
+		u := r.users[id]
+		if u.Role != domain.RoleAthlete {
+			continue
+		}
+		ranking = append(ranking, domain.ScoreEntry{UserID: id, Name: u.Name, Username: u.Username, Score: score})
+	}
+	sortRanking(ranking)
+	return ranking
+}
+
+// sortRanking orders entries by score DESC, name ASC
+func sortRanking(ranking []domain.ScoreEntry) {
+	sort.Slice(ranking, func(i, j int) bool {
+		if ranking[i].Score != ranking[j].Score {
+			return ranking[i].Score > ranking[j].Score
+		}
+		return ranking[i].Name < ranking[j].Name
+	})
+}
+
+func (r *MemoryRepository) ListStaff(ctx context.Context) ([]domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []domain.User
+	for _, u := range r.users {
+		if u.Role.IsStaff() {
+			users = append(users, u.User)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Role != users[j].Role {
+			return users[i].Role == domain.RoleAdmin
+		}
+		return users[i].Name < users[j].Name
+	})
+	return users, nil
+}
+
+func (r *MemoryRepository) SetUserRole(ctx context.Context, userID int64, role domain.Role, changedBy *int64) (domain.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return "", fmt.Errorf("пользователь не найден: %w", sql.ErrNoRows)
+	}
+	old := u.Role
+	if old == role {
+		return old, nil
+	}
+	if !role.IsStaff() {
+		var sole []int
+		for teamID, coaches := range r.teamCoaches {
+			if coaches[userID] && len(coaches) == 1 {
+				sole = append(sole, teamID)
+			}
+		}
+		if len(sole) > 0 {
+			slices.Sort(sole)
+			return "", fmt.Errorf("%w: это единственный тренер команд %s", ErrLastCoach, formatTeamIDs(sole))
+		}
+	}
+
+	u.Role = role
+	change := domain.RoleChange{
+		ID:        len(r.roleChanges) + 1,
+		UserID:    userID,
+		Username:  u.Username,
+		OldRole:   old,
+		NewRole:   role,
+		CreatedAt: time.Now(),
+	}
+	if changedBy != nil {
+		if by, ok := r.users[*changedBy]; ok {
+			name := by.Name
+			change.ChangedBy = &name
+		}
+	}
+	r.roleChanges = append(r.roleChanges, change)
+
+	if _, ok := r.scores[userID]; !ok && role == domain.RoleAthlete {
+		r.scores[userID] = 0
+	}
+	if !role.IsStaff() {
+		for _, coaches := range r.teamCoaches {
+			delete(coaches, userID)
+		}
+	}
+	return old, nil
+}
+
+func (r *MemoryRepository) ListRoleChanges(ctx context.Context, limit int) ([]domain.RoleChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var changes []domain.RoleChange
+	for i := len(r.roleChanges) - 1; i >= 0 && len(changes) < limit; i-- {
+		changes = append(changes, r.roleChanges[i])
+	}
+	return changes, nil
+}
+
+func (r *MemoryRepository) CreateCoachInvite(ctx context.Context, tokenHash string, createdBy int64, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.invites[tokenHash]; ok {
+		return fmt.Errorf("не удалось сохранить приглашение: токен уже существует")
+	}
+	r.invites[tokenHash] = &memInvite{CreatedBy: createdBy, ExpiresAt: expiresAt}
+	return nil
+}
+
+func (r *MemoryRepository) RedeemCoachInvite(ctx context.Context, tokenHash string, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[user.ID]; ok {
+		return fmt.Errorf("failed to insert into users: пользователь %d уже существует", user.ID)
+	}
+	inv, ok := r.invites[tokenHash]
+	if !ok || inv.UsedBy != nil || !time.Now().Before(inv.ExpiresAt) {
+		return ErrInviteInvalid
+	}
+
+	id := user.ID
+	inv.UsedBy = &id
+	coach := *user
+	coach.Role = domain.RoleCoach
+	r.users[user.ID] = &memUser{User: coach, NotifyMode: domain.NotifyInstant}
+	return nil
+}
+
+func (r *MemoryRepository) CreateTeamInvite(ctx context.Context, invite *domain.TeamInvite) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	team, ok := r.teams[invite.TeamID]
+	if !ok {
+		return 0, fmt.Errorf("не удалось сохранить приглашение: команда %d не найдена", invite.TeamID)
+	}
+	for _, inv := range r.teamInvites {
+		if inv.TokenHash == invite.TokenHash {
+			return 0, fmt.Errorf("не удалось сохранить приглашение: токен уже существует")
+		}
+	}
+
+	stored := *invite
+	stored.ID = len(r.teamInvites) + 1
+	stored.CreatedAt = time.Now()
+	r.teamInvites = append(r.teamInvites, &stored)
+
+	team.TokenInvites = true
+	r.teams[team.ID] = team
+	return stored.ID, nil
+}
+
+func (r *MemoryRepository) GetTeamInvite(ctx context.Context, id int) (*domain.TeamInvite, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id <= 0 || id > len(r.teamInvites) {
+		return nil, fmt.Errorf("приглашение #%d не найдено: %w", id, sql.ErrNoRows)
+	}
+	invite := *r.teamInvites[id-1]
+	return &invite, nil
+}
+
+func (r *MemoryRepository) ListTeamInvites(ctx context.Context, teamID int) ([]domain.TeamInvite, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var invites []domain.TeamInvite
+	now := time.Now()
+	for _, inv := range r.teamInvites {
+		if inv.TeamID == teamID && inv.Active(now) {
+			invites = append(invites, *inv)
+		}
+	}
+	return invites, nil
+}
+
+func (r *MemoryRepository) RevokeTeamInvite(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id <= 0 || id > len(r.teamInvites) || r.teamInvites[id-1].RevokedAt != nil {
+		return fmt.Errorf("приглашение #%d не найдено или уже отозвано", id)
+	}
+	now := time.Now()
+	r.teamInvites[id-1].RevokedAt = &now
+	return nil
+}
+
+func (r *MemoryRepository) RedeemTeamInvite(ctx context.Context, tokenHash string, user *domain.User) (*domain.JoinRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var invite *domain.TeamInvite
+	for _, inv := range r.teamInvites {
+		if inv.TokenHash == tokenHash {
+			invite = inv
+		}
+	}
+	if invite == nil || !invite.Active(time.Now()) {
+		return nil, ErrInviteInvalid
+	}
+
+	if r.isMember(user.ID, invite.TeamID) {
+		return nil, ErrAlreadyMember
+	}
+
+	status := domain.JoinApproved
+	if invite.NeedsApproval {
+		status = domain.JoinPending
+	}
+
+	inviteID := invite.ID
+	req, err := r.addJoinRequest(user, invite.TeamID, &inviteID, status)
+	if err != nil {
+		return nil, err
+	}
+	if status == domain.JoinApproved {
+		r.addAthlete(req)
+	}
+	invite.Uses++
+	return req, nil
+}
+
+func (r *MemoryRepository) ListTeamCoaches(ctx context.Context, teamID int) ([]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []int64
+	for id := range r.teamCoaches[teamID] {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (r *MemoryRepository) CreateJoinRequest(ctx context.Context, user *domain.User, teamID int) (*domain.JoinRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.addJoinRequest(user, teamID, nil, domain.JoinPending)
+}
+
+func (r *MemoryRepository) addJoinRequest(user *domain.User, teamID int, inviteID *int, status domain.JoinStatus) (*domain.JoinRequest, error) {
+	team, ok := r.teams[teamID]
+	if !ok {
+		return nil, fmt.Errorf("не удалось сохранить заявку: команда %d не найдена", teamID)
+	}
+	// как уникальный индекс join_request_pending_idx: на рассмотрении у пользователя может быть одна заявка
+	for _, j := range r.joins {
+		if status == domain.JoinPending && j.UserID == user.ID && j.Status == domain.JoinPending {
+			return nil, ErrJoinPending
+		}
+	}
+
+	req := &domain.JoinRequest{
+		ID:        len(r.joins) + 1,
+		UserID:    user.ID,
+		Name:      user.Name,
+		Username:  user.Username,
+		TeamID:    teamID,
+		TeamName:  team.Name,
+		InviteID:  inviteID,
+		Status:    status,
+		CreatedAt: time.Now(),
+	}
+	if status != domain.JoinPending {
+		req.DecidedAt = &req.CreatedAt
+	}
+	r.joins = append(r.joins, req)
+
+	result := *req
+	return &result, nil
+}
+
+// addAthlete mirrors insertAthlete: registered athletes keep their account and only join the team
+func (r *MemoryRepository) addAthlete(req *domain.JoinRequest) {
+	if _, ok := r.users[req.UserID]; !ok {
+		r.users[req.UserID] = &memUser{
+			User:       domain.User{ID: req.UserID, Name: req.Name, Username: req.Username, Role: domain.RoleAthlete},
+			NotifyMode: domain.NotifyInstant,
+		}
+	}
+	if _, ok := r.scores[req.UserID]; !ok {
+		r.scores[req.UserID] = 0
+	}
+	if !r.isMember(req.UserID, req.TeamID) {
+		r.memberships = append(r.memberships, &memMembership{UserID: req.UserID, TeamID: req.TeamID, JoinedAt: time.Now()})
+	}
+}
+
+func (r *MemoryRepository) GetJoinRequest(ctx context.Context, id int) (*domain.JoinRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id <= 0 || id > len(r.joins) {
+		return nil, fmt.Errorf("заявка #%d не найдена: %w", id, sql.ErrNoRows)
+	}
+	req := *r.joins[id-1]
+	return &req, nil
+}
+
+func (r *MemoryRepository) GetPendingJoinRequest(ctx context.Context, userID int64) (*domain.JoinRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, j := range r.joins {
+		if j.UserID == userID && j.Status == domain.JoinPending {
+			req := *j
+			return &req, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MemoryRepository) ListJoinRequests(ctx context.Context) ([]domain.JoinRequest, error) {
+	return r.listJoinRequests(func(*domain.JoinRequest) bool { return true }), nil
+}
+
+func (r *MemoryRepository) ListJoinRequestsByCoach(ctx context.Context, coachID int64) ([]domain.JoinRequest, error) {
+	return r.listJoinRequests(func(j *domain.JoinRequest) bool { return r.teamCoaches[j.TeamID][coachID] }), nil
+}
+
+func (r *MemoryRepository) listJoinRequests(keep func(*domain.JoinRequest) bool) []domain.JoinRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var reqs []domain.JoinRequest
+	for _, j := range r.joins {
+		if j.Status == domain.JoinPending && keep(j) {
+			reqs = append(reqs, *j)
+		}
+	}
+	return reqs
+}
+
+func (r *MemoryRepository) ApproveJoinRequest(ctx context.Context, id int, coachID int64) (*domain.JoinRequest, error) {
+	return r.decideJoinRequest(id, coachID, domain.JoinApproved)
+}
+
+func (r *MemoryRepository) DenyJoinRequest(ctx context.Context, id int, coachID int64) (*domain.JoinRequest, error) {
+	return r.decideJoinRequest(id, coachID, domain.JoinDenied)
+}
+
+func (r *MemoryRepository) decideJoinRequest(id int, coachID int64, status domain.JoinStatus) (*domain.JoinRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id <= 0 || id > len(r.joins) || r.joins[id-1].Status != domain.JoinPending {
+		return nil, fmt.Errorf("заявка #%d не найдена или уже рассмотрена", id)
+	}
+	req := r.joins[id-1]
+	if u, ok := r.users[req.UserID]; ok && status == domain.JoinApproved && u.Role != domain.RoleAthlete {
+		return nil, fmt.Errorf("%w: @%s — %s", ErrNotAthlete, req.Username, u.Role)
+	}
+	if status == domain.JoinApproved {
+		r.addAthlete(req)
+	}
+
+	now := time.Now()
+	req.Status = status
+	req.DecidedBy = &coachID
+	req.DecidedAt = &now
+
+	result := *req
+	return &result, nil
+}
+
+func (r *MemoryRepository) ListActivities(ctx context.Context, teamID int) ([]domain.Activity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var activities []domain.Activity
+	for _, a := range r.activities {
+		if a.TeamID == teamID {
+			activities = append(activities, a)
+		}
+	}
+	sort.Slice(activities, func(i, j int) bool {
+		if activities[i].Amount != activities[j].Amount {
+			return activities[i].Amount < activities[j].Amount
+		}
+		return activities[i].Name < activities[j].Name
+	})
+	return activities, nil
+}
+
+func (r *MemoryRepository) GetActivity(ctx context.Context, id int) (*domain.Activity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, a := range r.activities {
+		if a.ID == id {
+			return &a, nil
+		}
+	}
+	return nil, fmt.Errorf("занятие #%d не найдено: %w", id, sql.ErrNoRows)
+}
+
+func (r *MemoryRepository) AddActivity(ctx context.Context, activity *domain.Activity) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teams[activity.TeamID]; !ok {
+		return 0, fmt.Errorf("не удалось добавить занятие: команда %d не найдена", activity.TeamID)
+	}
+	for _, a := range r.activities {
+		if a.TeamID == activity.TeamID && strings.EqualFold(a.Name, activity.Name) {
+			return 0, ErrActivityExists
+		}
+	}
+
+	r.nextActivityID++
+	a := *activity
+	a.ID = r.nextActivityID
+	a.CreatedAt = time.Now()
+	r.activities = append(r.activities, a)
+	return a.ID, nil
+}
+
+func (r *MemoryRepository) RemoveActivity(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(r.activities)
+	r.activities = slices.DeleteFunc(r.activities, func(a domain.Activity) bool { return a.ID == id })
+	if len(r.activities) == n {
+		return fmt.Errorf("занятие #%d не найдено", id)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// internal/repository/repository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"surf_bot/internal/domain"
+)
+
+// ErrInviteInvalid is returned for unknown, used up or expired invitations
+var ErrInviteInvalid = errors.New("приглашение недействительно или уже использовано")
+
+// ErrTeamHasHistory is returned when deleting a team would lose members or point history
+var ErrTeamHasHistory = errors.New("у команды есть спортсмены или история начислений, её можно только архивировать")
+
+// ErrLastCoach is returned when a change would leave a team without a coach
+var ErrLastCoach = errors.New("нельзя оставить команду без тренера")
+
+// ErrNegativeScore is returned when a fine would take a score below zero where the team forbids it
+var ErrNegativeScore = errors.New("счёт спортсмена не может уйти в минус")
+
+// ErrActivityExists is returned when the team's catalog already has an activity with that name
+var ErrActivityExists = errors.New("такое занятие уже есть в каталоге команды")
+
+// ErrAlreadyMember is returned when an athlete redeems an invite into a team they are already in
+var ErrAlreadyMember = errors.New("спортсмен уже состоит в этой команде")
+
+// ErrNotAthlete is returned when approving a join request of a user who has become a coach or an admin
+var ErrNotAthlete = errors.New("пользователь зарегистрирован не как спортсмен")
+
+// ErrJoinPending is returned when the applicant already waits for a coach's decision
+var ErrJoinPending = errors.New("заявка в команду уже на рассмотрении")
+
+// Users stores registered athletes and coaches.
+// GetUserByID returns nil, nil for an unknown user; GetUserByUsername returns sql.ErrNoRows.
+type Users interface {
+	GetUserByID(ctx context.Context, id int64) (*domain.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*domain.User, error)
+	RegisterUser(ctx context.Context, user *domain.User) error
+	RegisterAdmin(ctx context.Context, user *domain.User) error
+	ListAthletes(ctx context.Context) ([]domain.AthleteShort, error)
+	ListAthletesByTeam(ctx context.Context, teamID *int) ([]domain.AthleteShort, error)
+	ListAthletesByCoach(ctx context.Context, coachID int64) ([]domain.AthleteShort, error)
+	GetNotifyMode(ctx context.Context, userID int64) (domain.NotifyMode, error)
+	SetNotifyMode(ctx context.Context, userID int64, mode domain.NotifyMode) error
+	ListRequestRecipients(ctx context.Context, athleteID int64, teamID *int) ([]Recipient, error)
+	ListStaff(ctx context.Context) ([]domain.User, error)
+	SetUserRole(ctx context.Context, userID int64, role domain.Role, changedBy *int64) (domain.Role, error)
+	ListRoleChanges(ctx context.Context, limit int) ([]domain.RoleChange, error)
+	CreateCoachInvite(ctx context.Context, tokenHash string, createdBy int64, expiresAt time.Time) error
+	RedeemCoachInvite(ctx context.Context, tokenHash string, user *domain.User) error
+}
+
+// Teams stores teams, athlete memberships and which coaches run which teams.
+// An athlete may be in several teams at once; one without a team is in scope of every coach.
+// Archived teams are left out of team lists but keep their members, ranking and history.
+type Teams interface {
+	GetTeamByName(ctx context.Context, name string) (*domain.Team, error)
+	GetTeamByID(ctx context.Context, id int) (*domain.Team, error)
+	CreateTeam(ctx context.Context, name string, coachID int64) error
+	DeleteTeam(ctx context.Context, teamID int) (*TeamClosure, error)
+	GetTeamImpact(ctx context.Context, teamID int) (*domain.TeamImpact, error)
+	ArchiveTeam(ctx context.Context, teamID int, adminID int64) (*TeamClosure, error)
+	RestoreTeam(ctx context.Context, teamID int) error
+	MoveTeamMembers(ctx context.Context, fromTeamID, toTeamID int) (int, error)
+	SetTeamAllowNegative(ctx context.Context, teamID int, allow bool) error
+	SetTeamFreeRequests(ctx context.Context, teamID int, allow bool) error
+	ListTeams(ctx context.Context) ([]domain.Team, error)
+	ListArchivedTeams(ctx context.Context) ([]domain.Team, error)
+	AssignUserToTeam(ctx context.Context, userID int64, teamID int) error
+	RemoveUserFromTeam(ctx context.Context, userID int64, teamID int) error
+	ListUserTeams(ctx context.Context, userID int64) ([]domain.Team, error)
+	ListMemberships(ctx context.Context, userID int64) ([]domain.Membership, error)
+	AddTeamCoach(ctx context.Context, teamID int, coachID int64) error
+	RemoveTeamCoach(ctx context.Context, teamID int, coachID int64) error
+	ListCoachTeams(ctx context.Context, coachID int64) ([]domain.Team, error)
+	CoachesTeam(ctx context.Context, coachID int64, teamID int) (bool, error)
+	CoachesAthlete(ctx context.Context, coachID int64, athleteID int64) (bool, error)
+	CreateTeamInvite(ctx context.Context, invite *domain.TeamInvite) (int, error)
+	GetTeamInvite(ctx context.Context, id int) (*domain.TeamInvite, error)
+	ListTeamInvites(ctx context.Context, teamID int) ([]domain.TeamInvite, error)
+	RevokeTeamInvite(ctx context.Context, id int) error
+	RedeemTeamInvite(ctx context.Context, tokenHash string, user *domain.User) (*domain.JoinRequest, error)
+	ListTeamCoaches(ctx context.Context, teamID int) ([]int64, error)
+	CreateJoinRequest(ctx context.Context, user *domain.User, teamID int) (*domain.JoinRequest, error)
+	GetJoinRequest(ctx context.Context, id int) (*domain.JoinRequest, error)
+	GetPendingJoinRequest(ctx context.Context, userID int64) (*domain.JoinRequest, error)
+	ListJoinRequests(ctx context.Context) ([]domain.JoinRequest, error)
+	ListJoinRequestsByCoach(ctx context.Context, coachID int64) ([]domain.JoinRequest, error)
+	ApproveJoinRequest(ctx context.Context, id int, coachID int64) (*domain.JoinRequest, error)
+	DenyJoinRequest(ctx context.Context, id int, coachID int64) (*domain.JoinRequest, error)
+}
+
+// Points stores point requests and grants
+type Points interface {
+	CreatePendingRequest(ctx context.Context, fromID int64, amount int, reason string, teamID *int) (int, error)
+	GetPendingRequest(ctx context.Context, id int) (*PendingRequest, error)
+	GetPendingRequests(ctx context.Context) ([]PendingRequest, error)
+	GetPendingRequestsByTeam(ctx context.Context, teamID *int) ([]PendingRequest, error)
+	GetPendingRequestsByCoach(ctx context.Context, coachID int64) ([]PendingRequest, error)
+	UpdatePendingAmount(ctx context.Context, id int, amount int) error
+	ApproveRequest(ctx context.Context, id int, coachID int64, amount *int, comment string) (*Grant, error)
+	RejectRequest(ctx context.Context, id int, coachID int64, comment string) (int64, error)
+	CancelRequest(ctx context.Context, id int, userID int64) error
+	ExpirePendingRequests(ctx context.Context, maxAge time.Duration) (int64, error)
+	GivePoints(ctx context.Context, coachID int64, toUsername string, amount int, reason string, teamID *int) (*Grant, error)
+	FinePoints(ctx context.Context, coachID int64, athleteID int64, amount int, reason string, teamID *int) (*Grant, error)
+	GetGrant(ctx context.Context, pointID int) (*Grant, error)
+	CorrectGrant(ctx context.Context, coachID int64, pointID int, newAmount int, comment string) (*Correction, error)
+	GetUserHistory(ctx context.Context, userID int64) ([]domain.PointRecord, error)
+}
+
+// Activities stores the per-team catalogs of activities with fixed point values
+type Activities interface {
+	ListActivities(ctx context.Context, teamID int) ([]domain.Activity, error)
+	GetActivity(ctx context.Context, id int) (*domain.Activity, error)
+	AddActivity(ctx context.Context, activity *domain.Activity) (int, error)
+	RemoveActivity(ctx context.Context, id int) error
+}
+
+// Rankings reads athlete scores
+type Rankings interface {
+	GetUserScore(ctx context.Context, userID int64) (int, error)
+	GetRanking(ctx context.Context) ([]domain.ScoreEntry, error)
+	GetRankingByTeam(ctx context.Context, teamID int) ([]domain.ScoreEntry, error)
+	GetRankingByCoach(ctx context.Context, coachID int64) ([]domain.ScoreEntry, error)
+}
+
+// Repository is everything the bot needs from storage.
+// UserRepository implements it on PostgreSQL, MemoryRepository keeps it in memory.
+type Repository interface {
+	Users
+	Teams
+	Points
+	Activities
+	Rankings
+}
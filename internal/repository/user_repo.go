@@ -1,27 +1,36 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"surf_bot/internal/domain"
 	"surf_bot/internal/util"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
+// UserRepository is the PostgreSQL implementation of Repository
 type UserRepository struct {
 	DB *sqlx.DB
 }
 
+var _ Repository = (*UserRepository)(nil)
+
 func NewUserRepository(db *sqlx.DB) *UserRepository {
 	return &UserRepository{DB: db}
 }
 
 // GetUserByID returns a user if exists
-func (r *UserRepository) GetUserByID(id int64) (*domain.User, error) {
+func (r *UserRepository) GetUserByID(ctx context.Context, id int64) (*domain.User, error) {
 	var user domain.User
-	err := r.DB.Get(&user, "SELECT id, name, role FROM users WHERE id = $1", id)
+	err := r.DB.GetContext(ctx, &user, "SELECT id, name, username, role FROM users WHERE id = $1", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -32,8 +41,8 @@ func (r *UserRepository) GetUserByID(id int64) (*domain.User, error) {
 }
 
 // RegisterUser inserts user and score if not exists
-func (r *UserRepository) RegisterUser(user *domain.User) error {
-	existing, err := r.GetUserByID(user.ID)
+func (r *UserRepository) RegisterUser(ctx context.Context, user *domain.User) error {
+	existing, err := r.GetUserByID(ctx, user.ID)
 	if err != nil {
 		return err
 	}
@@ -41,16 +50,19 @@ func (r *UserRepository) RegisterUser(user *domain.User) error {
 		return nil // already exists
 	}
 
-	tx := r.DB.MustBegin()
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
 
-	_, err = tx.Exec("INSERT INTO users (id, name, username, role) VALUES ($1, $2, $3, $4)", user.ID, user.Name, user.Username, user.Role)
+	_, err = tx.ExecContext(ctx, "INSERT INTO users (id, name, username, role) VALUES ($1, $2, $3, $4)", user.ID, user.Name, user.Username, user.Role)
 	if err != nil {
 		util.SafeRollback(tx)
 		return fmt.Errorf("failed to insert into users: %w", err)
 	}
 
-	if user.Role != domain.RoleCoach {
-		_, err = tx.Exec("INSERT INTO user_score (user_id, score) VALUES ($1, 0)", user.ID)
+	if user.Role == domain.RoleAthlete {
+		_, err = tx.ExecContext(ctx, "INSERT INTO user_score (user_id, score) VALUES ($1, 0)", user.ID)
 		if err != nil {
 			util.SafeRollback(tx)
 			return fmt.Errorf("failed to insert into user_score: %w", err)
@@ -61,9 +73,9 @@ func (r *UserRepository) RegisterUser(user *domain.User) error {
 }
 
 // GetRanking returns athletes ordered by score DESC
-func (r *UserRepository) GetRanking() ([]domain.ScoreEntry, error) {
+func (r *UserRepository) GetRanking(ctx context.Context) ([]domain.ScoreEntry, error) {
 	query := `
-		SELECT u.id as user_id, u.name, s.score
+		SELECT u.id as user_id, u.name, u.username, s.score
 		FROM users u
 		JOIN user_score s ON u.id = s.user_id
 		WHERE u.role = 'athlete'
@@ -71,7 +83,7 @@ func (r *UserRepository) GetRanking() ([]domain.ScoreEntry, error) {
 	`
 
 	var ranking []domain.ScoreEntry
-	err := r.DB.Select(&ranking, query)
+	err := r.DB.SelectContext(ctx, &ranking, query)
 	if err != nil {
 		return nil, err
 	}
@@ -80,18 +92,69 @@ func (r *UserRepository) GetRanking() ([]domain.ScoreEntry, error) {
 }
 
 // CreatePendingRequest stores a pending point request from athlete
-func (r *UserRepository) CreatePendingRequest(fromID int64, amount int, reason string) error {
-	_, err := r.DB.Exec(`
-		INSERT INTO point (from_id, amount, reason, pending)
-		VALUES ($1, $2, $3, true)
-	`, fromID, amount, reason)
+func (r *UserRepository) CreatePendingRequest(ctx context.Context, fromID int64, amount int, reason string, teamID *int) (int, error) {
+	var id int
+	err := r.DB.GetContext(ctx, &id, `
+		INSERT INTO point (from_id, amount, reason, status, team_id)
+		VALUES ($1, $2, $3, 'pending', $4)
+		RETURNING id
+	`, fromID, amount, reason, teamID)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert point request: %w", err)
+	}
+	return id, nil
+}
 
+// Recipient is a coach to be told about new point requests
+type Recipient struct {
+	ID   int64             `db:"id"`
+	Mode domain.NotifyMode `db:"notify_mode"`
+}
+
+// ListRequestRecipients returns coaches responsible for a request of the athlete
+// that have not turned notifications off: the coaches of the request's team,
+// or of any of the athlete's teams when the request has none
+func (r *UserRepository) ListRequestRecipients(ctx context.Context, athleteID int64, teamID *int) ([]Recipient, error) {
+	var recipients []Recipient
+	err := r.DB.SelectContext(ctx, &recipients, `
+		SELECT c.id, c.notify_mode FROM users c
+		WHERE c.role IN ('coach', 'admin') AND c.notify_mode <> 'off' AND c.id <> $1
+		  AND CASE
+		      WHEN $2::int IS NOT NULL THEN
+		          c.id IN (SELECT coach_id FROM coach_team WHERE team_id = $2)
+		      WHEN EXISTS (SELECT 1 FROM team_membership m WHERE m.user_id = $1 AND m.left_at IS NULL) THEN
+		          c.id IN (SELECT ct.coach_id FROM coach_team ct
+		                   JOIN team_membership m ON m.team_id = ct.team_id
+		                   WHERE m.user_id = $1 AND m.left_at IS NULL)
+		      ELSE true
+		  END
+		ORDER BY c.id
+	`, athleteID, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить список тренеров: %w", err)
+	}
+	return recipients, nil
+}
+
+func (r *UserRepository) GetNotifyMode(ctx context.Context, userID int64) (domain.NotifyMode, error) {
+	var mode domain.NotifyMode
+	err := r.DB.GetContext(ctx, &mode, `SELECT notify_mode FROM users WHERE id = $1`, userID)
 	if err != nil {
-		return fmt.Errorf("failed to insert point request: %w", err)
+		return "", fmt.Errorf("не удалось получить настройки уведомлений: %w", err)
+	}
+	return mode, nil
+}
+
+func (r *UserRepository) SetNotifyMode(ctx context.Context, userID int64, mode domain.NotifyMode) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE users SET notify_mode = $2 WHERE id = $1`, userID, mode)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить настройки уведомлений: %w", err)
 	}
 	return nil
 }
 
+// PendingRequest is a point request waiting for a coach decision
 type PendingRequest struct {
 	ID       int    `db:"id"`
 	UserID   int64  `db:"from_id"`
@@ -99,20 +162,53 @@ type PendingRequest struct {
 	Username string `db:"username"`
 	Amount   int    `db:"amount"`
 	Reason   string `db:"reason"`
+	TeamID   *int   `db:"team_id"`
+	TeamName string `db:"team_name"` // пусто, если запрос не привязан к команде
+}
+
+// Grant is a point entry that has just been credited to an athlete
+type Grant struct {
+	PointID int    `db:"id"`
+	UserID  int64  `db:"from_id"`
+	Amount  int    `db:"amount"`
+	Reason  string `db:"reason"`
+	TeamID  *int   `db:"team_id"`
+
+	Requested *int   `db:"requested_amount"` // сколько просил спортсмен, если тренер изменил сумму
+	Comment   string `db:"decision_comment"`
+}
+
+// TeamClosure lists what archiving or deleting a team closed, so the people waiting can be told
+type TeamClosure struct {
+	DeniedJoins      []domain.JoinRequest // заявки на вступление, которые больше не рассмотрят
+	RejectedRequests []PendingRequest     // запросы на баллы, отклонённые при архивации
+}
+
+// ArchivedRequestComment is the rejection reason of point requests closed by archiving their team
+const ArchivedRequestComment = "команда перенесена в архив"
+
+// Correction is a compensating entry written against an approved one.
+// Its Amount is the difference; the original entry is never changed.
+type Correction struct {
+	Grant
+	OriginalID int
+	OldAmount  int // сумма исходной записи с учётом прежних исправлений
+	NewAmount  int
 }
 
 // GetPendingRequests returns all pending point requests
-func (r *UserRepository) GetPendingRequests() ([]PendingRequest, error) {
+func (r *UserRepository) GetPendingRequests(ctx context.Context) ([]PendingRequest, error) {
 	query := `
-		SELECT p.id, p.from_id, u.name, u.username, p.amount, p.reason
+		SELECT p.id, p.from_id, u.name, u.username, p.amount, p.reason, p.team_id, COALESCE(t.name, '') AS team_name
 		FROM point p
 		JOIN users u ON p.from_id = u.id
-		WHERE p.pending = true
+		LEFT JOIN team t ON t.id = p.team_id
+		WHERE p.status = 'pending'
 		ORDER BY p.id ASC
 	`
 
 	var requests []PendingRequest
-	err := r.DB.Select(&requests, query)
+	err := r.DB.SelectContext(ctx, &requests, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pendings data: %w", err)
 	}
@@ -120,85 +216,237 @@ func (r *UserRepository) GetPendingRequests() ([]PendingRequest, error) {
 	return requests, nil
 }
 
-func (r *UserRepository) ApproveRequest(id int) error {
-	tx := r.DB.MustBegin()
-
-	var req struct {
-		FromID int64 `db:"from_id"`
-		Amount int   `db:"amount"`
+// ApproveRequest credits a pending request and records which coach approved it.
+// A non-nil amount overrides the requested one, which is kept in requested_amount.
+func (r *UserRepository) ApproveRequest(ctx context.Context, id int, coachID int64, amount *int, comment string) (*Grant, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
 	}
 
-	// Найти запрос
-	err := tx.Get(&req, "SELECT from_id, amount FROM point WHERE id = $1 AND pending = true", id)
+	var g Grant
+
+	// Пометить как подтвержденный; условие по статусу не даёт подтвердить запрос дважды
+	err = tx.GetContext(ctx, &g, `
+		UPDATE point
+		SET status = 'approved', decided_at = now(), decided_by = $2,
+		    requested_amount = CASE WHEN $3::int IS NULL OR $3 = amount THEN requested_amount
+		                            ELSE COALESCE(requested_amount, amount) END,
+		    amount = COALESCE($3, amount),
+		    decision_comment = NULLIF($4, '')
+		WHERE id = $1 AND status = 'pending'
+		RETURNING id, from_id, amount, reason, team_id, requested_amount, COALESCE(decision_comment, '') AS decision_comment
+	`, id, coachID, amount, comment)
 	if err != nil {
 		util.SafeRollback(tx)
-		return fmt.Errorf("запрос не найден или уже обработан: %w", err)
+		return nil, fmt.Errorf("запрос не найден или уже обработан: %w", err)
 	}
 
 	// Начислить баллы
-	_, err = tx.Exec("UPDATE user_score SET score = score + $1 WHERE user_id = $2", req.Amount, req.FromID)
+	_, err = tx.ExecContext(ctx, "UPDATE user_score SET score = score + $1 WHERE user_id = $2", g.Amount, g.UserID)
 	if err != nil {
 		util.SafeRollback(tx)
-		return fmt.Errorf("не удалось начислить баллы: %w", err)
+		return nil, fmt.Errorf("не удалось начислить баллы: %w", err)
 	}
 
-	// Пометить как подтвержденный
-	_, err = tx.Exec("UPDATE point SET pending = false WHERE id = $1", id)
-	if err != nil {
-		util.SafeRollback(tx)
-		return fmt.Errorf("не удалось обновить статус запроса: %w", err)
+	if err := tx.Commit(); err != nil {
+		return nil, err
 	}
-
-	return tx.Commit()
+	return &g, nil
 }
 
-func (r *UserRepository) GivePoints(toUsername string, amount int, reason string) error {
-	tx := r.DB.MustBegin()
+// GivePoints credits an athlete directly; the entry is stored as approved by the coach
+func (r *UserRepository) GivePoints(ctx context.Context, coachID int64, toUsername string, amount int, reason string, teamID *int) (*Grant, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
 
 	// найти пользователя по username (без @)
 	var user struct {
 		ID int64 `db:"id"`
 	}
-	err := tx.Get(&user, `
+	err = tx.GetContext(ctx, &user, `
 		SELECT id FROM users
 		WHERE LOWER(username) = LOWER($1) AND role = 'athlete'
 	`, toUsername)
 	if err != nil {
 		util.SafeRollback(tx)
-		return fmt.Errorf("спортсмен с именем %s не найден: %w", toUsername, err)
+		return nil, fmt.Errorf("спортсмен с именем %s не найден: %w", toUsername, err)
 	}
 
 	// начислить баллы
-	_, err = tx.Exec(`
+	_, err = tx.ExecContext(ctx, `
 		UPDATE user_score SET score = score + $1 WHERE user_id = $2
 	`, amount, user.ID)
 	if err != nil {
 		util.SafeRollback(tx)
-		return fmt.Errorf("не удалось начислить баллы: %w", err)
+		return nil, fmt.Errorf("не удалось начислить баллы: %w", err)
 	}
 
 	// сохранить в point
-	_, err = tx.Exec(`
-		INSERT INTO point (from_id, amount, reason, pending)
-		VALUES ($1, $2, $3, false)
-	`, user.ID, amount, reason)
+	g := Grant{UserID: user.ID, Amount: amount, Reason: reason, TeamID: teamID}
+	err = tx.GetContext(ctx, &g.PointID, `
+		INSERT INTO point (from_id, amount, reason, status, decided_at, decided_by, team_id)
+		VALUES ($1, $2, $3, 'approved', now(), $4, $5)
+		RETURNING id
+	`, user.ID, amount, reason, coachID, teamID)
 	if err != nil {
 		util.SafeRollback(tx)
-		return fmt.Errorf("не удалось сохранить в историю: %w", err)
+		return nil, fmt.Errorf("не удалось сохранить в историю: %w", err)
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &g, nil
 }
 
-type AthleteShort struct {
-	ID       int64  `db:"id"`
-	Name     string `db:"name"`
-	Username string `db:"username"`
+// FinePoints deducts points from an athlete: the entry is stored approved with a negative amount.
+// Unless the team allows it, neither the total nor the team score may go below zero;
+// a fine without a team always keeps the total at zero or above.
+func (r *UserRepository) FinePoints(ctx context.Context, coachID int64, athleteID int64, amount int, reason string, teamID *int) (*Grant, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	// блокируем счёт, чтобы два штрафа не увели его в минус одновременно
+	var score int
+	err = tx.GetContext(ctx, &score, `SELECT score FROM user_score WHERE user_id = $1 FOR UPDATE`, athleteID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("не удалось получить счёт спортсмена: %w", err)
+	}
+
+	allowNegative := false
+	available := score
+	if teamID != nil {
+		var team struct {
+			AllowNegative bool `db:"allow_negative"`
+			Score         int  `db:"score"`
+		}
+		err = tx.GetContext(ctx, &team, `
+			SELECT t.allow_negative,
+			       COALESCE((SELECT SUM(p.amount) FROM point p
+			                 WHERE p.from_id = $2 AND p.team_id = t.id AND p.status = 'approved'
+			                   AND `+earnedInTeam+`), 0) AS score
+			FROM team t WHERE t.id = $1
+		`, *teamID, athleteID)
+		if err != nil {
+			util.SafeRollback(tx)
+			return nil, fmt.Errorf("не удалось получить команду: %w", err)
+		}
+		allowNegative = team.AllowNegative
+		available = min(score, team.Score)
+	}
+	if !allowNegative && available < amount {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("%w: доступно %d баллов", ErrNegativeScore, max(available, 0))
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE user_score SET score = score - $1 WHERE user_id = $2`, amount, athleteID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("не удалось списать баллы: %w", err)
+	}
+
+	g := Grant{UserID: athleteID, Amount: -amount, Reason: reason, TeamID: teamID}
+	err = tx.GetContext(ctx, &g.PointID, `
+		INSERT INTO point (from_id, amount, reason, status, decided_at, decided_by, team_id)
+		VALUES ($1, $2, $3, 'approved', now(), $4, $5)
+		RETURNING id
+	`, athleteID, -amount, reason, coachID, teamID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("не удалось сохранить в историю: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// grantColumns reads an approved entry with its corrections applied to the amount
+const grantColumns = `p.id, p.from_id, p.reason, p.team_id,
+	p.amount + COALESCE((SELECT SUM(c.amount) FROM point c WHERE c.corrects_id = p.id), 0) AS amount`
+
+// GetGrant returns an approved entry that can be corrected, with its current amount
+func (r *UserRepository) GetGrant(ctx context.Context, pointID int) (*Grant, error) {
+	var g Grant
+	err := r.DB.GetContext(ctx, &g, `
+		SELECT `+grantColumns+` FROM point p
+		WHERE p.id = $1 AND p.status = 'approved' AND p.corrects_id IS NULL
+	`, pointID)
+	if err != nil {
+		return nil, fmt.Errorf("начисление #%d не найдено: %w", pointID, err)
+	}
+	return &g, nil
+}
+
+// CorrectGrant brings an approved entry to newAmount by writing a compensating entry
+// linked to it and moving user_score by the difference. Revoking is correcting to zero.
+// Corrections are not limited by the team's negative score rule.
+func (r *UserRepository) CorrectGrant(ctx context.Context, coachID int64, pointID int, newAmount int, comment string) (*Correction, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	// блокировка исходной записи не даёт двум исправлениям посчитать разницу от одной суммы
+	var orig Grant
+	err = tx.GetContext(ctx, &orig, `
+		SELECT p.id, p.from_id, p.reason, p.team_id, p.amount FROM point p
+		WHERE p.id = $1 AND p.status = 'approved' AND p.corrects_id IS NULL
+		FOR UPDATE
+	`, pointID)
+	if err == nil {
+		err = tx.GetContext(ctx, &orig.Amount, `
+			SELECT $2::int + COALESCE(SUM(amount), 0) FROM point WHERE corrects_id = $1
+		`, pointID, orig.Amount)
+	}
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("начисление #%d не найдено: %w", pointID, err)
+	}
+	if orig.Amount == newAmount {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("в начислении #%d уже %d баллов", pointID, newAmount)
+	}
+
+	c := Correction{
+		Grant:      Grant{UserID: orig.UserID, Amount: newAmount - orig.Amount, Reason: orig.Reason, TeamID: orig.TeamID},
+		OriginalID: pointID,
+		OldAmount:  orig.Amount,
+		NewAmount:  newAmount,
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE user_score SET score = score + $1 WHERE user_id = $2`, c.Amount, c.UserID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("не удалось изменить счёт: %w", err)
+	}
+
+	err = tx.GetContext(ctx, &c.PointID, `
+		INSERT INTO point (from_id, amount, reason, status, decided_at, decided_by, decision_comment, team_id, corrects_id)
+		VALUES ($1, $2, $3, 'approved', now(), $4, NULLIF($5, ''), $6, $7)
+		RETURNING id
+	`, c.UserID, c.Amount, c.Reason, coachID, comment, c.TeamID, pointID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("не удалось сохранить в историю: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &c, nil
 }
 
-func (r *UserRepository) ListAthletes() ([]AthleteShort, error) {
-	var athletes []AthleteShort
-	err := r.DB.Select(&athletes, `
+func (r *UserRepository) ListAthletes(ctx context.Context) ([]domain.AthleteShort, error) {
+	var athletes []domain.AthleteShort
+	err := r.DB.SelectContext(ctx, &athletes, `
 		SELECT id, name, username FROM users WHERE role = 'athlete' ORDER BY name ASC
 	`)
 	if err != nil {
@@ -207,28 +455,55 @@ func (r *UserRepository) ListAthletes() ([]AthleteShort, error) {
 	return athletes, nil
 }
 
-func (r *UserRepository) RejectRequest(id int) (int64, error) {
+// RejectRequest marks a pending request as rejected and returns the athlete ID.
+// The row is kept so the rejection shows up in history.
+func (r *UserRepository) RejectRequest(ctx context.Context, id int, coachID int64, comment string) (int64, error) {
 	var fromID int64
-	err := r.DB.Get(&fromID, `
-		SELECT from_id FROM point WHERE id = $1 AND pending = true
-	`, id)
+	err := r.DB.GetContext(ctx, &fromID, `
+		UPDATE point
+		SET status = 'rejected', decided_at = now(), decided_by = $2, decision_comment = NULLIF($3, '')
+		WHERE id = $1 AND status = 'pending'
+		RETURNING from_id
+	`, id, coachID, comment)
 	if err != nil {
 		return 0, fmt.Errorf("запрос не найден или уже обработан: %w", err)
 	}
 
-	_, err = r.DB.Exec(`
-		DELETE FROM point WHERE id = $1
-	`, id)
+	return fromID, nil
+}
+
+// CancelRequest lets an athlete withdraw their own pending request
+func (r *UserRepository) CancelRequest(ctx context.Context, id int, userID int64) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE point
+		SET status = 'cancelled', decided_at = now(), decided_by = $2
+		WHERE id = $1 AND from_id = $2 AND status = 'pending'
+	`, id, userID)
 	if err != nil {
-		return 0, fmt.Errorf("ошибка при удалении запроса: %w", err)
+		return fmt.Errorf("не удалось отменить запрос: %w", err)
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("запрос не найден или уже обработан")
+	}
+	return nil
+}
 
-	return fromID, nil
+// ExpirePendingRequests closes requests that waited longer than maxAge and returns how many
+func (r *UserRepository) ExpirePendingRequests(ctx context.Context, maxAge time.Duration) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE point
+		SET status = 'expired', decided_at = now()
+		WHERE status = 'pending' AND created_at < now() - $1 * interval '1 second'
+	`, maxAge.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("не удалось закрыть устаревшие запросы: %w", err)
+	}
+	return res.RowsAffected()
 }
 
-func (r *UserRepository) GetUserByUsername(username string) (*domain.User, error) {
+func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
 	var u domain.User
-	err := r.DB.Get(&u, `
+	err := r.DB.GetContext(ctx, &u, `
 		SELECT id, name, username, role
 		FROM users
 		WHERE LOWER(username) = LOWER($1)
@@ -239,9 +514,9 @@ func (r *UserRepository) GetUserByUsername(username string) (*domain.User, error
 	return &u, nil
 }
 
-func (r *UserRepository) GetUserScore(userID int64) (int, error) {
+func (r *UserRepository) GetUserScore(ctx context.Context, userID int64) (int, error) {
 	var score int
-	err := r.DB.Get(&score, `
+	err := r.DB.GetContext(ctx, &score, `
 		SELECT score FROM user_score WHERE user_id = $1
 	`, userID)
 	if err != nil {
@@ -250,18 +525,22 @@ func (r *UserRepository) GetUserScore(userID int64) (int, error) {
 	return score, nil
 }
 
-// GetUserHistory returns the list of confirmed point records for a given user.
-func (r *UserRepository) GetUserHistory(userID int64) ([]domain.PointRecord, error) {
+// GetUserHistory returns all point entries of a user with their status and
+// the coach who decided on them, newest first.
+func (r *UserRepository) GetUserHistory(ctx context.Context, userID int64) ([]domain.PointRecord, error) {
 	var history []domain.PointRecord
 
 	query := `
-		SELECT amount, reason
-		FROM point
-		WHERE from_id = $1 AND pending = false
-		ORDER BY id DESC
+		SELECT p.id, p.amount, p.reason, p.status, p.created_at, p.decided_at,
+		       d.name AS decided_by, p.decision_comment, t.name AS team_name, p.requested_amount, p.corrects_id
+		FROM point p
+		LEFT JOIN users d ON d.id = p.decided_by
+		LEFT JOIN team t ON t.id = p.team_id
+		WHERE p.from_id = $1
+		ORDER BY p.id DESC
 	`
 
-	err := r.DB.Select(&history, query, userID)
+	err := r.DB.SelectContext(ctx, &history, query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("не удалось получить историю начислений: %w", err)
 	}
@@ -269,46 +548,275 @@ func (r *UserRepository) GetUserHistory(userID int64) ([]domain.PointRecord, err
 	return history, nil
 }
 
-func (r *UserRepository) GetTeamByName(name string) (*domain.Team, error) {
+const teamColumns = `id, name, token_invites, allow_negative, free_requests, archived_at`
+
+func (r *UserRepository) GetTeamByName(ctx context.Context, name string) (*domain.Team, error) {
 	var team domain.Team
-	err := r.DB.Get(&team, `SELECT id, name FROM team WHERE name = $1`, name)
+	err := r.DB.GetContext(ctx, &team, `SELECT `+teamColumns+` FROM team WHERE name = $1`, name)
 	if err != nil {
 		return nil, err
 	}
 	return &team, nil
 }
 
-func (r *UserRepository) GetTeamByID(id int) (*domain.Team, error) {
+func (r *UserRepository) GetTeamByID(ctx context.Context, id int) (*domain.Team, error) {
 	var team domain.Team
-	err := r.DB.Get(&team, "SELECT id, name FROM team WHERE id = $1", id)
+	err := r.DB.GetContext(ctx, &team, `SELECT `+teamColumns+` FROM team WHERE id = $1`, id)
 	if err != nil {
 		return nil, fmt.Errorf("команда с ID %d не найдена: %w", id, err)
 	}
 	return &team, nil
 }
 
-func (r *UserRepository) AssignUserToTeam(userID int64, teamID int) error {
-	_, err := r.DB.Exec("UPDATE users SET team_id = $1 WHERE id = $2", teamID, userID)
+// AssignUserToTeam adds the user to the team, keeping their other teams.
+// Adding a current member again changes nothing.
+func (r *UserRepository) AssignUserToTeam(ctx context.Context, userID int64, teamID int) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO team_membership (user_id, team_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, team_id) WHERE left_at IS NULL DO NOTHING
+	`, userID, teamID)
 	if err != nil {
 		return fmt.Errorf("не удалось назначить команду пользователю: %w", err)
 	}
 	return nil
 }
 
-func (r *UserRepository) CreateTeam(name string) error {
-	_, err := r.DB.Exec(`INSERT INTO team (name) VALUES ($1)`, name)
-	return err
+// RemoveUserFromTeam closes the user's membership; its history is kept
+func (r *UserRepository) RemoveUserFromTeam(ctx context.Context, userID int64, teamID int) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE team_membership SET left_at = now()
+		WHERE user_id = $1 AND team_id = $2 AND left_at IS NULL
+	`, userID, teamID)
+	if err != nil {
+		return fmt.Errorf("не удалось вывести пользователя из команды: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("пользователь не состоит в команде #%d", teamID)
+	}
+	return nil
+}
+
+// ListUserTeams returns the active teams the user is currently a member of
+func (r *UserRepository) ListUserTeams(ctx context.Context, userID int64) ([]domain.Team, error) {
+	var teams []domain.Team
+	err := r.DB.SelectContext(ctx, &teams, `
+		SELECT t.id, t.name, t.token_invites, t.allow_negative, t.free_requests, t.archived_at FROM team t
+		JOIN team_membership m ON m.team_id = t.id
+		WHERE m.user_id = $1 AND m.left_at IS NULL AND t.archived_at IS NULL
+		ORDER BY m.joined_at, t.id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить команды пользователя: %w", err)
+	}
+	return teams, nil
+}
+
+// ListMemberships returns all current and past memberships of the user, newest first
+func (r *UserRepository) ListMemberships(ctx context.Context, userID int64) ([]domain.Membership, error) {
+	var memberships []domain.Membership
+	err := r.DB.SelectContext(ctx, &memberships, `
+		SELECT m.team_id, t.name AS team_name, m.joined_at, m.left_at
+		FROM team_membership m
+		JOIN team t ON t.id = m.team_id
+		WHERE m.user_id = $1
+		ORDER BY m.left_at IS NULL DESC, m.joined_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить историю команд: %w", err)
+	}
+	return memberships, nil
 }
 
-func (r *UserRepository) DeleteTeam(teamID int) error {
-	_, err := r.DB.Exec(`DELETE FROM team WHERE id = $1`, teamID)
+// CreateTeam creates a team run by the given coach
+func (r *UserRepository) CreateTeam(ctx context.Context, name string, coachID int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+		WITH t AS (INSERT INTO team (name) VALUES ($1) RETURNING id)
+		INSERT INTO coach_team (coach_id, team_id) SELECT $2, id FROM t
+	`, name, coachID)
 	return err
 }
 
-func (r *UserRepository) ListTeams() ([]domain.Team, error) {
+// DeleteTeam removes a team that never had athletes or points; others can only be archived
+// Pending join requests go away with the team and are returned so applicants can be told.
+func (r *UserRepository) DeleteTeam(ctx context.Context, teamID int) (*TeamClosure, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	var closure TeamClosure
+	err = tx.SelectContext(ctx, &closure.DeniedJoins, `
+		SELECT `+joinRequestColumns+` FROM join_request j JOIN team t ON t.id = j.team_id
+		WHERE j.team_id = $1 AND j.status = 'pending'
+		FOR UPDATE OF j
+	`, teamID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("не удалось получить заявки команды: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM team t WHERE t.id = $1
+		  AND NOT EXISTS (SELECT 1 FROM team_membership m WHERE m.team_id = t.id)
+		  AND NOT EXISTS (SELECT 1 FROM point p WHERE p.team_id = t.id)
+	`, teamID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("не удалось удалить команду: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		util.SafeRollback(tx)
+		if _, err := r.GetTeamByID(ctx, teamID); err != nil {
+			return nil, err
+		}
+		return nil, ErrTeamHasHistory
+	}
+	return &closure, tx.Commit()
+}
+
+// GetTeamImpact counts members, requests, invites and points tied to the team
+func (r *UserRepository) GetTeamImpact(ctx context.Context, teamID int) (*domain.TeamImpact, error) {
+	var impact domain.TeamImpact
+	err := r.DB.GetContext(ctx, &impact, `
+		SELECT
+		  (SELECT COUNT(DISTINCT user_id) FROM team_membership WHERE team_id = $1 AND left_at IS NULL) AS members,
+		  (SELECT COUNT(DISTINCT user_id) FROM team_membership m WHERE team_id = $1 AND left_at IS NOT NULL
+		      AND NOT EXISTS (SELECT 1 FROM team_membership c WHERE c.team_id = $1 AND c.user_id = m.user_id AND c.left_at IS NULL)
+		  ) AS former_members,
+		  (SELECT COUNT(*) FROM point WHERE team_id = $1 AND status = 'pending') AS pending_requests,
+		  (SELECT COUNT(*) FROM join_request WHERE team_id = $1 AND status = 'pending') AS pending_joins,
+		  (SELECT COUNT(*) FROM team_invite WHERE team_id = $1 AND revoked_at IS NULL
+		      AND (expires_at IS NULL OR expires_at > now())
+		      AND (max_uses IS NULL OR uses < max_uses)) AS active_invites,
+		  (SELECT COUNT(*) FROM point WHERE team_id = $1) AS point_entries
+	`, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось посчитать данные команды: %w", err)
+	}
+	return &impact, nil
+}
+
+// ArchiveTeam hides the team, revokes its invites and denies its pending join requests,
+// which are returned so that the applicants can be told. Members and points are kept.
+func (r *UserRepository) ArchiveTeam(ctx context.Context, teamID int, adminID int64) (*TeamClosure, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE team SET archived_at = now() WHERE id = $1 AND archived_at IS NULL`, teamID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("не удалось архивировать команду: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("команда #%d не найдена или уже в архиве", teamID)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE team_invite SET revoked_at = now() WHERE team_id = $1 AND revoked_at IS NULL`, teamID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("не удалось отозвать приглашения: %w", err)
+	}
+
+	var closure TeamClosure
+	err = tx.SelectContext(ctx, &closure.DeniedJoins, `
+		UPDATE join_request j SET status = 'denied', decided_at = now()
+		FROM team t
+		WHERE j.team_id = $1 AND j.status = 'pending' AND t.id = j.team_id
+		RETURNING `+joinRequestColumns+`
+	`, teamID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("не удалось закрыть заявки: %w", err)
+	}
+
+	// запросы на баллы за архивную команду никто не рассмотрит, поэтому отклоняем их
+	err = tx.SelectContext(ctx, &closure.RejectedRequests, `
+		UPDATE point p
+		SET status = 'rejected', decided_at = now(), decided_by = $2, decision_comment = $3
+		FROM users u, team t
+		WHERE p.team_id = $1 AND p.status = 'pending' AND u.id = p.from_id AND t.id = p.team_id
+		RETURNING p.id, p.from_id, u.name, u.username, p.amount, p.reason, p.team_id, t.name AS team_name
+	`, teamID, adminID, ArchivedRequestComment)
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("не удалось закрыть запросы на баллы: %w", err)
+	}
+
+	return &closure, tx.Commit()
+}
+
+// RestoreTeam brings an archived team back; revoked invites stay revoked
+func (r *UserRepository) RestoreTeam(ctx context.Context, teamID int) error {
+	res, err := r.DB.ExecContext(ctx, `UPDATE team SET archived_at = NULL WHERE id = $1 AND archived_at IS NOT NULL`, teamID)
+	if err != nil {
+		return fmt.Errorf("не удалось восстановить команду: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("команда #%d не найдена в архиве", teamID)
+	}
+	return nil
+}
+
+// MoveTeamMembers ends every current membership in one team and opens it in another.
+// Athletes already in the target team just leave the source one. Returns how many moved.
+func (r *UserRepository) MoveTeamMembers(ctx context.Context, fromTeamID, toTeamID int) (int, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	var moved []int64
+	err = tx.SelectContext(ctx, &moved, `
+		UPDATE team_membership SET left_at = now()
+		WHERE team_id = $1 AND left_at IS NULL
+		RETURNING user_id
+	`, fromTeamID)
+	if err == nil && len(moved) > 0 {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO team_membership (user_id, team_id)
+			SELECT unnest($1::bigint[]), $2
+			ON CONFLICT (user_id, team_id) WHERE left_at IS NULL DO NOTHING
+		`, pq.Array(moved), toTeamID)
+	}
+	if err != nil {
+		util.SafeRollback(tx)
+		return 0, fmt.Errorf("не удалось перевести спортсменов: %w", err)
+	}
+
+	return len(moved), tx.Commit()
+}
+
+// SetTeamAllowNegative sets whether fines for the team may take scores below zero
+func (r *UserRepository) SetTeamAllowNegative(ctx context.Context, teamID int, allow bool) error {
+	res, err := r.DB.ExecContext(ctx, `UPDATE team SET allow_negative = $2 WHERE id = $1`, teamID, allow)
+	if err != nil {
+		return fmt.Errorf("не удалось изменить правило команды: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("команда #%d не найдена", teamID)
+	}
+	return nil
+}
+
+// SetTeamFreeRequests sets whether athletes of the team may request points outside the activity catalog
+func (r *UserRepository) SetTeamFreeRequests(ctx context.Context, teamID int, allow bool) error {
+	res, err := r.DB.ExecContext(ctx, `UPDATE team SET free_requests = $2 WHERE id = $1`, teamID, allow)
+	if err != nil {
+		return fmt.Errorf("не удалось изменить правило команды: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("команда #%d не найдена", teamID)
+	}
+	return nil
+}
+
+func (r *UserRepository) ListTeams(ctx context.Context) ([]domain.Team, error) {
 	var teams []domain.Team
-	err := r.DB.Select(&teams, `
-		SELECT id, name FROM team ORDER BY name ASC
+	err := r.DB.SelectContext(ctx, &teams, `
+		SELECT `+teamColumns+` FROM team WHERE archived_at IS NULL ORDER BY name ASC
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при получении команд: %w", err)
@@ -316,69 +824,797 @@ func (r *UserRepository) ListTeams() ([]domain.Team, error) {
 	return teams, nil
 }
 
-func (r *UserRepository) ListAthletesByTeam(teamID *int) ([]domain.AthleteShort, error) {
-	query := "SELECT id, name, username FROM users WHERE role = 'athlete'"
+func (r *UserRepository) ListArchivedTeams(ctx context.Context) ([]domain.Team, error) {
+	var teams []domain.Team
+	err := r.DB.SelectContext(ctx, &teams, `
+		SELECT `+teamColumns+` FROM team WHERE archived_at IS NOT NULL ORDER BY archived_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении архива команд: %w", err)
+	}
+	return teams, nil
+}
+
+func (r *UserRepository) ListAthletesByTeam(ctx context.Context, teamID *int) ([]domain.AthleteShort, error) {
+	query := "SELECT id, name, username FROM users u WHERE role = 'athlete'"
 	var args []interface{}
 	if teamID != nil {
-		query += " AND team_id = $1"
+		query += " AND EXISTS (SELECT 1 FROM team_membership m WHERE m.user_id = u.id AND m.team_id = $1 AND m.left_at IS NULL)"
 		args = append(args, *teamID)
 	}
 	query += " ORDER BY name ASC"
 
 	var athletes []domain.AthleteShort
-	err := r.DB.Select(&athletes, query, args...)
+	err := r.DB.SelectContext(ctx, &athletes, query, args...)
 	return athletes, err
 }
 
-func (r *UserRepository) GetPendingRequestsByTeam(teamID *int) ([]PendingRequest, error) {
+func (r *UserRepository) GetPendingRequestsByTeam(ctx context.Context, teamID *int) ([]PendingRequest, error) {
 	query := `
-		SELECT p.id, p.from_id, u.name, u.username, p.amount, p.reason
+		SELECT p.id, p.from_id, u.name, u.username, p.amount, p.reason, p.team_id, COALESCE(t.name, '') AS team_name
 		FROM point p
 		JOIN users u ON p.from_id = u.id
-		WHERE p.pending = true`
+		LEFT JOIN team t ON t.id = p.team_id
+		WHERE p.status = 'pending'`
 	var args []interface{}
 	if teamID != nil {
-		query += " AND u.team_id = $1"
+		query += " AND p.team_id = $1"
 		args = append(args, *teamID)
 	}
 	query += " ORDER BY p.id ASC"
 
 	var requests []PendingRequest
-	err := r.DB.Select(&requests, query, args...)
+	err := r.DB.SelectContext(ctx, &requests, query, args...)
 	return requests, err
 }
 
-func (r *UserRepository) GetRankingByTeam(teamID int) ([]domain.ScoreEntry, error) {
+// GetRankingByTeam ranks current members of the team by the points they earned for it
+// while being its members. An archived team keeps the ranking of everyone who was ever its member.
+func (r *UserRepository) GetRankingByTeam(ctx context.Context, teamID int) ([]domain.ScoreEntry, error) {
 	query := `
-		SELECT u.id as user_id, u.name, u.username, s.score
-		FROM users u
-		JOIN user_score s ON u.id = s.user_id
-		WHERE u.role = 'athlete' AND u.team_id = $1
-		ORDER BY s.score DESC, u.name ASC
+		SELECT u.id as user_id, u.name, u.username,
+		       COALESCE((SELECT SUM(p.amount) FROM point p
+		                 WHERE p.from_id = u.id AND p.team_id = t.id AND p.status = 'approved'
+		                   AND `+earnedInTeam+`), 0) AS score
+		FROM team t
+		JOIN users u ON u.role = 'athlete'
+		WHERE t.id = $1 AND EXISTS (
+		    SELECT 1 FROM team_membership m
+		    WHERE m.user_id = u.id AND m.team_id = t.id AND (m.left_at IS NULL OR t.archived_at IS NOT NULL)
+		)
+		ORDER BY score DESC, u.name ASC
 	`
 	var ranking []domain.ScoreEntry
-	err := r.DB.Select(&ranking, query, teamID)
+	err := r.DB.SelectContext(ctx, &ranking, query, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return ranking, nil
+}
+
+// GetPendingRequest returns a single pending point request by ID
+func (r *UserRepository) GetPendingRequest(ctx context.Context, id int) (*PendingRequest, error) {
+	var req PendingRequest
+	err := r.DB.GetContext(ctx, &req, `
+		SELECT p.id, p.from_id, u.name, u.username, p.amount, p.reason, p.team_id, COALESCE(t.name, '') AS team_name
+		FROM point p
+		JOIN users u ON p.from_id = u.id
+		LEFT JOIN team t ON t.id = p.team_id
+		WHERE p.id = $1 AND p.status = 'pending'
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("запрос не найден или уже обработан: %w", err)
+	}
+	return &req, nil
+}
+
+// UpdatePendingAmount changes the amount of a request that is still pending;
+// the amount the athlete asked for is kept in requested_amount
+func (r *UserRepository) UpdatePendingAmount(ctx context.Context, id int, amount int) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE point
+		SET requested_amount = CASE WHEN $1 = amount THEN requested_amount ELSE COALESCE(requested_amount, amount) END,
+		    amount = $1
+		WHERE id = $2 AND status = 'pending'
+	`, amount, id)
+	if err != nil {
+		return fmt.Errorf("не удалось изменить количество баллов: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("запрос не найден или уже обработан")
+	}
+	return nil
+}
+
+// earnedInTeam limits points aliased as p to those recorded while their author was a member
+// of the point's team; a correction counts when the point it corrects does
+const earnedInTeam = `EXISTS (
+	SELECT 1 FROM team_membership w
+	LEFT JOIN point o ON o.id = p.corrects_id
+	WHERE w.user_id = p.from_id AND w.team_id = p.team_id
+	  AND w.joined_at <= COALESCE(o.created_at, p.created_at)
+	  AND (w.left_at IS NULL OR w.left_at > COALESCE(o.created_at, p.created_at)))`
+
+// coachScope limits athletes aliased as u to members of the coach's teams and athletes without a team
+const coachScope = `(
+	NOT EXISTS (SELECT 1 FROM team_membership m WHERE m.user_id = u.id AND m.left_at IS NULL)
+	OR EXISTS (SELECT 1 FROM team_membership m JOIN coach_team ct ON ct.team_id = m.team_id
+	           WHERE m.user_id = u.id AND m.left_at IS NULL AND ct.coach_id = $1))`
+
+// requestScope limits pending requests aliased as p to the coach's teams;
+// a request without a team follows the scope of its author
+const requestScope = `(
+	p.team_id IN (SELECT team_id FROM coach_team WHERE coach_id = $1)
+	OR (p.team_id IS NULL AND ` + coachScope + `))`
+
+func (r *UserRepository) ListAthletesByCoach(ctx context.Context, coachID int64) ([]domain.AthleteShort, error) {
+	var athletes []domain.AthleteShort
+	err := r.DB.SelectContext(ctx, &athletes, `
+		SELECT u.id, u.name, u.username FROM users u
+		WHERE u.role = 'athlete' AND `+coachScope+`
+		ORDER BY u.name ASC
+	`, coachID)
+	return athletes, err
+}
+
+func (r *UserRepository) GetPendingRequestsByCoach(ctx context.Context, coachID int64) ([]PendingRequest, error) {
+	var requests []PendingRequest
+	err := r.DB.SelectContext(ctx, &requests, `
+		SELECT p.id, p.from_id, u.name, u.username, p.amount, p.reason, p.team_id, COALESCE(t.name, '') AS team_name
+		FROM point p
+		JOIN users u ON p.from_id = u.id
+		LEFT JOIN team t ON t.id = p.team_id
+		WHERE p.status = 'pending' AND `+requestScope+`
+		ORDER BY p.id ASC
+	`, coachID)
+	return requests, err
+}
+
+// GetRankingByCoach ranks athletes of all active teams the coach runs together.
+// Like GetRankingByTeam, only points earned for those teams count, summed over
+// the teams the athlete is in now.
+func (r *UserRepository) GetRankingByCoach(ctx context.Context, coachID int64) ([]domain.ScoreEntry, error) {
+	var ranking []domain.ScoreEntry
+	err := r.DB.SelectContext(ctx, &ranking, `
+		WITH coached AS (
+		    SELECT m.user_id, m.team_id
+		    FROM team_membership m
+		    JOIN coach_team ct ON ct.team_id = m.team_id AND ct.coach_id = $1
+		    JOIN team t ON t.id = m.team_id AND t.archived_at IS NULL
+		    WHERE m.left_at IS NULL
+		)
+		SELECT u.id as user_id, u.name, u.username,
+		       COALESCE((SELECT SUM(p.amount) FROM point p
+		                 JOIN coached c ON c.user_id = p.from_id AND c.team_id = p.team_id
+		                 WHERE p.from_id = u.id AND p.status = 'approved' AND `+earnedInTeam+`), 0) AS score
+		FROM users u
+		WHERE u.role = 'athlete' AND EXISTS (SELECT 1 FROM coached c WHERE c.user_id = u.id)
+		ORDER BY score DESC, u.name ASC
+	`, coachID)
 	if err != nil {
 		return nil, err
 	}
 	return ranking, nil
 }
 
-func (r *UserRepository) GetUserTeamID(userID int64) (int, error) {
-	var teamID int
-	err := r.DB.Get(&teamID, `SELECT team_id FROM users WHERE id = $1`, userID)
+func (r *UserRepository) AddTeamCoach(ctx context.Context, teamID int, coachID int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO coach_team (coach_id, team_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, coachID, teamID)
+	if err != nil {
+		return fmt.Errorf("не удалось добавить тренера в команду: %w", err)
+	}
+	return nil
+}
+
+// RemoveTeamCoach removes a coach from a team; the last coach cannot leave
+func (r *UserRepository) RemoveTeamCoach(ctx context.Context, teamID int, coachID int64) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	// блокируем тренеров команды, чтобы двое не ушли одновременно
+	var coaches []int64
+	err = tx.SelectContext(ctx, &coaches, `SELECT coach_id FROM coach_team WHERE team_id = $1 FOR UPDATE`, teamID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return fmt.Errorf("не удалось получить тренеров команды: %w", err)
+	}
+	if !slices.Contains(coaches, coachID) {
+		util.SafeRollback(tx)
+		return fmt.Errorf("тренер не ведёт команду #%d", teamID)
+	}
+	if len(coaches) == 1 {
+		util.SafeRollback(tx)
+		return fmt.Errorf("нельзя оставить команду #%d без тренера", teamID)
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM coach_team WHERE team_id = $1 AND coach_id = $2`, teamID, coachID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return fmt.Errorf("не удалось убрать тренера из команды: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (r *UserRepository) ListCoachTeams(ctx context.Context, coachID int64) ([]domain.Team, error) {
+	var teams []domain.Team
+	err := r.DB.SelectContext(ctx, &teams, `
+		SELECT t.id, t.name, t.token_invites, t.allow_negative, t.free_requests, t.archived_at FROM team t
+		JOIN coach_team ct ON ct.team_id = t.id
+		WHERE ct.coach_id = $1 AND t.archived_at IS NULL
+		ORDER BY t.name ASC
+	`, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении команд тренера: %w", err)
+	}
+	return teams, nil
+}
+
+func (r *UserRepository) CoachesTeam(ctx context.Context, coachID int64, teamID int) (bool, error) {
+	var ok bool
+	err := r.DB.GetContext(ctx, &ok, `
+		SELECT EXISTS (SELECT 1 FROM coach_team WHERE coach_id = $1 AND team_id = $2)
+	`, coachID, teamID)
+	return ok, err
+}
+
+// CoachesAthlete reports whether the athlete is in one of the coach's teams or has no team
+func (r *UserRepository) CoachesAthlete(ctx context.Context, coachID int64, athleteID int64) (bool, error) {
+	var ok bool
+	err := r.DB.GetContext(ctx, &ok, `
+		SELECT `+coachScope+` FROM users u WHERE u.id = $2
+	`, coachID, athleteID)
+	return ok, err
+}
+
+// ListStaff returns coaches and admins
+func (r *UserRepository) ListStaff(ctx context.Context) ([]domain.User, error) {
+	var users []domain.User
+	err := r.DB.SelectContext(ctx, &users, `
+		SELECT id, name, username, role FROM users
+		WHERE role IN ('coach', 'admin')
+		ORDER BY role = 'admin' DESC, name ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении списка тренеров: %w", err)
+	}
+	return users, nil
+}
+
+// SetUserRole changes a user's role, records the change and returns the previous role.
+// changedBy is nil when the change comes from configuration.
+func (r *UserRepository) SetUserRole(ctx context.Context, userID int64, role domain.Role, changedBy *int64) (domain.Role, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	var old domain.Role
+	err = tx.GetContext(ctx, &old, `SELECT role FROM users WHERE id = $1 FOR UPDATE`, userID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return "", fmt.Errorf("пользователь не найден: %w", err)
+	}
+	if old == role {
+		util.SafeRollback(tx)
+		return old, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE users SET role = $2 WHERE id = $1`, userID, role)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO role_change (user_id, old_role, new_role, changed_by)
+			VALUES ($1, $2, $3, $4)
+		`, userID, old, role, changedBy)
+	}
+	if err == nil && role == domain.RoleAthlete {
+		// у спортсмена должен быть счёт
+		_, err = tx.ExecContext(ctx, `INSERT INTO user_score (user_id, score) VALUES ($1, 0) ON CONFLICT DO NOTHING`, userID)
+	}
+	if err == nil && !role.IsStaff() {
+		// бывший тренер больше не ведёт команды, но команда не может остаться без тренера
+		var sole []int
+		sole, err = soleCoachTeams(ctx, tx, userID)
+		if err == nil && len(sole) > 0 {
+			util.SafeRollback(tx)
+			return "", fmt.Errorf("%w: это единственный тренер команд %s", ErrLastCoach, formatTeamIDs(sole))
+		}
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `DELETE FROM coach_team WHERE coach_id = $1`, userID)
+		}
+	}
+	if err != nil {
+		util.SafeRollback(tx)
+		return "", fmt.Errorf("не удалось изменить роль: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return old, nil
+}
+
+// soleCoachTeams returns the teams the coach runs alone, locking their coaches like RemoveTeamCoach does
+func soleCoachTeams(ctx context.Context, tx *sqlx.Tx, coachID int64) ([]int, error) {
+	var rows []struct {
+		TeamID  int   `db:"team_id"`
+		CoachID int64 `db:"coach_id"`
+	}
+	err := tx.SelectContext(ctx, &rows, `
+		SELECT team_id, coach_id FROM coach_team
+		WHERE team_id IN (SELECT team_id FROM coach_team WHERE coach_id = $1)
+		ORDER BY team_id
+		FOR UPDATE
+	`, coachID)
+	if err != nil {
+		return nil, err
+	}
+
+	coaches := map[int]int{}
+	for _, row := range rows {
+		coaches[row.TeamID]++
+	}
+	var sole []int
+	for _, row := range rows {
+		if row.CoachID == coachID && coaches[row.TeamID] == 1 {
+			sole = append(sole, row.TeamID)
+		}
+	}
+	return sole, nil
+}
+
+// formatTeamIDs renders team IDs as "#1, #3"
+func formatTeamIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("#%d", id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ListRoleChanges returns the latest role changes, newest first
+// RegisterAdmin registers a chat listed in ADMIN_IDS on its first /start and
+// records the grant in role_change without an author. Registered users are left as is.
+func (r *UserRepository) RegisterAdmin(ctx context.Context, user *domain.User) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO users (id, name, username, role) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO NOTHING
+	`, user.ID, user.Name, user.Username, domain.RoleAdmin)
+	var n int64
+	if err == nil {
+		n, err = res.RowsAffected()
+	}
+	if err == nil && n == 0 {
+		util.SafeRollback(tx)
+		return nil // already exists
+	}
+	if err == nil {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO role_change (user_id, old_role, new_role, changed_by) VALUES ($1, '', $2, NULL)
+		`, user.ID, domain.RoleAdmin)
+	}
+	if err != nil {
+		util.SafeRollback(tx)
+		return fmt.Errorf("не удалось зарегистрировать администратора: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (r *UserRepository) ListRoleChanges(ctx context.Context, limit int) ([]domain.RoleChange, error) {
+	var changes []domain.RoleChange
+	err := r.DB.SelectContext(ctx, &changes, `
+		SELECT c.id, c.user_id, u.username, c.old_role, c.new_role, a.name AS changed_by, c.created_at
+		FROM role_change c
+		JOIN users u ON u.id = c.user_id
+		LEFT JOIN users a ON a.id = c.changed_by
+		ORDER BY c.id DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить журнал ролей: %w", err)
+	}
+	return changes, nil
+}
+
+// CreateCoachInvite stores a single-use coach invitation by the hash of its token
+func (r *UserRepository) CreateCoachInvite(ctx context.Context, tokenHash string, createdBy int64, expiresAt time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO coach_invite (token_hash, created_by, expires_at) VALUES ($1, $2, $3)
+	`, tokenHash, createdBy, expiresAt)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить приглашение: %w", err)
+	}
+	return nil
+}
+
+// RedeemCoachInvite registers the user as a coach and uses up the invitation.
+// Both happen in one transaction, so an invitation cannot be used twice.
+func (r *UserRepository) RedeemCoachInvite(ctx context.Context, tokenHash string, user *domain.User) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO users (id, name, username, role) VALUES ($1, $2, $3, 'coach')
+	`, user.ID, user.Name, user.Username)
+	if err != nil {
+		util.SafeRollback(tx)
+		return fmt.Errorf("failed to insert into users: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE coach_invite SET used_by = $2, used_at = now()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+	`, tokenHash, user.ID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return fmt.Errorf("не удалось использовать приглашение: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		util.SafeRollback(tx)
+		return ErrInviteInvalid
+	}
+
+	return tx.Commit()
+}
+
+// CreateTeamInvite stores an invite and switches the team to token invites
+func (r *UserRepository) CreateTeamInvite(ctx context.Context, invite *domain.TeamInvite) (int, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	var id int
+	err = tx.GetContext(ctx, &id, `
+		INSERT INTO team_invite (team_id, token_hash, created_by, expires_at, max_uses, needs_approval)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, invite.TeamID, invite.TokenHash, invite.CreatedBy, invite.ExpiresAt, invite.MaxUses, invite.NeedsApproval)
+	if err != nil {
+		util.SafeRollback(tx)
+		return 0, fmt.Errorf("не удалось сохранить приглашение: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE team SET token_invites = true WHERE id = $1`, invite.TeamID)
+	if err != nil {
+		util.SafeRollback(tx)
+		return 0, fmt.Errorf("не удалось обновить команду: %w", err)
+	}
+
+	return id, tx.Commit()
+}
+
+const teamInviteColumns = `id, team_id, token_hash, created_by, created_at, expires_at, max_uses, uses, needs_approval, revoked_at`
+
+func (r *UserRepository) GetTeamInvite(ctx context.Context, id int) (*domain.TeamInvite, error) {
+	var invite domain.TeamInvite
+	err := r.DB.GetContext(ctx, &invite, `SELECT `+teamInviteColumns+` FROM team_invite WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("приглашение #%d не найдено: %w", id, err)
+	}
+	return &invite, nil
+}
+
+// ListTeamInvites returns invites of the team that can still be used
+func (r *UserRepository) ListTeamInvites(ctx context.Context, teamID int) ([]domain.TeamInvite, error) {
+	var invites []domain.TeamInvite
+	err := r.DB.SelectContext(ctx, &invites, `
+		SELECT `+teamInviteColumns+` FROM team_invite
+		WHERE team_id = $1 AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > now())
+		  AND (max_uses IS NULL OR uses < max_uses)
+		ORDER BY id
+	`, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить приглашения: %w", err)
+	}
+	return invites, nil
+}
+
+func (r *UserRepository) RevokeTeamInvite(ctx context.Context, id int) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE team_invite SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return fmt.Errorf("не удалось отозвать приглашение: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("приглашение #%d не найдено или уже отозвано", id)
+	}
+	return nil
+}
+
+// RedeemTeamInvite counts a use of the invite and either registers the user as an athlete
+// of its team right away or, for invites that need approval, files a pending join request
+func (r *UserRepository) RedeemTeamInvite(ctx context.Context, tokenHash string, user *domain.User) (*domain.JoinRequest, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	// блокировка не даёт превысить max_uses при одновременных переходах
+	var invite domain.TeamInvite
+	err = tx.GetContext(ctx, &invite, `
+		SELECT `+teamInviteColumns+` FROM team_invite WHERE token_hash = $1 FOR UPDATE
+	`, tokenHash)
+	if err == sql.ErrNoRows || (err == nil && !invite.Active(time.Now())) {
+		util.SafeRollback(tx)
+		return nil, ErrInviteInvalid
+	}
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("не удалось проверить приглашение: %w", err)
+	}
+
+	// зарегистрированный спортсмен может вступить по приглашению ещё в одну команду
+	var member bool
+	err = tx.GetContext(ctx, &member, `
+		SELECT EXISTS (SELECT 1 FROM team_membership WHERE user_id = $1 AND team_id = $2 AND left_at IS NULL)
+	`, user.ID, invite.TeamID)
+	if err == nil && member {
+		util.SafeRollback(tx)
+		return nil, ErrAlreadyMember
+	}
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("не удалось проверить приглашение: %w", err)
+	}
+
+	status := domain.JoinApproved
+	if invite.NeedsApproval {
+		status = domain.JoinPending
+	}
+	req, err := insertJoinRequest(ctx, tx, user, invite.TeamID, &invite.ID, status)
+	if err == nil && status == domain.JoinApproved {
+		err = insertAthlete(ctx, tx, req)
+	}
+	if err == nil {
+		_, err = tx.ExecContext(ctx, `UPDATE team_invite SET uses = uses + 1 WHERE id = $1`, invite.ID)
+	}
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, err
+	}
+
+	return req, tx.Commit()
+}
+
+func (r *UserRepository) ListTeamCoaches(ctx context.Context, teamID int) ([]int64, error) {
+	var ids []int64
+	err := r.DB.SelectContext(ctx, &ids, `SELECT coach_id FROM coach_team WHERE team_id = $1 ORDER BY coach_id`, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить тренеров команды: %w", err)
+	}
+	return ids, nil
+}
+
+// CreateJoinRequest files a pending request of an unregistered user to join the team
+func (r *UserRepository) CreateJoinRequest(ctx context.Context, user *domain.User, teamID int) (*domain.JoinRequest, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	req, err := insertJoinRequest(ctx, tx, user, teamID, nil, domain.JoinPending)
+	if err != nil {
+		util.SafeRollback(tx)
+		return nil, err
+	}
+	return req, tx.Commit()
+}
+
+func insertJoinRequest(ctx context.Context, tx *sqlx.Tx, user *domain.User, teamID int, inviteID *int, status domain.JoinStatus) (*domain.JoinRequest, error) {
+	var decidedAt *time.Time
+	if status != domain.JoinPending {
+		now := time.Now()
+		decidedAt = &now
+	}
+
+	var id int
+	err := tx.GetContext(ctx, &id, `
+		INSERT INTO join_request (user_id, name, username, team_id, invite_id, status, decided_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, user.ID, user.Name, user.Username, teamID, inviteID, status, decidedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrJoinPending
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сохранить заявку: %w", err)
+	}
+
+	var req domain.JoinRequest
+	err = tx.GetContext(ctx, &req, `SELECT `+joinRequestColumns+` FROM join_request j JOIN team t ON t.id = j.team_id WHERE j.id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сохранить заявку: %w", err)
+	}
+	return &req, nil
+}
+
+// insertAthlete creates the account of an approved applicant and adds them to the team.
+// A registered athlete keeps their account and only gets the new membership.
+func insertAthlete(ctx context.Context, tx *sqlx.Tx, req *domain.JoinRequest) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO users (id, name, username, role) VALUES ($1, $2, $3, 'athlete')
+		ON CONFLICT (id) DO NOTHING
+	`, req.UserID, req.Name, req.Username)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, `INSERT INTO user_score (user_id, score) VALUES ($1, 0) ON CONFLICT DO NOTHING`, req.UserID)
+	}
+	if err == nil {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO team_membership (user_id, team_id) VALUES ($1, $2)
+			ON CONFLICT (user_id, team_id) WHERE left_at IS NULL DO NOTHING
+		`, req.UserID, req.TeamID)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка регистрации: %w", err)
+	}
+	return nil
+}
+
+const joinRequestColumns = `j.id, j.user_id, j.name, COALESCE(j.username, '') AS username, j.team_id, t.name AS team_name,
+	j.invite_id, j.status, j.created_at, j.decided_by, j.decided_at`
+
+func (r *UserRepository) GetJoinRequest(ctx context.Context, id int) (*domain.JoinRequest, error) {
+	var req domain.JoinRequest
+	err := r.DB.GetContext(ctx, &req, `
+		SELECT `+joinRequestColumns+` FROM join_request j JOIN team t ON t.id = j.team_id WHERE j.id = $1
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("заявка #%d не найдена: %w", id, err)
+	}
+	return &req, nil
+}
+
+// GetPendingJoinRequest returns the applicant's request under review, or nil
+func (r *UserRepository) GetPendingJoinRequest(ctx context.Context, userID int64) (*domain.JoinRequest, error) {
+	var req domain.JoinRequest
+	err := r.DB.GetContext(ctx, &req, `
+		SELECT `+joinRequestColumns+` FROM join_request j JOIN team t ON t.id = j.team_id
+		WHERE j.user_id = $1 AND j.status = 'pending'
+	`, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (r *UserRepository) ListJoinRequests(ctx context.Context) ([]domain.JoinRequest, error) {
+	var reqs []domain.JoinRequest
+	err := r.DB.SelectContext(ctx, &reqs, `
+		SELECT `+joinRequestColumns+` FROM join_request j JOIN team t ON t.id = j.team_id
+		WHERE j.status = 'pending'
+		ORDER BY j.created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить заявки: %w", err)
+	}
+	return reqs, nil
+}
+
+func (r *UserRepository) ListJoinRequestsByCoach(ctx context.Context, coachID int64) ([]domain.JoinRequest, error) {
+	var reqs []domain.JoinRequest
+	err := r.DB.SelectContext(ctx, &reqs, `
+		SELECT `+joinRequestColumns+` FROM join_request j JOIN team t ON t.id = j.team_id
+		WHERE j.status = 'pending'
+		  AND j.team_id IN (SELECT team_id FROM coach_team WHERE coach_id = $1)
+		ORDER BY j.created_at
+	`, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить заявки: %w", err)
+	}
+	return reqs, nil
+}
+
+// ApproveJoinRequest registers the applicant as an athlete of the requested team
+func (r *UserRepository) ApproveJoinRequest(ctx context.Context, id int, coachID int64) (*domain.JoinRequest, error) {
+	return r.decideJoinRequest(ctx, id, coachID, domain.JoinApproved)
+}
+
+func (r *UserRepository) DenyJoinRequest(ctx context.Context, id int, coachID int64) (*domain.JoinRequest, error) {
+	return r.decideJoinRequest(ctx, id, coachID, domain.JoinDenied)
+}
+
+func (r *UserRepository) decideJoinRequest(ctx context.Context, id int, coachID int64, status domain.JoinStatus) (*domain.JoinRequest, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	var req domain.JoinRequest
+	err = tx.GetContext(ctx, &req, `
+		UPDATE join_request j SET status = $2, decided_by = $3, decided_at = now()
+		FROM team t
+		WHERE j.id = $1 AND j.status = 'pending' AND t.id = j.team_id
+		RETURNING `+joinRequestColumns+`
+	`, id, status, coachID)
+	if err == sql.ErrNoRows {
+		util.SafeRollback(tx)
+		return nil, fmt.Errorf("заявка #%d не найдена или уже рассмотрена", id)
+	}
+	if err == nil && status == domain.JoinApproved {
+		// пока заявка ждала, заявителя могли зарегистрировать тренером
+		var role domain.Role
+		err = tx.GetContext(ctx, &role, `SELECT role FROM users WHERE id = $1 FOR UPDATE`, req.UserID)
+		switch {
+		case err == sql.ErrNoRows:
+			err = nil
+		case err == nil && role != domain.RoleAthlete:
+			err = fmt.Errorf("%w: @%s — %s", ErrNotAthlete, req.Username, role)
+		}
+	}
+	if err == nil && status == domain.JoinApproved {
+		err = insertAthlete(ctx, tx, &req)
+	}
 	if err != nil {
-		return 0, fmt.Errorf("не удалось получить team_id пользователя: %w", err)
+		util.SafeRollback(tx)
+		return nil, err
 	}
-	return teamID, nil
+
+	return &req, tx.Commit()
 }
 
+const activityColumns = `id, team_id, name, amount, created_by, created_at`
 
-func (r *UserRepository) GetUserTeamName(userID int64) (string, error) {
-	var name string
-	err := r.DB.Get(&name, `SELECT name FROM users WHERE id = $1`, userID)
+func (r *UserRepository) ListActivities(ctx context.Context, teamID int) ([]domain.Activity, error) {
+	var activities []domain.Activity
+	err := r.DB.SelectContext(ctx, &activities, `
+		SELECT `+activityColumns+` FROM activity WHERE team_id = $1 ORDER BY amount ASC, name ASC
+	`, teamID)
 	if err != nil {
-		return "", fmt.Errorf("не удалось получить команду пользователя: %w", err)
+		return nil, fmt.Errorf("ошибка при получении каталога занятий: %w", err)
 	}
-	return name, nil
-}
\ No newline at end of file
+	return activities, nil
+}
+
+func (r *UserRepository) GetActivity(ctx context.Context, id int) (*domain.Activity, error) {
+	var activity domain.Activity
+	err := r.DB.GetContext(ctx, &activity, `SELECT `+activityColumns+` FROM activity WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("занятие #%d не найдено: %w", id, err)
+	}
+	return &activity, nil
+}
+
+// AddActivity adds an activity to the team's catalog; names are unique within a team regardless of case
+func (r *UserRepository) AddActivity(ctx context.Context, activity *domain.Activity) (int, error) {
+	var id int
+	err := r.DB.GetContext(ctx, &id, `
+		INSERT INTO activity (team_id, name, amount, created_by) VALUES ($1, $2, $3, $4) RETURNING id
+	`, activity.TeamID, activity.Name, activity.Amount, activity.CreatedBy)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return 0, ErrActivityExists
+	}
+	if err != nil {
+		return 0, fmt.Errorf("не удалось добавить занятие: %w", err)
+	}
+	return id, nil
+}
+
+// RemoveActivity deletes an activity; requests made from it keep their name and points
+func (r *UserRepository) RemoveActivity(ctx context.Context, id int) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM activity WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("не удалось удалить занятие: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("занятие #%d не найдено", id)
+	}
+	return nil
+}
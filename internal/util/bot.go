@@ -4,12 +4,28 @@ package util
 import (
 	"log"
 
+	"surf_bot/internal/messenger"
+
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 // SafeSend отправляет сообщение и логирует ошибку, если она произошла
-func SafeSend(bot *tgbotapi.BotAPI, msg tgbotapi.MessageConfig) {
+func SafeSend(bot messenger.Messenger, msg tgbotapi.MessageConfig) {
 	if _, err := bot.Send(msg); err != nil {
 		log.Printf("⚠️  failed to send message: %v", err)
 	}
 }
+
+// SafeEdit редактирует сообщение и логирует ошибку, если она произошла
+func SafeEdit(bot messenger.Messenger, edit tgbotapi.EditMessageTextConfig) {
+	if err := bot.Edit(edit); err != nil {
+		log.Printf("⚠️  failed to edit message: %v", err)
+	}
+}
+
+// SafeAnswer отвечает на нажатие inline-кнопки и логирует ошибку, если она произошла
+func SafeAnswer(bot messenger.Messenger, cb tgbotapi.CallbackConfig) {
+	if err := bot.AnswerCallback(cb); err != nil {
+		log.Printf("⚠️  failed to answer callback: %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+-- +goose Up
+ALTER TABLE point
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'approved', 'rejected', 'cancelled', 'expired')),
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN decided_at TIMESTAMPTZ,
+    ADD COLUMN decided_by BIGINT REFERENCES users(id),
+    ADD COLUMN decision_comment TEXT;
+
+UPDATE point SET status = 'approved', decided_at = created_at WHERE pending = false;
+
+ALTER TABLE point DROP COLUMN pending;
+
+CREATE INDEX IF NOT EXISTS point_pending_idx ON point (created_at) WHERE status = 'pending';
+
+-- +goose Down
+DROP INDEX IF EXISTS point_pending_idx;
+
+ALTER TABLE point ADD COLUMN pending BOOLEAN DEFAULT true;
+
+-- до этой миграции отклонённые запросы удалялись
+DELETE FROM point WHERE status IN ('rejected', 'cancelled', 'expired');
+UPDATE point SET pending = (status = 'pending');
+
+ALTER TABLE point
+    DROP COLUMN decision_comment,
+    DROP COLUMN decided_by,
+    DROP COLUMN decided_at,
+    DROP COLUMN created_at,
+    DROP COLUMN status;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN notify_mode TEXT NOT NULL DEFAULT 'instant'
+    CHECK (notify_mode IN ('instant', 'digest', 'off'));
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN IF EXISTS notify_mode;
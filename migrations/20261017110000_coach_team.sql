@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS coach_team (
+    coach_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    team_id INTEGER NOT NULL REFERENCES team(id) ON DELETE CASCADE,
+    PRIMARY KEY (coach_id, team_id)
+);
+
+CREATE INDEX IF NOT EXISTS coach_team_team_idx ON coach_team (team_id);
+
+-- до этой миграции любой тренер управлял любой командой: сохраняем это для существующих
+INSERT INTO coach_team (coach_id, team_id)
+SELECT u.id, t.id FROM users u CROSS JOIN team t
+WHERE u.role = 'coach';
+
+-- +goose Down
+DROP TABLE IF EXISTS coach_team;
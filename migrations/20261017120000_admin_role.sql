@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('athlete', 'coach', 'admin'));
+
+CREATE TABLE IF NOT EXISTS role_change (
+    id SERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_role TEXT NOT NULL,
+    new_role TEXT NOT NULL,
+    changed_by BIGINT REFERENCES users(id) ON DELETE SET NULL, -- NULL: из конфигурации
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS role_change;
+
+UPDATE users SET role = 'coach' WHERE role = 'admin';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('athlete', 'coach'));
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS coach_invite (
+    id SERIAL PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE, -- sha256 от токена, сам токен не хранится
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    used_at TIMESTAMPTZ
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS coach_invite;
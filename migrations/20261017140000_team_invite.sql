@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE team ADD COLUMN token_invites BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS team_invite (
+    id SERIAL PRIMARY KEY,
+    team_id INTEGER NOT NULL REFERENCES team(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE, -- sha256 от токена
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ,
+    max_uses INTEGER CHECK (max_uses > 0),
+    uses INTEGER NOT NULL DEFAULT 0,
+    needs_approval BOOLEAN NOT NULL DEFAULT false,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS team_invite_team_idx ON team_invite (team_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS team_invite;
+ALTER TABLE team DROP COLUMN IF EXISTS token_invites;
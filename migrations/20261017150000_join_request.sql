@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS join_request (
+    id SERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL, -- Telegram ID заявителя, пользователя ещё нет
+    name TEXT NOT NULL,
+    username TEXT,
+    team_id INTEGER NOT NULL REFERENCES team(id) ON DELETE CASCADE,
+    invite_id INTEGER REFERENCES team_invite(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'denied')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    decided_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMPTZ
+);
+
+-- у заявителя может быть только одна заявка на рассмотрении
+CREATE UNIQUE INDEX IF NOT EXISTS join_request_pending_idx ON join_request (user_id) WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE IF EXISTS join_request;
@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS team_membership (
+    id SERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    team_id INTEGER NOT NULL REFERENCES team(id) ON DELETE CASCADE,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    left_at TIMESTAMPTZ, -- NULL, пока спортсмен в команде
+    CHECK (left_at IS NULL OR left_at >= joined_at)
+);
+
+-- в одной команде можно состоять только один раз одновременно
+CREATE UNIQUE INDEX IF NOT EXISTS team_membership_active_idx ON team_membership (user_id, team_id) WHERE left_at IS NULL;
+CREATE INDEX IF NOT EXISTS team_membership_team_idx ON team_membership (team_id) WHERE left_at IS NULL;
+
+-- дата вступления раньше не хранилась: берём первую запись в point, иначе текущее время
+INSERT INTO team_membership (user_id, team_id, joined_at)
+SELECT u.id, u.team_id, COALESCE((SELECT MIN(p.created_at) FROM point p WHERE p.from_id = u.id), now())
+FROM users u
+WHERE u.team_id IS NOT NULL;
+
+-- команда, за которую начислены баллы; старые записи относим к текущей команде спортсмена
+ALTER TABLE point ADD COLUMN team_id INTEGER REFERENCES team(id) ON DELETE SET NULL;
+UPDATE point p SET team_id = u.team_id FROM users u WHERE u.id = p.from_id;
+CREATE INDEX IF NOT EXISTS point_team_idx ON point (team_id);
+
+ALTER TABLE users DROP COLUMN team_id;
+
+-- +goose Down
+ALTER TABLE users ADD COLUMN team_id INTEGER REFERENCES team(id);
+UPDATE users u SET team_id = (
+    SELECT MIN(m.team_id) FROM team_membership m WHERE m.user_id = u.id AND m.left_at IS NULL
+);
+
+ALTER TABLE point DROP COLUMN IF EXISTS team_id;
+DROP TABLE IF EXISTS team_membership;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE team ADD COLUMN archived_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE team DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- штрафы хранятся в point с отрицательным amount, отдельная колонка не нужна
+ALTER TABLE team ADD COLUMN allow_negative BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE team DROP COLUMN IF EXISTS allow_negative;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- исправление начисления — отдельная запись с разницей, исходная не меняется
+ALTER TABLE point ADD COLUMN corrects_id INT REFERENCES point(id);
+
+CREATE INDEX IF NOT EXISTS point_corrects_idx ON point (corrects_id) WHERE corrects_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS point_corrects_idx;
+ALTER TABLE point DROP COLUMN IF EXISTS corrects_id;
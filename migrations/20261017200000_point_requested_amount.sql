@@ -0,0 +1,6 @@
+-- +goose Up
+-- сколько просил спортсмен, если тренер изменил сумму; NULL — сумма не менялась
+ALTER TABLE point ADD COLUMN requested_amount INT;
+
+-- +goose Down
+ALTER TABLE point DROP COLUMN IF EXISTS requested_amount;
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE team ADD COLUMN free_requests BOOLEAN NOT NULL DEFAULT true;
+
+CREATE TABLE IF NOT EXISTS activity (
+    id SERIAL PRIMARY KEY,
+    team_id INTEGER NOT NULL REFERENCES team(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- запрос по каталогу копирует название и баллы в point, поэтому удалять занятия можно без потери истории
+CREATE UNIQUE INDEX IF NOT EXISTS activity_team_name_idx ON activity (team_id, lower(name));
+
+-- +goose Down
+DROP TABLE IF EXISTS activity;
+ALTER TABLE team DROP COLUMN IF EXISTS free_requests;